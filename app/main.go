@@ -1,14 +1,15 @@
 package main
 
 import (
+	"context"
 	"embed"
 	"fmt"
 	"os"
 	"time"
 
+	"github.com/schachte/better-sync/pkg/bettersync"
 	"github.com/schachte/better-sync/pkg/device"
 	"github.com/schachte/better-sync/pkg/model"
-	"github.com/schachte/better-sync/pkg/operations"
 	"github.com/schachte/better-sync/pkg/util"
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -30,26 +31,18 @@ func main() {
 	go func() {
 		for {
 			util.LogError("Attempting to connect to device...")
-			dev, err := device.Initialize(timeout)
+			client, err := bettersync.Connect(context.Background(), bettersync.ConnectOptions{Timeout: timeout})
 			if err != nil {
-				util.LogError("Failed to initialize device: %v", err)
+				util.LogError("Failed to connect to device: %v", err)
 				device.CheckForCommonMTPConflicts(err)
 				time.Sleep(timeout)
 				continue
 			}
 
-			storages, err := device.FetchStorages(dev, timeout)
-			if err != nil {
-				util.LogError("Failed to fetch storages: %v", err)
-				dev.Close()
-				time.Sleep(timeout)
-				continue
-			}
-
-			songs, err := operations.GetSongs(dev, storages)
+			songs, err := client.Songs(context.Background())
 			if err != nil {
 				util.LogError("Failed to get songs: %v", err)
-				dev.Close()
+				client.Close()
 				time.Sleep(timeout)
 				continue
 			}
@@ -57,7 +50,7 @@ func main() {
 			app.Songs = songs
 
 			runtime.EventsEmit(app.ctx, "songs-loaded", songs)
-			dev.Close()
+			client.Close()
 			time.Sleep(timeout)
 		}
 	}()
@@ -0,0 +1,810 @@
+// cmd/better-sync/browse.go
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fatih/color"
+	"github.com/mattn/go-runewidth"
+	"github.com/schachte/better-sync/pkg/bettersync"
+	"github.com/schachte/better-sync/pkg/device"
+	"github.com/schachte/better-sync/pkg/model"
+	"github.com/schachte/better-sync/pkg/operations"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+// The panes of the browser, in the order Tab moves through them
+const (
+	paneStorages = iota
+	paneFolders
+	paneSongs
+	panePlaylists
+	paneCount
+)
+
+var paneTitles = [paneCount]string{"Storages", "Folders", "Songs", "Playlists"}
+
+// What the keyboard is typing into
+const (
+	inputNone = iota
+	inputFilter
+	inputPrompt
+)
+
+const browseHelp = "tab pane  / filter  space mark  * mark all  d delete  D delete with songs  " +
+	"p add to playlist  u upload  r reload  q quit"
+
+var (
+	paneStyle        = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("8"))
+	focusedPaneStyle = paneStyle.Copy().BorderForeground(lipgloss.Color("14"))
+	titleStyle       = lipgloss.NewStyle().Bold(true)
+	cursorStyle      = lipgloss.NewStyle().Reverse(true)
+	markedStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	faintStyle       = lipgloss.NewStyle().Faint(true)
+	errorStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+)
+
+// browserData is what the browser shows, read from the device when it opens and again
+// after every change
+type browserData struct {
+	storages  []device.StorageInfo
+	songs     []model.Song
+	playlists []model.PlaylistInfo
+}
+
+func loadBrowserData(ctx context.Context, client *bettersync.Client) (*browserData, error) {
+	fmt.Println("Reading songs and playlists...")
+	songs, err := client.Songs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting songs: %w", err)
+	}
+	playlists, err := client.Playlists(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting playlists: %w", err)
+	}
+	return &browserData{
+		storages:  device.ListStorages(client.Storages()),
+		songs:     songs,
+		playlists: playlists,
+	}, nil
+}
+
+// browserItem is one row of a pane. The filter matches text; key identifies the row
+// across reloads.
+type browserItem struct {
+	label string
+	text  string
+	key   string
+}
+
+// browserPane is a scrolling list with its own filter. Marks are kept by key, so they
+// survive filtering and moving between folders.
+type browserPane struct {
+	items   []browserItem
+	visible []int
+	query   string
+	cursor  int
+	offset  int
+	marked  map[string]bool
+}
+
+// setItems replaces the rows, keeping the cursor on the same row when it is still there
+func (p *browserPane) setItems(items []browserItem) {
+	current := p.current()
+	p.items = items
+	p.filter()
+	for i, index := range p.visible {
+		if current != nil && p.items[index].key == current.key {
+			p.cursor = i
+		}
+	}
+}
+
+// filter lists the rows matching the query, best match first
+func (p *browserPane) filter() {
+	type match struct {
+		index int
+		score int
+	}
+	var matches []match
+	for i, item := range p.items {
+		if score, ok := fuzzyScore(p.query, item.text); ok {
+			matches = append(matches, match{i, score})
+		}
+	}
+	if p.query != "" {
+		sort.SliceStable(matches, func(a, b int) bool { return matches[a].score > matches[b].score })
+	}
+
+	p.visible = p.visible[:0]
+	for _, m := range matches {
+		p.visible = append(p.visible, m.index)
+	}
+	p.cursor, p.offset = 0, 0
+}
+
+func (p *browserPane) current() *browserItem {
+	if p.cursor < 0 || p.cursor >= len(p.visible) {
+		return nil
+	}
+	return &p.items[p.visible[p.cursor]]
+}
+
+func (p *browserPane) move(delta int) {
+	p.cursor += delta
+	if p.cursor >= len(p.visible) {
+		p.cursor = len(p.visible) - 1
+	}
+	if p.cursor < 0 {
+		p.cursor = 0
+	}
+}
+
+func (p *browserPane) toggleMark() {
+	item := p.current()
+	if item == nil {
+		return
+	}
+	if p.marked[item.key] {
+		delete(p.marked, item.key)
+	} else {
+		p.marked[item.key] = true
+	}
+}
+
+// markAll marks every row shown, or unmarks them when they are all marked already
+func (p *browserPane) markAll() {
+	all := true
+	for _, index := range p.visible {
+		all = all && p.marked[p.items[index].key]
+	}
+	for _, index := range p.visible {
+		if all {
+			delete(p.marked, p.items[index].key)
+		} else {
+			p.marked[p.items[index].key] = true
+		}
+	}
+}
+
+// fuzzyScore matches the characters of query in order anywhere in text, ignoring case
+// and spaces in the query. Runs of adjacent characters and matches at the start of a
+// word score higher.
+func fuzzyScore(query, text string) (int, bool) {
+	needle := []rune(strings.ToLower(strings.Join(strings.Fields(query), "")))
+	if len(needle) == 0 {
+		return 0, true
+	}
+
+	haystack := []rune(strings.ToLower(text))
+	score, n, last := 0, 0, -2
+	for i, r := range haystack {
+		if n == len(needle) {
+			break
+		}
+		if r != needle[n] {
+			continue
+		}
+		switch {
+		case i == last+1:
+			score += 3
+		case i == 0 || !unicode.IsLetter(haystack[i-1]) && !unicode.IsDigit(haystack[i-1]):
+			score += 2
+		default:
+			score++
+		}
+		last = i
+		n++
+	}
+	if n < len(needle) {
+		return 0, false
+	}
+	return score*100 - len(haystack), true
+}
+
+// browser is the full-screen model: storages and the folder tree pick the songs shown,
+// and the actions run the same operations as the commands
+type browser struct {
+	client *bettersync.Client
+	data   *browserData
+	panes  [paneCount]browserPane
+	focus  int
+
+	width  int
+	height int
+
+	input       int
+	prompt      string
+	promptValue string
+	promptDone  func(string) tea.Cmd
+
+	status    string
+	statusErr bool
+}
+
+func newBrowser(client *bettersync.Client, data *browserData) *browser {
+	b := &browser{client: client, focus: paneSongs}
+	for i := range b.panes {
+		b.panes[i].marked = make(map[string]bool)
+	}
+	b.setData(data)
+	return b
+}
+
+// setData shows newly read device contents, dropping marks of rows that are gone
+func (b *browser) setData(data *browserData) {
+	b.data = data
+
+	storages := []browserItem{{label: "All storages", text: "all", key: ""}}
+	for _, storage := range data.storages {
+		storages = append(storages, browserItem{
+			label: storage.StorageDescription,
+			text:  storage.StorageDescription,
+			key:   strconv.FormatUint(uint64(storage.StorageID), 10),
+		})
+	}
+	b.panes[paneStorages].setItems(storages)
+	b.refresh(paneFolders)
+
+	songs := make(map[string]bool)
+	for _, song := range data.songs {
+		songs[song.Path] = true
+	}
+	for key := range b.panes[paneSongs].marked {
+		if !songs[key] {
+			delete(b.panes[paneSongs].marked, key)
+		}
+	}
+	playlists := make(map[string]bool)
+	for _, playlist := range data.playlists {
+		playlists[playlist.Path] = true
+	}
+	for key := range b.panes[panePlaylists].marked {
+		if !playlists[key] {
+			delete(b.panes[panePlaylists].marked, key)
+		}
+	}
+}
+
+func (b *browser) inStorage(storageID uint32) bool {
+	item := b.panes[paneStorages].current()
+	return item == nil || item.key == "" || item.key == strconv.FormatUint(uint64(storageID), 10)
+}
+
+// refresh rebuilds the panes that depend on the selection in the one before from
+func (b *browser) refresh(from int) {
+	if from <= paneFolders {
+		folders := map[string]bool{}
+		for _, song := range b.data.songs {
+			if !b.inStorage(song.StorageID) {
+				continue
+			}
+			for dir := path.Dir(song.Path); dir != "/" && dir != "." && !folders[dir]; dir = path.Dir(dir) {
+				folders[dir] = true
+			}
+		}
+		var paths []string
+		for dir := range folders {
+			paths = append(paths, dir)
+		}
+		sort.Slice(paths, func(i, j int) bool { return strings.ToLower(paths[i]) < strings.ToLower(paths[j]) })
+
+		items := []browserItem{{label: "All folders", text: "", key: ""}}
+		for _, dir := range paths {
+			depth := strings.Count(dir, "/") - 1
+			items = append(items, browserItem{
+				label: strings.Repeat("  ", depth) + path.Base(dir),
+				text:  dir,
+				key:   dir,
+			})
+		}
+		b.panes[paneFolders].setItems(items)
+
+		var playlists []browserItem
+		for _, playlist := range b.data.playlists {
+			if b.inStorage(playlist.StorageID) {
+				playlists = append(playlists, browserItem{label: playlist.Name, text: playlist.Name, key: playlist.Path})
+			}
+		}
+		b.panes[panePlaylists].setItems(playlists)
+	}
+
+	folder := ""
+	if item := b.panes[paneFolders].current(); item != nil {
+		folder = item.key
+	}
+	var songs []browserItem
+	for _, song := range b.data.songs {
+		if !b.inStorage(song.StorageID) || folder != "" && !strings.HasPrefix(song.Path, folder+"/") {
+			continue
+		}
+		label := strings.TrimPrefix(song.Path, folder+"/")
+		songs = append(songs, browserItem{label: label, text: label, key: song.Path})
+	}
+	b.panes[paneSongs].setItems(songs)
+}
+
+// targets are the keys of the marked rows of a pane, or of the row under the cursor when
+// none is marked
+func (b *browser) targets(pane int) []string {
+	var keys []string
+	for key := range b.panes[pane].marked {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if len(keys) == 0 {
+		if item := b.panes[pane].current(); item != nil {
+			keys = append(keys, item.key)
+		}
+	}
+	return keys
+}
+
+func (b *browser) Init() tea.Cmd {
+	return nil
+}
+
+func (b *browser) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		b.width, b.height = msg.Width, msg.Height
+	case browserTaskDone:
+		b.finishTask(msg)
+	case tea.KeyMsg:
+		switch b.input {
+		case inputFilter:
+			b.updateFilter(msg)
+		case inputPrompt:
+			return b, b.updatePrompt(msg)
+		default:
+			return b, b.updateKeys(msg)
+		}
+	}
+	return b, nil
+}
+
+// editInput applies a key to a line being typed and reports whether it was Enter or Esc
+func editInput(value *string, msg tea.KeyMsg) (done, cancelled bool) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		return true, false
+	case tea.KeyEsc, tea.KeyCtrlC:
+		return false, true
+	case tea.KeyBackspace:
+		if runes := []rune(*value); len(runes) > 0 {
+			*value = string(runes[:len(runes)-1])
+		}
+	case tea.KeyCtrlU:
+		*value = ""
+	case tea.KeyRunes, tea.KeySpace:
+		*value += string(msg.Runes)
+	}
+	return false, false
+}
+
+func (b *browser) updateFilter(msg tea.KeyMsg) {
+	pane := &b.panes[b.focus]
+	done, cancelled := editInput(&pane.query, msg)
+	if cancelled {
+		pane.query = ""
+	}
+	if done || cancelled {
+		b.input = inputNone
+	}
+	pane.filter()
+	if b.focus < paneSongs {
+		b.refresh(b.focus + 1)
+	}
+}
+
+func (b *browser) updatePrompt(msg tea.KeyMsg) tea.Cmd {
+	done, cancelled := editInput(&b.promptValue, msg)
+	if cancelled {
+		b.input = inputNone
+		b.setStatus("Cancelled.", false)
+		return nil
+	}
+	if done {
+		b.input = inputNone
+		return b.promptDone(strings.TrimSpace(b.promptValue))
+	}
+	return nil
+}
+
+func (b *browser) updateKeys(msg tea.KeyMsg) tea.Cmd {
+	pane := &b.panes[b.focus]
+	page := b.paneRows(b.focus)
+
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return tea.Quit
+	case "tab", "right", "l":
+		b.focus = (b.focus + 1) % paneCount
+	case "shift+tab", "left", "h":
+		b.focus = (b.focus + paneCount - 1) % paneCount
+	case "up", "k":
+		pane.move(-1)
+	case "down", "j":
+		pane.move(1)
+	case "pgup":
+		pane.move(-page)
+	case "pgdown":
+		pane.move(page)
+	case "home", "g":
+		pane.move(-len(pane.visible))
+	case "end", "G":
+		pane.move(len(pane.visible))
+	case "/":
+		b.input = inputFilter
+		return nil
+	case "esc":
+		if pane.query != "" {
+			pane.query = ""
+			pane.filter()
+		} else {
+			pane.marked = make(map[string]bool)
+		}
+	case " ":
+		if b.focus >= paneSongs {
+			pane.toggleMark()
+			pane.move(1)
+		}
+	case "*":
+		if b.focus >= paneSongs {
+			pane.markAll()
+		}
+	case "r":
+		return b.runTask(nil, func(*browserData) string { return "Reloaded." })
+	case "d", "D":
+		return b.deleteTargets(msg.String() == "D")
+	case "p":
+		b.addToPlaylist()
+		return nil
+	case "u":
+		b.upload()
+		return nil
+	default:
+		return nil
+	}
+
+	if b.focus < paneSongs {
+		b.refresh(b.focus + 1)
+	}
+	return nil
+}
+
+func (b *browser) ask(prompt, value string, done func(string) tea.Cmd) {
+	b.input = inputPrompt
+	b.prompt, b.promptValue, b.promptDone = prompt, value, done
+}
+
+func (b *browser) setStatus(status string, isErr bool) {
+	b.status, b.statusErr = status, isErr
+}
+
+// deleteTargets deletes the marked songs or playlists, asking first as the commands do
+func (b *browser) deleteTargets(withSongs bool) tea.Cmd {
+	switch b.focus {
+	case paneSongs:
+		paths := b.targets(paneSongs)
+		if len(paths) == 0 {
+			return nil
+		}
+		run := func(ctx context.Context) error {
+			dev, storages := b.client.Device(), b.client.Storages()
+			result, err := operations.RunBulkDelete(ctx, dev, storages, operations.DeleteSelector{Paths: paths}, operations.BulkDeleteOptions{})
+			if result != nil && result.Deleted+len(result.Failed) > 0 {
+				operations.DisplayBulkDeleteResult(result)
+			}
+			return err
+		}
+		return b.runTask(run, func(data *browserData) string {
+			present := make(map[string]bool)
+			for _, song := range data.songs {
+				present[song.Path] = true
+			}
+			return fmt.Sprintf("Deleted %d of %d songs.", countGone(paths, present), len(paths))
+		})
+	case panePlaylists:
+		playlists := b.targets(panePlaylists)
+		if len(playlists) == 0 {
+			return nil
+		}
+		run := func(ctx context.Context) error {
+			for _, playlistPath := range playlists {
+				if err := removePlaylist(ctx, b.client, path.Base(playlistPath), withSongs, false); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return b.runTask(run, func(data *browserData) string {
+			present := make(map[string]bool)
+			for _, playlist := range data.playlists {
+				present[playlist.Path] = true
+			}
+			return fmt.Sprintf("Deleted %d of %d playlists.", countGone(playlists, present), len(playlists))
+		})
+	}
+	b.setStatus("Select songs or playlists to delete.", false)
+	return nil
+}
+
+// addToPlaylist asks for a playlist, suggesting the one under the cursor, and appends
+// the marked songs to it
+func (b *browser) addToPlaylist() {
+	paths := b.targets(paneSongs)
+	if len(paths) == 0 {
+		b.setStatus("Mark the songs to add first.", false)
+		return
+	}
+
+	suggestion := ""
+	if item := b.panes[panePlaylists].current(); item != nil {
+		suggestion = strings.TrimSuffix(item.label, path.Ext(item.label))
+	}
+	b.ask(fmt.Sprintf("Add %d songs to playlist: ", len(paths)), suggestion, func(name string) tea.Cmd {
+		if name == "" {
+			b.setStatus("Cancelled.", false)
+			return nil
+		}
+		run := func(ctx context.Context) error {
+			playlist, err := b.client.AddToPlaylist(ctx, name, paths)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Playlist %s now lists %d songs\n", playlist.Path, len(playlist.SongPaths))
+			b.panes[paneSongs].marked = make(map[string]bool)
+			return nil
+		}
+		return b.runTask(run, func(*browserData) string {
+			return fmt.Sprintf("Added %d songs to %s.", len(paths), name)
+		})
+	})
+}
+
+// upload asks for a local file or folder and uploads it; a folder becomes a playlist
+// named after it, as with upload-dir
+func (b *browser) upload() {
+	b.ask("Upload local file or folder: ", "", func(localPath string) tea.Cmd {
+		localPath = util.ExpandPath(strings.Trim(localPath, "\"'"))
+		info, err := os.Stat(localPath)
+		if err != nil {
+			b.setStatus(err.Error(), true)
+			return nil
+		}
+
+		run := func(ctx context.Context) error {
+			var result *bettersync.UploadResult
+			var err error
+			if info.IsDir() {
+				result, err = b.client.UploadDirectory(ctx, bettersync.UploadOptions{Directory: localPath})
+			} else {
+				result, err = b.client.UploadFiles(ctx, bettersync.UploadOptions{Files: []string{localPath}})
+			}
+			return finishUpload(b.client, result, err)
+		}
+		return b.runTask(run, func(*browserData) string {
+			return "Uploaded " + filepath.Base(localPath) + "."
+		})
+	})
+}
+
+// browserTask runs an action on the normal screen, where it prints and asks for
+// confirmation just as it does from the menu, then reads the device again. Ctrl-C stops
+// the action as it does on the command line. Without an action it only reads the device.
+type browserTask struct {
+	client  *bettersync.Client
+	run     func(ctx context.Context) error
+	summary func(data *browserData) string
+	err     error
+	data    *browserData
+}
+
+type browserTaskDone struct {
+	task *browserTask
+	err  error
+}
+
+func (t *browserTask) SetStdin(io.Reader)  {}
+func (t *browserTask) SetStdout(io.Writer) {}
+func (t *browserTask) SetStderr(io.Writer) {}
+
+func (t *browserTask) Run() error {
+	if t.run != nil {
+		fmt.Println()
+		ctx, stop := interruptContext()
+		t.err = t.run(ctx)
+		stop()
+		if t.err != nil {
+			util.LogError("%v", t.err)
+		}
+	}
+
+	data, err := loadBrowserData(context.Background(), t.client)
+	if err != nil {
+		return err
+	}
+	t.data = data
+
+	if t.run != nil {
+		fmt.Print("\n" + color.HiWhiteString("Press Enter to return to the browser..."))
+		bufio.NewReader(os.Stdin).ReadBytes('\n')
+	}
+	return nil
+}
+
+func (b *browser) runTask(run func(ctx context.Context) error, summary func(data *browserData) string) tea.Cmd {
+	task := &browserTask{client: b.client, run: run, summary: summary}
+	return tea.Exec(task, func(err error) tea.Msg {
+		return browserTaskDone{task: task, err: err}
+	})
+}
+
+func (b *browser) finishTask(done browserTaskDone) {
+	if done.task.data != nil {
+		b.setData(done.task.data)
+	}
+	switch {
+	case done.err != nil:
+		b.setStatus(done.err.Error(), true)
+	case done.task.err != nil:
+		b.setStatus(done.task.err.Error(), true)
+	default:
+		b.setStatus(done.task.summary(done.task.data), false)
+	}
+}
+
+// countGone counts the keys missing from present
+func countGone(keys []string, present map[string]bool) int {
+	gone := 0
+	for _, key := range keys {
+		if !present[key] {
+			gone++
+		}
+	}
+	return gone
+}
+
+// paneSize returns the outer width and height of a pane
+func (b *browser) paneSize(pane int) (int, int) {
+	height := b.height - 2
+	left := b.width / 4
+	right := b.width / 4
+	storagesHeight := len(b.panes[paneStorages].items) + 3
+	if storagesHeight > height/3 {
+		storagesHeight = height / 3
+	}
+	if storagesHeight < 4 {
+		storagesHeight = 4
+	}
+
+	switch pane {
+	case paneStorages:
+		return left, storagesHeight
+	case paneFolders:
+		return left, height - storagesHeight
+	case paneSongs:
+		return b.width - left - right, height
+	default:
+		return right, height
+	}
+}
+
+// paneRows is the number of rows a pane shows below its title
+func (b *browser) paneRows(pane int) int {
+	_, height := b.paneSize(pane)
+	if height < 4 {
+		return 1
+	}
+	return height - 3
+}
+
+func truncate(text string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	return runewidth.FillRight(runewidth.Truncate(text, width, "…"), width)
+}
+
+func (b *browser) renderPane(index int) string {
+	pane := &b.panes[index]
+	width, _ := b.paneSize(index)
+	inner := width - 2
+	rows := b.paneRows(index)
+
+	title := fmt.Sprintf("%s %d", paneTitles[index], len(pane.visible))
+	if len(pane.visible) != len(pane.items) {
+		title = fmt.Sprintf("%s %d/%d", paneTitles[index], len(pane.visible), len(pane.items))
+	}
+	if len(pane.marked) > 0 {
+		title += fmt.Sprintf(", %d marked", len(pane.marked))
+	}
+	if pane.query != "" || b.input == inputFilter && b.focus == index {
+		title += " /" + pane.query
+	}
+	lines := []string{titleStyle.Render(truncate(title, inner))}
+
+	if pane.cursor < pane.offset {
+		pane.offset = pane.cursor
+	}
+	if pane.cursor >= pane.offset+rows {
+		pane.offset = pane.cursor - rows + 1
+	}
+	for i := pane.offset; i < len(pane.visible) && i < pane.offset+rows; i++ {
+		item := pane.items[pane.visible[i]]
+		mark := "  "
+		if pane.marked[item.key] {
+			mark = "● "
+		}
+		line := truncate(mark+item.label, inner)
+		switch {
+		case i == pane.cursor && index == b.focus:
+			line = cursorStyle.Render(line)
+		case i == pane.cursor:
+			line = faintStyle.Copy().Bold(true).Render(line)
+		case pane.marked[item.key]:
+			line = markedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	for len(lines) < rows+1 {
+		lines = append(lines, strings.Repeat(" ", inner))
+	}
+
+	style := paneStyle
+	if index == b.focus {
+		style = focusedPaneStyle
+	}
+	return style.Render(strings.Join(lines, "\n"))
+}
+
+func (b *browser) View() string {
+	if b.width < 40 || b.height < 10 {
+		return "The terminal is too small for the browser. Press q to quit."
+	}
+
+	left := lipgloss.JoinVertical(lipgloss.Left, b.renderPane(paneStorages), b.renderPane(paneFolders))
+	panes := lipgloss.JoinHorizontal(lipgloss.Top, left, b.renderPane(paneSongs), b.renderPane(panePlaylists))
+
+	var footer string
+	switch {
+	case b.input == inputPrompt:
+		footer = b.prompt + b.promptValue + "█"
+	case b.input == inputFilter:
+		footer = "Filter " + paneTitles[b.focus] + ": " + b.panes[b.focus].query + "█  (enter keep, esc clear)"
+	case b.statusErr:
+		footer = errorStyle.Render("Error: " + b.status)
+	default:
+		footer = b.status
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, panes, truncate(footer, b.width), faintStyle.Render(truncate(browseHelp, b.width)))
+}
+
+// runBrowser opens the full-screen browser of the songs and playlists on the device
+func runBrowser(client *bettersync.Client) error {
+	if !util.StdinIsTerminal() {
+		return &exitError{code: exitUsage, err: errors.New("the browser needs a terminal")}
+	}
+
+	data, err := loadBrowserData(context.Background(), client)
+	if err != nil {
+		return err
+	}
+
+	_, err = tea.NewProgram(newBrowser(client, data), tea.WithAltScreen()).Run()
+	return err
+}
@@ -0,0 +1,1152 @@
+// cmd/better-sync/commands.go
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/schachte/better-sync/pkg/bettersync"
+	"github.com/schachte/better-sync/pkg/config"
+	"github.com/schachte/better-sync/pkg/files"
+	"github.com/schachte/better-sync/pkg/model"
+	"github.com/schachte/better-sync/pkg/operations"
+	"github.com/schachte/better-sync/pkg/output"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+const commandUsage = `Commands:
+  songs ls [--durations]
+  songs put [--playlist name] <file>...
+  songs rm [--artist a] [--album a] [--path-glob g] [--older-than 30d] [--larger-than 8MB] [--yes] [device-path...]
+  playlists ls [--songs]
+  playlists create --name name [--replace] <device-path>...
+  playlists rm [--with-songs] [--yes] <name>
+  playlists <rename|copy|smart|refresh|doctor> ...
+  upload-dir [--playlist name] <dir>
+  spotify download [--name name] [--dir dir] [--upload] [--playlist name] <url>
+  wipe --yes
+  config show [--device serial]
+  browse
+  fs ls [path] | get <path> [local] | put <local> [folder] | rm [-r] [--yes] <path>
+  fs mkdir <path> | mv <path> <target>     (all take --storage id|name)
+  shell
+  import-playlist, import-library, upload, export, probe, orphans, cleanup-empty,
+  prune, trash, rm (see README)
+
+Without a command better-sync opens the interactive menu, which needs a terminal.
+Commands never prompt when stdin is not a terminal; ones that delete need --yes.
+
+Exit codes: 0 success, 1 failure, 2 invalid usage, 3 no device, 4 confirmation needed,
+130 interrupted
+`
+
+// printUsage describes the global flags and the commands
+func printUsage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: better-sync [flags] [command]\n\nFlags:\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(out, "\n%s", commandUsage)
+}
+
+// knownCommands are the commands runCommand accepts
+var knownCommands = map[string]bool{
+	"help": true, "songs": true, "playlists": true, "upload-dir": true, "spotify": true, "wipe": true,
+	"playlist": true, "import-playlist": true, "import-library": true, "export": true, "upload": true,
+	"probe": true, "orphans": true, "cleanup-empty": true, "trash": true, "rm": true, "prune": true,
+	"config": true, "browse": true, "fs": true, "shell": true,
+}
+
+// commandNeedsDevice reports whether a command talks to the device; commands that only
+// touch local data, and unknown commands, run without connecting
+func commandNeedsDevice(args []string) bool {
+	switch {
+	case args[0] == "help" || args[0] == "config" || !knownCommands[args[0]]:
+		return false
+	case len(args) >= 2 && (args[0] == "playlist" || args[0] == "playlists") && args[1] == "smart":
+		return false
+	case args[0] == "trash" && (len(args) == 1 || args[1] != "restore"):
+		return false
+	case args[0] == "spotify":
+		return hasFlag(args[1:], "upload")
+	}
+	return true
+}
+
+// hasFlag reports whether a boolean flag is set in args, before they are parsed
+func hasFlag(args []string, name string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		switch strings.TrimLeft(arg, "-") {
+		case name, name + "=true":
+			return strings.HasPrefix(arg, "-")
+		}
+	}
+	return false
+}
+
+// runCommand executes a command given as positional arguments instead of showing the
+// menu; client is nil for commands that do not need the device
+func runCommand(client *bettersync.Client, args []string) error {
+	var dev *mtp.Device
+	var storages interface{}
+	if client != nil {
+		dev, storages = client.Device(), client.Storages()
+	}
+
+	switch args[0] {
+	case "help":
+		printUsage()
+		return nil
+	case "songs":
+		return runSongsCommand(client, args[1:])
+	case "playlists":
+		return runPlaylistsCommand(client, args[1:])
+	case "upload-dir":
+		return runUploadDirCommand(client, args[1:])
+	case "spotify":
+		return runSpotifyCommand(client, args[1:])
+	case "wipe":
+		return runWipeCommand(client, args[1:])
+	case "config":
+		return runConfigCommand(args[1:])
+	case "browse":
+		if len(args) != 1 {
+			return usageErrorf("browse")
+		}
+		return runBrowser(client)
+	case "fs":
+		return runFsCommand(client, args[1:])
+	case "shell":
+		if len(args) != 1 {
+			return usageErrorf("shell")
+		}
+		return runShell(client)
+	case "playlist":
+		return runPlaylistCommand(dev, storages, args[1:])
+	case "import-playlist":
+		return runImportPlaylistCommand(dev, storages, args[1:])
+	case "import-library":
+		return runImportLibraryCommand(dev, storages, args[1:])
+	case "export":
+		return runExportCommand(dev, storages, args[1:])
+	case "upload":
+		return runUploadCommand(dev, storages, args[1:])
+	case "probe":
+		return runProbeCommand(dev, storages, args[1:])
+	case "orphans":
+		return runOrphansCommand(dev, storages, args[1:])
+	case "cleanup-empty":
+		return runCleanupEmptyCommand(dev, storages, args[1:])
+	case "trash":
+		return runTrashCommand(dev, storages, args[1:])
+	case "rm":
+		return runRmCommand(dev, storages, args[1:])
+	case "prune":
+		if len(args) != 1 {
+			return usageErrorf("prune")
+		}
+		result, err := operations.PruneEmptyFolders(dev, storages)
+		if result != nil {
+			if outputFormat.Structured() {
+				folders := output.PrunedFolders(result)
+				if writeErr := writeResult("prune", folders, folders); writeErr != nil {
+					return writeErr
+				}
+			} else {
+				operations.DisplayPruneResult(result)
+			}
+		}
+		return err
+	default:
+		return unknownCommandError("command", args[0])
+	}
+}
+
+func runSongsCommand(client *bettersync.Client, args []string) error {
+	ctx := context.Background()
+
+	if len(args) == 0 {
+		return usageErrorf("songs <ls|put|rm> ...")
+	}
+
+	switch args[0] {
+	case "ls":
+		flags := flag.NewFlagSet("songs ls", flag.ContinueOnError)
+		withDurations := flags.Bool("durations", false, "Read the length of every song (slower)")
+		if err := parseFlags(flags, args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 0 {
+			return usageErrorf("songs ls [--durations]")
+		}
+
+		songs, err := client.Songs(ctx)
+		if err != nil {
+			return err
+		}
+		var durations map[uint32]time.Duration
+		if *withDurations {
+			if durations, err = client.SongDurations(ctx, songs); err != nil {
+				return err
+			}
+		}
+		if outputFormat.Structured() {
+			records := output.Songs(songs, durations)
+			return writeResult("songs", records, records)
+		}
+		operations.DisplaySongsToConsole(songs, durations)
+	case "put":
+		flags := flag.NewFlagSet("songs put", flag.ContinueOnError)
+		playlist := flags.String("playlist", "", "Also create a playlist of the uploaded songs")
+		if err := parseFlags(flags, args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() == 0 {
+			return usageErrorf("songs put [--playlist name] <file>...")
+		}
+		for _, path := range flags.Args() {
+			if info, err := os.Stat(path); err != nil {
+				return err
+			} else if info.IsDir() {
+				return usageErrorf("%s is a directory, use upload-dir", path)
+			}
+		}
+
+		return runUpload(client, func(ctx context.Context) (*bettersync.UploadResult, error) {
+			return client.UploadFiles(ctx, bettersync.UploadOptions{Files: flags.Args(), Playlist: *playlist})
+		})
+	case "rm":
+		return runRmCommand(client.Device(), client.Storages(), args[1:])
+	default:
+		return unknownCommandError("songs command", args[0])
+	}
+
+	return nil
+}
+
+func runPlaylistsCommand(client *bettersync.Client, args []string) error {
+	ctx := context.Background()
+
+	if len(args) == 0 {
+		return usageErrorf("playlists <ls|create|rm|rename|copy|smart|refresh|doctor> ...")
+	}
+
+	switch args[0] {
+	case "ls":
+		flags := flag.NewFlagSet("playlists ls", flag.ContinueOnError)
+		withSongs := flags.Bool("songs", false, "List the songs of each playlist")
+		if err := parseFlags(flags, args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 0 {
+			return usageErrorf("playlists ls [--songs]")
+		}
+
+		if *withSongs {
+			result, err := client.PlaylistsWithSongs(ctx)
+			if err != nil {
+				return err
+			}
+			if outputFormat.Structured() {
+				playlists, rows := output.DevicePlaylistsOf(result)
+				return writeResult("devicePlaylists", playlists, rows)
+			}
+			operations.PrintPlaylistsAndSongs(client.Device(), result)
+			return nil
+		}
+		playlists, err := client.Playlists(ctx)
+		if err != nil {
+			return err
+		}
+		if outputFormat.Structured() {
+			records := output.Playlists(playlists)
+			return writeResult("playlists", records, records)
+		}
+		operations.DisplayPlaylistsToConsole(playlists)
+	case "create":
+		flags := flag.NewFlagSet("playlists create", flag.ContinueOnError)
+		name := flags.String("name", "", "Name of the playlist")
+		replace := flags.Bool("replace", false, "Replace a device playlist with the same name")
+		if err := parseFlags(flags, args[1:]); err != nil {
+			return err
+		}
+		if *name == "" || flags.NArg() == 0 {
+			return usageErrorf("playlists create --name name [--replace] <device-path>...")
+		}
+
+		playlist, err := client.CreatePlaylist(ctx, bettersync.PlaylistOptions{
+			Name:    *name,
+			Songs:   flags.Args(),
+			Replace: *replace,
+		})
+		if err != nil {
+			return err
+		}
+		if outputFormat.Structured() {
+			record := output.PlaylistFileOf(playlist)
+			return writeResult("playlist", record, record)
+		}
+		fmt.Printf("Created playlist %s with %d songs\n", playlist.Path, len(playlist.SongPaths))
+	case "rm":
+		flags := flag.NewFlagSet("playlists rm", flag.ContinueOnError)
+		withSongs := flags.Bool("with-songs", false, "Also delete the songs no other playlist uses")
+		assumeYes := flags.Bool("yes", false, "Delete without asking for confirmation")
+		if err := parseFlags(flags, args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return usageErrorf("playlists rm [--with-songs] [--yes] <name>")
+		}
+		if err := requireConfirmation(*assumeYes); err != nil {
+			return err
+		}
+		ctx, stop := interruptContext()
+		defer stop()
+		return removePlaylist(ctx, client, flags.Arg(0), *withSongs, *assumeYes)
+	default:
+		var dev *mtp.Device
+		var storages interface{}
+		if client != nil {
+			dev, storages = client.Device(), client.Storages()
+		}
+		return runPlaylistCommand(dev, storages, args)
+	}
+
+	return nil
+}
+
+// removePlaylist deletes a playlist after showing what goes with it and asking, unless
+// assumeYes is set
+func removePlaylist(ctx context.Context, client *bettersync.Client, name string, withSongs, assumeYes bool) error {
+	var plan *operations.PlaylistDeletionPlan
+	prompt := fmt.Sprintf("Delete playlist '%s'? (y/n): ", name)
+	if withSongs {
+		var err error
+		if plan, err = client.PlanPlaylistDeletion(ctx, name); err != nil {
+			return err
+		}
+		operations.DisplayPlaylistDeletionPlan(plan)
+		prompt = fmt.Sprintf("\nDelete playlist '%s' and %d songs? (y/n): ", name, len(plan.Delete))
+	}
+
+	if !assumeYes {
+		fmt.Print(prompt)
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Scan()
+		if strings.ToLower(strings.TrimSpace(scanner.Text())) != "y" {
+			fmt.Println("Operation cancelled.")
+			return nil
+		}
+	}
+
+	deletion, err := client.DeletePlaylist(ctx, bettersync.DeletePlaylistOptions{
+		Name:      name,
+		WithSongs: withSongs,
+		Plan:      plan,
+	})
+	if err != nil {
+		return err
+	}
+	if plan == nil {
+		fmt.Printf("Deleted playlist %s\n", deletion.Playlist.Path)
+	}
+
+	if outputFormat.Structured() {
+		record := output.PlaylistDeletionOf(deletion.Playlist, deletion.Plan, deletion.DeletedSongs)
+		return writeResult("playlistDeletion", record, record)
+	}
+	return nil
+}
+
+func runUploadDirCommand(client *bettersync.Client, args []string) error {
+	flags := flag.NewFlagSet("upload-dir", flag.ContinueOnError)
+	playlist := flags.String("playlist", "", "Name of the playlist created for the upload (default: directory name)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return usageErrorf("upload-dir [--playlist name] <dir>")
+	}
+
+	return runUpload(client, func(ctx context.Context) (*bettersync.UploadResult, error) {
+		return client.UploadDirectory(ctx, bettersync.UploadOptions{Directory: flags.Arg(0), Playlist: *playlist})
+	})
+}
+
+func runSpotifyCommand(client *bettersync.Client, args []string) error {
+	usage := usageErrorf("spotify download [--name name] [--dir dir] [--upload] [--playlist name] <url>")
+	if len(args) == 0 || args[0] != "download" {
+		return usage
+	}
+
+	flags := flag.NewFlagSet("spotify download", flag.ContinueOnError)
+	name := flags.String("name", "", "Folder name for the download (default: the Spotify playlist name)")
+	dir := flags.String("dir", "", "Where to create the playlist folder (default: the download_dir setting)")
+	upload := flags.Bool("upload", false, "Upload the downloaded tracks and create a playlist")
+	playlist := flags.String("playlist", "", "Name of the playlist created by --upload (default: folder name)")
+	if err := parseFlags(flags, args[1:]); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return usage
+	}
+
+	ctx := context.Background()
+	destDir, err := bettersync.DownloadSpotify(ctx, bettersync.DownloadOptions{
+		URL:       flags.Arg(0),
+		Name:      *name,
+		Directory: *dir,
+		Output:    os.Stdout,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Downloaded to %s\n", destDir)
+
+	if !*upload {
+		if outputFormat.Structured() {
+			record := output.Download{URL: flags.Arg(0), Directory: destDir}
+			return writeResult("download", record, record)
+		}
+		return nil
+	}
+	return runUpload(client, func(ctx context.Context) (*bettersync.UploadResult, error) {
+		return client.UploadDirectory(ctx, bettersync.UploadOptions{Directory: destDir, Playlist: *playlist})
+	})
+}
+
+func runWipeCommand(client *bettersync.Client, args []string) error {
+	flags := flag.NewFlagSet("wipe", flag.ContinueOnError)
+	assumeYes := flags.Bool("yes", false, "Delete everything without asking for confirmation")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return usageErrorf("wipe --yes")
+	}
+	if err := requireConfirmation(*assumeYes); err != nil {
+		return err
+	}
+
+	if !*assumeYes {
+		fmt.Print("\n⚠️ WARNING: This deletes every song and playlist in the Music folder.\nType 'yes' to confirm: ")
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Scan()
+		if strings.ToLower(strings.TrimSpace(scanner.Text())) != "yes" {
+			fmt.Println("Operation cancelled.")
+			return nil
+		}
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+	if err := client.Wipe(ctx); err != nil {
+		return err
+	}
+	fmt.Println("Deleted the contents of the Music folder")
+	return nil
+}
+
+// uploadResultError prints the outcome of an upload and returns its error
+func uploadResultError(result *bettersync.UploadResult, err error) error {
+	if result == nil {
+		return err
+	}
+
+	if outputFormat.Structured() {
+		upload, rows := output.UploadOf(result)
+		if err := writeResult("upload", upload, rows); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("Uploaded %d files\n", len(result.UploadedFiles))
+		if result.Playlist != nil {
+			fmt.Printf("Created playlist %s\n", result.Playlist.Path)
+		}
+		for _, message := range result.Errors {
+			util.LogError("%s", message)
+		}
+		if result.Interrupted {
+			color.HiYellow("Not uploaded: %d files (interrupted)", len(result.NotUploaded))
+			for _, localPath := range result.NotUploaded {
+				fmt.Printf("  %s\n", localPath)
+			}
+		}
+	}
+
+	return err
+}
+
+func runPlaylistCommand(dev *mtp.Device, storages interface{}, args []string) error {
+	if len(args) == 0 {
+		return usageErrorf("playlist <rename|copy|smart|refresh|doctor> ...")
+	}
+
+	switch args[0] {
+	case "rename":
+		if len(args) != 3 {
+			return usageErrorf("playlist rename <old> <new>")
+		}
+		ctx, stop := interruptContext()
+		playlist, err := operations.RenameDevicePlaylist(ctx, dev, storages, args[1], args[2])
+		stop()
+		if err != nil {
+			return err
+		}
+		if outputFormat.Structured() {
+			record := output.PlaylistRenameOf(args[1], *playlist)
+			return writeResult("playlistRename", record, record)
+		}
+		fmt.Printf("Renamed playlist to %s\n", playlist.Path)
+	case "copy":
+		if len(args) != 3 {
+			return usageErrorf("playlist copy <src> <dst>")
+		}
+		ctx, stop := interruptContext()
+		playlist, err := operations.CopyDevicePlaylist(ctx, dev, storages, args[1], args[2])
+		stop()
+		if err != nil {
+			return err
+		}
+		if outputFormat.Structured() {
+			record := output.PlaylistRenameOf(args[1], *playlist)
+			return writeResult("playlistCopy", record, record)
+		}
+		fmt.Printf("Copied playlist to %s\n", playlist.Path)
+	case "smart":
+		return runSmartPlaylistCommand(args[1:])
+	case "doctor":
+		flags := flag.NewFlagSet("playlist doctor", flag.ContinueOnError)
+		assumeYes := flags.Bool("yes", false, "Apply fixes without asking")
+		keepBroken := flags.Bool("keep-broken", false, "Keep entries that cannot be resolved")
+		if err := parseFlags(flags, args[1:]); err != nil {
+			return err
+		}
+		if err := requireConfirmation(*assumeYes); err != nil {
+			return err
+		}
+		ctx, stop := interruptContext()
+		checks, err := operations.RunPlaylistDoctor(ctx, dev, storages, flags.Args(), operations.PlaylistRepairOptions{
+			AssumeYes:  *assumeYes,
+			KeepBroken: *keepBroken,
+		})
+		stop()
+		if outputFormat.Structured() && checks != nil {
+			repairs, rows := output.PlaylistRepairsOf(checks)
+			if writeErr := writeResult("playlistRepairs", repairs, rows); writeErr != nil {
+				return writeErr
+			}
+		}
+		return err
+	case "refresh":
+		ctx, stop := interruptContext()
+		results, err := operations.RefreshSmartPlaylists(ctx, dev, storages, args[1:])
+		stop()
+		if outputFormat.Structured() && results != nil {
+			records := output.SmartRefreshes(results)
+			if writeErr := writeResult("smartRefresh", records, records); writeErr != nil {
+				return writeErr
+			}
+		} else if len(results) > 0 {
+			operations.DisplaySmartRefreshResults(results)
+		}
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			if result.Status == operations.SmartStatusFailed {
+				return fmt.Errorf("failed to refresh smart playlist %s", result.Name)
+			}
+		}
+	default:
+		return unknownCommandError("playlist command", args[0])
+	}
+
+	return nil
+}
+
+func runSmartPlaylistCommand(args []string) error {
+	if len(args) == 0 {
+		return usageErrorf("playlist smart <ls|add|rm> ...")
+	}
+
+	switch args[0] {
+	case "ls":
+		definitions, err := files.LoadSmartPlaylists()
+		if err != nil {
+			return err
+		}
+		operations.DisplaySmartPlaylistsToConsole(definitions)
+	case "add":
+		flags := flag.NewFlagSet("playlist smart add", flag.ContinueOnError)
+		matchAny := flags.Bool("any", false, "Include tracks matching any rule instead of all rules")
+		sortBy := flags.String("sort", "", "Sort by field, prefix with - for descending (e.g. -added)")
+		limit := flags.Int("limit", 0, "Maximum number of tracks (0 for no limit)")
+		format := flags.String("format", "", "Playlist format written to the device: m3u8 or pls")
+		if err := parseFlags(flags, args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() < 2 {
+			return usageErrorf("playlist smart add [--any] [--sort field] [--limit n] [--format m3u8|pls] <name> <rule>...")
+		}
+
+		definition := model.SmartPlaylist{
+			Name:     flags.Arg(0),
+			MatchAny: *matchAny,
+			Sort:     *sortBy,
+			Limit:    *limit,
+			Format:   *format,
+		}
+		for _, expr := range flags.Args()[1:] {
+			rule, err := operations.ParseSmartRule(expr)
+			if err != nil {
+				return err
+			}
+			definition.Rules = append(definition.Rules, rule)
+		}
+
+		if err := operations.AddSmartPlaylist(definition); err != nil {
+			return err
+		}
+		fmt.Printf("Saved smart playlist %s. Run 'playlist refresh' to write it to the device.\n", definition.Name)
+	case "rm":
+		if len(args) != 2 {
+			return usageErrorf("playlist smart rm <name>")
+		}
+		if err := operations.RemoveSmartPlaylist(args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Removed smart playlist %s\n", args[1])
+	default:
+		return unknownCommandError("smart playlist command", args[0])
+	}
+
+	return nil
+}
+
+func runImportPlaylistCommand(dev *mtp.Device, storages interface{}, args []string) error {
+	flags := flag.NewFlagSet("import-playlist", flag.ContinueOnError)
+	name := flags.String("name", "", "Name of the playlist on the device (default: file name)")
+	replace := flags.Bool("replace", false, "Replace a device playlist with the same name")
+	formatName := flags.String("format", "", "Playlist format written to the device: m3u8 or pls")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	format := ""
+	if *formatName != "" {
+		var err error
+		if format, err = files.ParsePlaylistFormat(*formatName); err != nil {
+			return err
+		}
+	}
+	if flags.NArg() != 1 {
+		return usageErrorf("import-playlist [--name name] [--replace] [--format m3u8|pls] <playlist>")
+	}
+
+	ctx, stop := interruptContext()
+	result, err := operations.ImportLocalPlaylist(ctx, dev, storages, flags.Arg(0), operations.ImportOptions{
+		Name:    *name,
+		Replace: *replace,
+		Format:  format,
+	})
+	stop()
+	if result != nil {
+		if writeErr := writeImportResults([]*operations.PlaylistImportResult{result}); writeErr != nil {
+			return writeErr
+		}
+	}
+	return err
+}
+
+func runImportLibraryCommand(dev *mtp.Device, storages interface{}, args []string) error {
+	flags := flag.NewFlagSet("import-library", flag.ContinueOnError)
+	var names stringList
+	flags.Var(&names, "playlist", "Library playlist to import (repeatable); default is to choose interactively")
+	all := flags.Bool("all", false, "Import every playlist in the library")
+	replace := flags.Bool("replace", false, "Replace device playlists with the same name")
+	formatName := flags.String("format", "", "Playlist format written to the device: m3u8 or pls")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return usageErrorf("import-library [--playlist name]... [--all] [--replace] [--format m3u8|pls] <Library.xml>")
+	}
+
+	format := ""
+	if *formatName != "" {
+		var err error
+		if format, err = files.ParsePlaylistFormat(*formatName); err != nil {
+			return err
+		}
+	}
+
+	libraryPlaylists, err := files.ReadITunesLibrary(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	selected := libraryPlaylists
+	if !*all {
+		if len(names) == 0 && !util.StdinIsTerminal() {
+			return usageErrorf("stdin is not a terminal: choose playlists with --playlist or --all")
+		}
+		selected, err = operations.SelectLibraryPlaylists(libraryPlaylists, names, bufio.NewScanner(os.Stdin))
+		if err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := interruptContext()
+	results, err := operations.ImportLibraryPlaylists(ctx, dev, storages, flags.Arg(0), selected, operations.ImportOptions{
+		Replace: *replace,
+		Format:  format,
+	})
+	stop()
+	if writeErr := writeImportResults(results); writeErr != nil {
+		return writeErr
+	}
+	return err
+}
+
+// writeImportResults writes imported playlists with --output, or shows them
+func writeImportResults(results []*operations.PlaylistImportResult) error {
+	if outputFormat.Structured() {
+		imports, rows := output.PlaylistImportsOf(results)
+		return writeResult("playlistImports", imports, rows)
+	}
+	for _, result := range results {
+		operations.DisplayImportResult(result)
+	}
+	return nil
+}
+
+func runExportCommand(dev *mtp.Device, storages interface{}, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	var playlists stringList
+	flags.Var(&playlists, "playlist", "Playlist to export (repeatable); default is the whole Music folder")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return usageErrorf("export [--playlist name]... <directory>")
+	}
+
+	ctx, stop := interruptContext()
+	result, err := operations.ExportFromDevice(ctx, dev, storages, operations.ExportOptions{
+		Destination: flags.Arg(0),
+		Playlists:   playlists,
+	})
+	stop()
+	if result != nil && outputFormat.Structured() {
+		record := output.ExportOf(result)
+		if writeErr := writeResult("export", record, record); writeErr != nil {
+			return writeErr
+		}
+	} else if result != nil {
+		operations.DisplayExportResult(result)
+	}
+	if err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%d files could not be exported", len(result.Errors))
+	}
+	return nil
+}
+
+func runUploadCommand(dev *mtp.Device, storages interface{}, args []string) error {
+	flags := flag.NewFlagSet("upload", flag.ContinueOnError)
+	source := flags.String("source", "", "Where to take tracks from, e.g. beets:/path/library.db")
+	playlist := flags.String("playlist", "", "Write the uploaded tracks to this playlist")
+	replace := flags.Bool("replace", false, "Replace a device playlist with the same name")
+	formatName := flags.String("format", "", "Playlist format written to the device: m3u8 or pls")
+	assumeYes := flags.Bool("yes", false, "Upload without asking for confirmation")
+	// beets queries may start with "-", so flags have to come before the query
+	if err := flags.Parse(args); err != nil {
+		return flagError(err)
+	}
+
+	format := ""
+	if *formatName != "" {
+		var err error
+		if format, err = files.ParsePlaylistFormat(*formatName); err != nil {
+			return err
+		}
+	}
+	dbPath, ok := strings.CutPrefix(*source, "beets:")
+	if !ok || dbPath == "" {
+		return usageErrorf("upload --source beets:<library.db> [--playlist name] [--replace] [--format m3u8|pls] [--yes] [query...]")
+	}
+	if err := requireConfirmation(*assumeYes); err != nil {
+		return err
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+	result, err := operations.UploadFromBeets(ctx, dev, storages, dbPath, flags.Args(), operations.BeetsUploadOptions{
+		Playlist:  *playlist,
+		Replace:   *replace,
+		Format:    format,
+		AssumeYes: *assumeYes,
+	})
+	if result != nil {
+		if writeErr := writeImportResults([]*operations.PlaylistImportResult{result}); writeErr != nil {
+			return writeErr
+		}
+	}
+	return err
+}
+
+func runProbeCommand(dev *mtp.Device, storages interface{}, args []string) error {
+	flags := flag.NewFlagSet("probe", flag.ContinueOnError)
+	selectStyle := flags.Int("select", 0, "Remember this path style (1-4) for the device and remove the probe playlists")
+	show := flags.Bool("show", false, "Only show the path style in use")
+	clean := flags.Bool("clean", false, "Remove the probe playlists")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return usageErrorf("probe [--show | --select n | --clean]")
+	}
+
+	switch {
+	case *show:
+		operations.DisplayPathStyle(operations.ResolvePathStyle(dev))
+		return nil
+	case *selectStyle != 0:
+		return operations.SelectProbedPathStyle(dev, storages, *selectStyle)
+	case *clean:
+		removed, err := operations.RemoveProbePlaylists(dev, storages)
+		fmt.Printf("Removed %d probe playlists\n", removed)
+		return err
+	}
+
+	operations.DisplayPathStyle(operations.ResolvePathStyle(dev))
+	probes, err := operations.WriteProbePlaylists(dev, storages)
+	if err != nil {
+		return err
+	}
+	operations.DisplayProbePlaylists(probes)
+	return nil
+}
+
+func runOrphansCommand(dev *mtp.Device, storages interface{}, args []string) error {
+	flags := flag.NewFlagSet("orphans", flag.ContinueOnError)
+	deleteOrphans := flags.Bool("delete", false, "Delete the orphaned songs after one confirmation")
+	assumeYes := flags.Bool("yes", false, "Delete without asking for confirmation")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return usageErrorf("orphans [--delete] [--yes]")
+	}
+	if *deleteOrphans {
+		if err := requireConfirmation(*assumeYes); err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := interruptContext()
+	report, err := operations.RunOrphanCleanup(ctx, dev, storages, operations.OrphanOptions{
+		Delete:    *deleteOrphans,
+		AssumeYes: *assumeYes,
+	})
+	stop()
+	if report != nil && outputFormat.Structured() {
+		orphans := output.OrphansOf(report)
+		if writeErr := writeResult("orphans", orphans, orphans.Orphans); writeErr != nil {
+			return writeErr
+		}
+	}
+	return err
+}
+
+func runCleanupEmptyCommand(dev *mtp.Device, storages interface{}, args []string) error {
+	flags := flag.NewFlagSet("cleanup-empty", flag.ContinueOnError)
+	var searchDirs stringList
+	flags.Var(&searchDirs, "search-dir", "Folder searched by file name for the original files (repeatable)")
+	reupload := flags.Bool("reupload", false, "Re-upload tracks whose original file was found")
+	assumeYes := flags.Bool("yes", false, "Delete without asking for confirmation")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return usageErrorf("cleanup-empty [--search-dir dir]... [--reupload] [--yes]")
+	}
+	if err := requireConfirmation(*assumeYes); err != nil {
+		return err
+	}
+
+	ctx, stop := interruptContext()
+	report, err := operations.RunEmptyCleanup(ctx, dev, storages, operations.EmptyCleanupOptions{
+		SearchDirs: searchDirs,
+		Reupload:   *reupload,
+		AssumeYes:  *assumeYes,
+	})
+	stop()
+	if report != nil && outputFormat.Structured() {
+		records := output.BrokenTracks(report)
+		if writeErr := writeResult("brokenTracks", records, records); writeErr != nil {
+			return writeErr
+		}
+	}
+	return err
+}
+
+// runRmCommand deletes songs by selector or by device path; it backs both "rm" and
+// "songs rm"
+func runRmCommand(dev *mtp.Device, storages interface{}, args []string) error {
+	flags := flag.NewFlagSet("songs rm", flag.ContinueOnError)
+	artist := flags.String("artist", "", "Delete songs by this artist")
+	album := flags.String("album", "", "Delete songs from this album")
+	pathGlob := flags.String("path-glob", "", "Delete songs whose device path matches, e.g. '/MUSIC/PODCAST*/**'")
+	olderThan := flags.String("older-than", "", "Delete songs added longer ago than this, e.g. 90d")
+	largerThan := flags.String("larger-than", "", "Delete songs bigger than this, e.g. 8MB")
+	assumeYes := flags.Bool("yes", false, "Delete without asking for confirmation")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	selector := operations.DeleteSelector{
+		Artist:   *artist,
+		Album:    *album,
+		PathGlob: *pathGlob,
+		Paths:    flags.Args(),
+	}
+	if *olderThan != "" {
+		age, err := operations.ParseAge(*olderThan)
+		if err != nil {
+			return err
+		}
+		selector.OlderThan = age
+	}
+	if *largerThan != "" {
+		size, err := util.ParseSize(*largerThan)
+		if err != nil {
+			return err
+		}
+		selector.LargerThan = size
+	}
+	if selector.IsEmpty() {
+		return usageErrorf("songs rm [--artist a] [--album a] [--path-glob g] [--older-than 30d] [--larger-than 8MB] [--yes] [device-path...]")
+	}
+	if err := requireConfirmation(*assumeYes); err != nil {
+		return err
+	}
+
+	ctx, stop := interruptContext()
+	result, err := operations.RunBulkDelete(ctx, dev, storages, selector, operations.BulkDeleteOptions{AssumeYes: *assumeYes})
+	stop()
+	if result != nil && outputFormat.Structured() {
+		deletion := output.DeletionOf(result)
+		if writeErr := writeResult("deletion", deletion, deletion.Songs); writeErr != nil {
+			return writeErr
+		}
+	} else if result != nil && result.Deleted+len(result.Failed) > 0 {
+		operations.DisplayBulkDeleteResult(result)
+	}
+	return err
+}
+
+func runTrashCommand(dev *mtp.Device, storages interface{}, args []string) error {
+	usage := usageErrorf("trash <list|restore <id>...|empty [--older-than 30d]>")
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "list":
+		entries, err := files.LoadTrash()
+		if err != nil {
+			return err
+		}
+		if outputFormat.Structured() {
+			if entries == nil {
+				entries = make([]model.TrashEntry, 0)
+			}
+			return writeResult("trash", entries, entries)
+		}
+		operations.DisplayTrash(entries)
+	case "restore":
+		if len(args) < 2 {
+			return usage
+		}
+		ids := make([]int, 0, len(args)-1)
+		for _, arg := range args[1:] {
+			id, err := strconv.Atoi(arg)
+			if err != nil {
+				return fmt.Errorf("invalid trash entry %q", arg)
+			}
+			ids = append(ids, id)
+		}
+
+		ctx, stop := interruptContext()
+		records := make([]output.TrashRestore, 0, len(ids))
+		var restoreErr error
+		for _, id := range ids {
+			if restoreErr != nil {
+				records = append(records, output.TrashRestore{ID: id, Status: output.RestoreStatusNotRestored})
+				continue
+			}
+			entry, err := operations.RestoreFromTrash(ctx, dev, storages, id)
+			if err != nil {
+				restoreErr = fmt.Errorf("error restoring #%d: %w", id, err)
+				record := output.TrashRestore{ID: id, Status: output.RestoreStatusFailed, Error: err.Error()}
+				if errors.Is(err, operations.ErrInterrupted) {
+					record.Status = output.RestoreStatusNotRestored
+				}
+				records = append(records, record)
+				continue
+			}
+			records = append(records, output.TrashRestore{
+				ID:         id,
+				Status:     output.RestoreStatusRestored,
+				DevicePath: entry.DevicePath,
+				StorageID:  entry.StorageID,
+			})
+			fmt.Printf("Restored %s\n", entry.DevicePath)
+		}
+		stop()
+
+		if outputFormat.Structured() {
+			if err := writeResult("trashRestore", records, records); err != nil {
+				return err
+			}
+		}
+		return restoreErr
+	case "empty":
+		flags := flag.NewFlagSet("trash empty", flag.ContinueOnError)
+		olderThan := flags.String("older-than", "", "Only remove items deleted longer ago than this, e.g. 30d")
+		if err := parseFlags(flags, args[1:]); err != nil {
+			return err
+		}
+
+		var age time.Duration
+		if *olderThan != "" {
+			var err error
+			if age, err = operations.ParseAge(*olderThan); err != nil {
+				return err
+			}
+		}
+
+		removed, size, err := operations.EmptyTrash(age)
+		if err != nil {
+			return err
+		}
+		if outputFormat.Structured() {
+			record := output.TrashEmpty{Removed: removed, Size: size}
+			return writeResult("trashEmpty", record, record)
+		}
+		fmt.Printf("Removed %d items (%s) from the trash\n", removed, util.FormatSize(size))
+	default:
+		return usage
+	}
+	return nil
+}
+
+// runConfigCommand shows the effective settings and where each one was set
+func runConfigCommand(args []string) error {
+	usage := usageErrorf("config show [--device serial]")
+	if len(args) == 0 || args[0] != "show" {
+		return usage
+	}
+
+	flags := flag.NewFlagSet("config show", flag.ContinueOnError)
+	serial := flags.String("device", "", "Apply the overrides of the device with this serial number")
+	if err := parseFlags(flags, args[1:]); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return usage
+	}
+	if *serial != "" {
+		config.SelectDevice(*serial)
+	}
+
+	values := config.Values()
+	if outputFormat.Structured() {
+		records := output.Settings(values)
+		return writeResult("config", records, records)
+	}
+
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		path += " (not found)"
+	}
+	fmt.Printf("Config file: %s\n", path)
+	if devices := config.Devices(); len(devices) > 0 {
+		fmt.Printf("Device overrides: %s\n", strings.Join(devices, ", "))
+	}
+	if *serial != "" {
+		fmt.Printf("Showing settings for device %s\n", *serial)
+	}
+
+	sourceColor := color.New(color.Faint).SprintFunc()
+	fmt.Println()
+	for _, value := range values {
+		fmt.Printf("%-22s %-30s %s\n", value.Key, value.Display(), sourceColor(value.Source))
+	}
+	return nil
+}
+
+// stringList collects a flag that may be given several times
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// parseFlags parses a command's flags, which may also follow its positional arguments,
+// e.g. "upload-dir ~/Music/Mix --playlist Mix". Arguments after "--" are positional.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	var ordered, positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			positional = append(positional, arg)
+			continue
+		}
+
+		ordered = append(ordered, arg)
+		name := strings.TrimLeft(arg, "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		if f := flags.Lookup(name); f != nil {
+			if boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && boolFlag.IsBoolFlag() {
+				continue
+			}
+			if i+1 < len(args) {
+				i++
+				ordered = append(ordered, args[i])
+			}
+		}
+	}
+
+	ordered = append(append(ordered, "--"), positional...)
+	return flagError(flags.Parse(ordered))
+}
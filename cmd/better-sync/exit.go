@@ -0,0 +1,94 @@
+// cmd/better-sync/exit.go
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/schachte/better-sync/pkg/bettersync"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+// Exit codes returned to scripts
+const (
+	exitOK = 0
+	// exitFailure means the command ran but failed, fully or for some items
+	exitFailure = 1
+	// exitUsage means the command line was invalid
+	exitUsage = 2
+	// exitNoDevice means no MTP device could be opened
+	exitNoDevice = 3
+	// exitNeedsConfirmation means the command would have asked for confirmation but
+	// stdin is not a terminal; rerun it with --yes
+	exitNeedsConfirmation = 4
+	// exitInterrupted means Ctrl-C stopped the command before it finished
+	exitInterrupted = 130
+)
+
+// exitError is an error that ends the program with a specific exit code
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// usageErrorf reports an invalid command line
+func usageErrorf(format string, args ...interface{}) error {
+	return &exitError{code: exitUsage, err: fmt.Errorf("usage: "+format, args...)}
+}
+
+// requireConfirmation fails commands that would prompt when nobody can answer,
+// instead of letting the prompt read an empty line and cancel
+func requireConfirmation(assumeYes bool) error {
+	if assumeYes || util.StdinIsTerminal() {
+		return nil
+	}
+	return &exitError{code: exitNeedsConfirmation, err: errors.New("stdin is not a terminal: pass --yes to confirm")}
+}
+
+// exitCode maps an error returned by a command to the process exit code
+func exitCode(err error) int {
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	if errors.Is(err, bettersync.ErrInterrupted) {
+		return exitInterrupted
+	}
+	return exitFailure
+}
+
+// flagError marks a flag parsing error as a usage error; -h stays a request for help
+func flagError(err error) error {
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return &exitError{code: exitUsage, err: err}
+}
+
+// unknownCommandError reports a command or subcommand that does not exist
+func unknownCommandError(kind, name string) error {
+	return &exitError{code: exitUsage, err: fmt.Errorf("unknown %s %q, run 'better-sync help' for a list", kind, name)}
+}
+
+// exitWithError reports a command's error and exits with its code; the flag package has
+// already printed the usage for -h
+func exitWithError(err error) {
+	if !errors.Is(err, flag.ErrHelp) {
+		util.LogError("%v", err)
+	}
+	os.Exit(exitCode(err))
+}
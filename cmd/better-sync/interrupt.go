@@ -0,0 +1,93 @@
+// cmd/better-sync/interrupt.go
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/schachte/better-sync/pkg/bettersync"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+// interruptContext returns a context for a transfer. The first Ctrl-C lets the file in
+// progress finish and then stops; the second aborts it, deleting what was written of
+// it. Call stop once the transfer returns to restore the default Ctrl-C handling.
+func interruptContext() (context.Context, func()) {
+	abortCtx, abort := context.WithCancel(context.Background())
+	stopCtx, requestStop := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-signals:
+			requestStop()
+			color.New(color.FgHiYellow).Fprintln(os.Stderr, "\nStopping after the current file. Press Ctrl-C again to abort it.")
+		case <-done:
+			return
+		}
+		select {
+		case <-signals:
+			signal.Stop(signals)
+			abort()
+			color.New(color.FgHiRed).Fprintln(os.Stderr, "\nAborting the current file.")
+		case <-done:
+		}
+	}()
+
+	stop := func() {
+		signal.Stop(signals)
+		close(done)
+		requestStop()
+		abort()
+	}
+	return bettersync.WithGracefulStop(abortCtx, stopCtx), stop
+}
+
+// runUpload runs an upload that Ctrl-C can stop, prints its outcome and, when it was
+// interrupted, offers to write the playlist of the files that finished
+func runUpload(client *bettersync.Client, upload func(ctx context.Context) (*bettersync.UploadResult, error)) error {
+	ctx, stop := interruptContext()
+	result, err := upload(ctx)
+	stop()
+	return finishUpload(client, result, err)
+}
+
+// finishUpload offers to write the playlist of an interrupted upload and returns the
+// error that describes its outcome
+func finishUpload(client *bettersync.Client, result *bettersync.UploadResult, err error) error {
+	if result != nil && result.Interrupted && result.PendingPlaylist != "" && len(result.UploadedFiles) > 0 {
+		writePendingPlaylist(client, result)
+	}
+	return uploadResultError(result, err)
+}
+
+// writePendingPlaylist asks whether to write the playlist of an interrupted upload for
+// the files that were uploaded. Without a terminal the playlist is not written.
+func writePendingPlaylist(client *bettersync.Client, result *bettersync.UploadResult) {
+	name := strings.TrimSuffix(result.PendingPlaylist, filepath.Ext(result.PendingPlaylist))
+	if !util.StdinIsTerminal() {
+		fmt.Printf("Playlist %s was not written because the upload was interrupted\n", name)
+		return
+	}
+
+	fmt.Printf("\nWrite playlist %s with the %d songs that were uploaded? (y/n): ", name, len(result.UploadedFiles))
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Scan()
+	if strings.ToLower(strings.TrimSpace(scanner.Text())) != "y" {
+		fmt.Println("Playlist not written.")
+		return
+	}
+
+	if err := client.WriteUploadPlaylist(context.Background(), result); err != nil {
+		util.LogError("Error writing playlist %s: %v", name, err)
+	}
+}
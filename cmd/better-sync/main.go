@@ -5,8 +5,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
-	"time"
 
+	"github.com/schachte/better-sync/pkg/bettersync"
+	"github.com/schachte/better-sync/pkg/config"
 	"github.com/schachte/better-sync/pkg/device"
 	"github.com/schachte/better-sync/pkg/operations"
 	"github.com/schachte/better-sync/pkg/util"
@@ -14,36 +15,112 @@ import (
 
 func main() {
 	verboseFlag := flag.Bool("verbose", false, "Enable verbose logging")
-	operationFlag := flag.Int("op", 0, "Operation to perform (0 for menu, 1-10 for specific operation)")
+	operationFlag := flag.Int("op", 0, "Open this menu option directly (deprecated: use a command instead)")
 	scanOnlyFlag := flag.Bool("scan", false, "Only scan for MTP devices and exit")
-	timeoutSecFlag := flag.Int("timeout", 30, "Timeout in seconds for device initialization")
+	flag.String("timeout", "", "Timeout for device initialization, e.g. 45s or 2m (default 30s)")
+	flag.String("path-style", "", "Playlist path style 1-4, or auto to use the style found for the device")
+	flag.String("max-file-size", "", "Skip uploading files larger than this, e.g. 50MB (default 10MB)")
+	flag.String("download-dir", "", "Folder spotdl downloads are saved to (default ~/Documents/music)")
+	flag.String("log-dir", "", "Folder log files are written to (default logs)")
+	noPruneFlag := flag.Bool("no-prune", false, "Keep empty artist and album folders after deleting songs")
+	trashFlag := flag.Bool("trash", false, "Copy deleted songs and playlists to the local trash so they can be restored")
+	outputFlag := flag.String("output", "text", "Result format of commands: text, json, ndjson or csv")
+	flag.Usage = printUsage
 	flag.Parse()
 
-	util.SetupLogging(*verboseFlag)
+	if err := setOutputFormat(*outputFlag); err != nil {
+		exitWithError(err)
+	}
+	if outputFormat.Structured() && flag.NArg() == 0 {
+		exitWithError(usageErrorf("better-sync --output %s <command>", outputFormat))
+	}
+	if flag.NArg() > 0 {
+		if err := checkOutputSupported(flag.Args()); err != nil {
+			exitWithError(err)
+		}
+	}
+
+	if err := loadConfig(); err != nil {
+		exitWithError(err)
+	}
+
+	util.SetupLogging(*verboseFlag, config.LogDir())
+	operations.SetAutoPrune(!*noPruneFlag)
+	operations.SetTrash(*trashFlag)
 
 	util.LogVerbose("Starting MTP Music Manager")
 
-	timeout := time.Duration(*timeoutSecFlag) * time.Second
+	if flag.NArg() > 0 && !commandNeedsDevice(flag.Args()) {
+		if err := runCommand(nil, flag.Args()); err != nil {
+			exitWithError(err)
+		}
+		return
+	}
+
+	if flag.NArg() == 0 && !*scanOnlyFlag && !util.StdinIsTerminal() {
+		util.LogError("The interactive menu needs a terminal; run a command instead")
+		printUsage()
+		os.Exit(exitUsage)
+	}
+
+	timeout := config.Timeout()
 	dev, err := device.Initialize(timeout)
 	if err != nil {
 		util.LogError("Failed to initialize device: %v", err)
 		device.CheckForCommonMTPConflicts(err)
-		os.Exit(1)
+		os.Exit(exitNoDevice)
 	}
 	defer dev.Close()
 
 	if *scanOnlyFlag {
 		fmt.Println("MTP device successfully detected. Exiting.")
-		os.Exit(0)
+		os.Exit(exitOK)
+	}
+
+	if _, serial, err := device.Identify(dev); err == nil && serial != "" {
+		config.SelectDevice(serial)
 	}
 
+	fmt.Println("Fetching device storage information...")
 	storages, err := device.FetchStorages(dev, timeout)
 	if err != nil {
 		util.LogError("Failed to fetch storages: %v", err)
-		os.Exit(1)
+		os.Exit(exitNoDevice)
+	}
+
+	client := bettersync.New(dev, storages)
+	if flag.NArg() > 0 {
+		if err := runCommand(client, flag.Args()); err != nil {
+			dev.Close()
+			exitWithError(err)
+		}
+		return
 	}
 
-	operations.Execute(dev, storages, *operationFlag)
+	runMenu(client, *operationFlag)
 
 	util.LogVerbose("Program completed")
 }
+
+// settingFlags are the global flags that override a config setting
+var settingFlags = map[string]bool{
+	"timeout": true, "path-style": true, "max-file-size": true, "download-dir": true, "log-dir": true,
+}
+
+// loadConfig reads the config file and applies the setting flags that were given
+func loadConfig() error {
+	if err := config.Load(); err != nil {
+		return err
+	}
+
+	var err error
+	flag.Visit(func(f *flag.Flag) {
+		if settingFlags[f.Name] && err == nil {
+			err = config.SetFlag(f.Name, f.Value.String())
+		}
+	})
+	if err != nil {
+		return &exitError{code: exitUsage, err: err}
+	}
+	return nil
+}
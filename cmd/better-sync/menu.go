@@ -0,0 +1,331 @@
+// cmd/better-sync/menu.go
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/schachte/better-sync/pkg/bettersync"
+	"github.com/schachte/better-sync/pkg/config"
+	"github.com/schachte/better-sync/pkg/operations"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+// showMenu prints the menu and reads the option picked
+func showMenu() int {
+	fmt.Print("\033[H\033[2J")
+
+	titleColor := color.New(color.FgHiCyan, color.Bold).SprintFunc()
+	sectionColor := color.New(color.FgHiYellow).SprintFunc()
+	optionColor := color.New(color.FgHiWhite).SprintFunc()
+	numberColor := color.New(color.FgHiGreen, color.Bold).SprintFunc()
+
+	fmt.Println(titleColor("╔══════════════════════════════════════╗"))
+	fmt.Println(titleColor("║       GARMIN BETTER SYNC CLI         ║"))
+	fmt.Println(titleColor("╚══════════════════════════════════════╝"))
+
+	fmt.Println("\n" + sectionColor("🎧 SPOTIFY MANAGEMENT:"))
+	fmt.Printf("  %s %s\n", numberColor("1."), optionColor("Download Spotify playlist"))
+
+	fmt.Println("\n" + sectionColor("🎵 SONG MANAGEMENT:"))
+	fmt.Printf("  %s %s\n", numberColor("2."), optionColor("Show songs"))
+	fmt.Printf("  %s %s\n", numberColor("26."), optionColor("Browse songs and playlists (full screen)"))
+	fmt.Printf("  %s %s\n", numberColor("3."), optionColor("Upload song"))
+	fmt.Printf("  %s %s\n", numberColor("4."), optionColor("Delete song"))
+	fmt.Printf("  %s %s\n", numberColor("19."), optionColor("Upload from beets library"))
+	fmt.Printf("  %s %s\n", numberColor("21."), optionColor("Find orphaned songs"))
+	fmt.Printf("  %s %s\n", numberColor("22."), optionColor("Clean up empty tracks"))
+	fmt.Printf("  %s %s\n", numberColor("23."), optionColor("Prune empty folders"))
+	fmt.Printf("  %s %s\n", numberColor("25."), optionColor("Delete songs matching filters"))
+
+	fmt.Println("\n" + sectionColor("📋 PLAYLIST MANAGEMENT:"))
+	fmt.Printf("  %s %s\n", numberColor("5."), optionColor("Show playlists"))
+	fmt.Printf("  %s %s\n", numberColor("6."), optionColor("Show playlists and songs"))
+	fmt.Printf("  %s %s\n", numberColor("7."), optionColor("Create and upload playlist"))
+	fmt.Printf("  %s %s\n", numberColor("8."), optionColor("Upload directory and create playlist"))
+	fmt.Printf("  %s %s\n", numberColor("9."), optionColor("Delete playlist"))
+	fmt.Printf("  %s %s\n", numberColor("10."), optionColor("Delete playlist and all its songs"))
+	fmt.Printf("  %s %s\n", numberColor("13."), optionColor("Rename playlist"))
+	fmt.Printf("  %s %s\n", numberColor("14."), optionColor("Copy playlist"))
+	fmt.Printf("  %s %s\n", numberColor("15."), optionColor("Refresh smart playlists"))
+	fmt.Printf("  %s %s\n", numberColor("16."), optionColor("Repair broken playlist entries"))
+	fmt.Printf("  %s %s\n", numberColor("17."), optionColor("Import local playlist or iTunes library"))
+	fmt.Printf("  %s %s\n", numberColor("20."), optionColor("Detect playlist path style"))
+
+	fmt.Println("\n" + sectionColor("📁 FOLDER MANAGEMENT:"))
+	fmt.Printf("  %s %s\n", numberColor("11."), optionColor("Delete all music contents from device"))
+	fmt.Printf("  %s %s\n", numberColor("18."), optionColor("Export music to computer"))
+	fmt.Printf("  %s %s\n", numberColor("24."), optionColor("Restore from trash"))
+	fmt.Printf("  %s %s\n", numberColor("27."), optionColor("Device shell (any folder)"))
+
+	fmt.Println("\n" + sectionColor("🚪 SYSTEM:"))
+	fmt.Printf("  %s %s\n", numberColor("12."), optionColor("Exit"))
+
+	fmt.Print("\n" + color.HiMagentaString("Select an option") + color.HiWhiteString(" ❯ "))
+
+	reader := bufio.NewReader(os.Stdin)
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSpace(input)
+
+	option := 0
+	fmt.Sscanf(input, "%d", &option)
+	return option
+}
+
+// runMenu shows the interactive menu until the user exits, or runs one menu option
+// when operation is set
+func runMenu(client *bettersync.Client, operation int) {
+	ctx := context.Background()
+	dev, storages := client.Device(), client.Storages()
+
+	for {
+		op := operation
+		if op == 0 {
+			op = showMenu()
+		}
+
+		switch op {
+		case 0: // Back
+			// Return to previous menu or application state
+			return
+		case 1: // Download Spotify playlist
+			downloadSpotifyPlaylist(ctx, client)
+		case 2: // Show songs
+			songs, err := client.Songs(ctx)
+			if err != nil {
+				util.LogError("Error getting songs: %v", err)
+				break
+			}
+			durations, err := client.SongDurations(ctx, songs)
+			if err != nil {
+				util.LogError("Error reading song lengths: %v", err)
+				break
+			}
+			operations.DisplaySongsToConsole(songs, durations)
+		case 3: // Upload song
+			operations.UploadSong(dev, storages)
+		case 4: // Delete song
+			operations.DeleteSong(dev, storages)
+		case 5: // Show playlists
+			playlists, err := client.Playlists(ctx)
+			if err != nil {
+				util.LogError("Error getting playlists: %v", err)
+				break
+			}
+			operations.DisplayPlaylistsToConsole(playlists)
+		case 6: // Show playlists and songs
+			result, err := client.PlaylistsWithSongs(ctx)
+			if err != nil {
+				util.LogError("Error getting playlists and songs: %v", err)
+				break
+			}
+			operations.PrintPlaylistsAndSongs(dev, result)
+		case 7: // Create and upload playlist
+			operations.CreateAndUploadPlaylist(dev, storages)
+		case 8: // Upload directory and create playlist
+			uploadDirectoryWithPlaylist(ctx, client)
+		case 9: // Delete playlist
+			operations.DeletePlaylist(dev, storages)
+		case 10: // Delete playlist and all its songs
+			operations.DeletePlaylistAndAllSongs(dev, storages)
+		case 11: // Delete folder and all its contents
+			fmt.Printf("\n⚠️ WARNING: Are you sure you want to delete this folder and all its contents?\n")
+			fmt.Print("Type 'yes' to confirm: ")
+			var confirmation string
+			fmt.Scanln(&confirmation)
+
+			if strings.ToLower(confirmation) != "yes" {
+				fmt.Println("Operation cancelled.")
+				break
+			}
+
+			if err := client.Wipe(ctx); err != nil {
+				util.LogError("Error deleting folder: %v", err)
+			}
+		case 12: // Exit
+			color.HiYellow("Exiting program. Goodbye!")
+			return
+		case 13: // Rename playlist
+			operations.RenamePlaylist(dev, storages)
+		case 14: // Copy playlist
+			operations.CopyPlaylist(dev, storages)
+		case 15: // Refresh smart playlists
+			results, err := operations.RefreshSmartPlaylists(ctx, dev, storages, nil)
+			if err != nil {
+				util.LogError("Error refreshing smart playlists: %v", err)
+			} else {
+				operations.DisplaySmartRefreshResults(results)
+			}
+		case 16: // Repair broken playlist entries
+			if _, err := operations.RunPlaylistDoctor(ctx, dev, storages, nil, operations.PlaylistRepairOptions{}); err != nil {
+				util.LogError("Error repairing playlists: %v", err)
+			}
+		case 17: // Import local playlist
+			operations.ImportPlaylist(dev, storages)
+		case 18: // Export music to computer
+			operations.ExportMusic(dev, storages)
+		case 19: // Upload from beets library
+			operations.UploadBeetsLibrary(dev, storages)
+		case 20: // Detect playlist path style
+			operations.ProbePathStyle(dev, storages)
+		case 21: // Find orphaned songs
+			operations.CleanupOrphans(dev, storages)
+		case 22: // Clean up empty tracks
+			operations.CleanupEmptyTracks(dev, storages)
+		case 23: // Prune empty folders
+			operations.PruneFolders(dev, storages)
+		case 24: // Restore from trash
+			operations.ManageTrash(dev, storages)
+		case 25: // Delete songs matching filters
+			operations.DeleteMatchingSongs(dev, storages)
+		case 26: // Browse songs and playlists
+			if err := runBrowser(client); err != nil {
+				util.LogError("%v", err)
+			}
+		case 27: // Device shell
+			if err := runShell(client); err != nil {
+				util.LogError("%v", err)
+			}
+		default:
+			color.HiRed("Invalid option. Please try again.")
+		}
+
+		if operation != 0 {
+			break
+		}
+
+		fmt.Print("\n" + color.HiWhiteString("Press Enter to continue..."))
+		bufio.NewReader(os.Stdin).ReadBytes('\n')
+	}
+}
+
+// uploadDirectoryWithPlaylist asks for a directory and uploads its MP3 files as a
+// playlist named after it
+func uploadDirectoryWithPlaylist(ctx context.Context, client *bettersync.Client) {
+	scanner := bufio.NewScanner(os.Stdin)
+	fmt.Print("Enter path to directory containing MP3 files: ")
+	scanner.Scan()
+	dirPath := util.ExpandPath(strings.Trim(strings.TrimSpace(scanner.Text()), "\"'"))
+
+	mp3Files, err := operations.FindLocalMP3Files(dirPath)
+	if err != nil {
+		util.LogError("%v", err)
+		return
+	}
+
+	fmt.Printf("Found %d MP3 files in %s\n", len(mp3Files), dirPath)
+	fmt.Printf("Do you want to upload %d MP3 files and create a playlist? (y/n): ", len(mp3Files))
+	scanner.Scan()
+	if confirm := strings.ToLower(strings.TrimSpace(scanner.Text())); confirm != "y" && confirm != "yes" {
+		fmt.Println("Upload cancelled.")
+		return
+	}
+
+	err = runUpload(client, func(ctx context.Context) (*bettersync.UploadResult, error) {
+		return client.UploadDirectory(ctx, bettersync.UploadOptions{Directory: dirPath})
+	})
+	if err != nil {
+		util.LogError("%v", err)
+	}
+}
+
+// downloadSpotifyPlaylist asks for a Spotify playlist, downloads it with spotdl and
+// offers to upload it
+func downloadSpotifyPlaylist(ctx context.Context, client *bettersync.Client) {
+	headerColor := color.New(color.FgHiCyan, color.Bold)
+	promptColor := color.New(color.FgHiYellow)
+	successColor := color.New(color.FgHiGreen, color.Bold)
+	errorColor := color.New(color.FgHiRed, color.Bold)
+	infoColor := color.New(color.FgHiWhite)
+
+	headerColor.Println("\n╔═══════════════════════════════════╗")
+	headerColor.Println("║    DOWNLOAD SPOTIFY PLAYLIST      ║")
+	headerColor.Println("╚═══════════════════════════════════╝")
+
+	reader := bufio.NewReader(os.Stdin)
+
+	promptColor.Print("\n🔗 Enter Spotify playlist URL: ")
+	playlistURL, err := reader.ReadString('\n')
+	playlistURL = strings.TrimSpace(playlistURL)
+	if err != nil || playlistURL == "" {
+		errorColor.Println("\n❌ Invalid input. Operation cancelled.")
+		return
+	}
+
+	playlistName, err := bettersync.SpotifyPlaylistName(ctx, playlistURL)
+	if err != nil {
+		errorColor.Printf("\n❌ Error getting playlist name: %v\n", err)
+		promptColor.Print("\n📝 Enter playlist name: ")
+		playlistName, err = reader.ReadString('\n')
+		playlistName = strings.TrimSpace(playlistName)
+	}
+	if err != nil || playlistName == "" {
+		errorColor.Println("\n❌ Invalid input. Operation cancelled.")
+		return
+	}
+
+	defaultDir := config.DownloadDir()
+
+	promptColor.Printf("\n📂 Enter download path [default: %s]: ", defaultDir)
+	downloadPath, err := reader.ReadString('\n')
+	downloadPath = strings.TrimSpace(downloadPath)
+	if err != nil {
+		errorColor.Println("\n❌ Error reading input.")
+		return
+	}
+
+	if downloadPath == "" {
+		downloadPath = defaultDir
+	}
+
+	headerColor.Println("\n📋 Configuration Summary:")
+	infoColor.Printf("  🔗 Playlist URL: ")
+	successColor.Printf("%s\n", playlistURL)
+	infoColor.Printf("  📝 Playlist Name: ")
+	successColor.Printf("%s\n", playlistName)
+	infoColor.Printf("  📂 Destination: ")
+	successColor.Printf("%s\n", filepath.Join(util.ExpandPath(downloadPath), playlistName))
+
+	promptColor.Print("\n⚠️  Proceed with download? (y/n): ")
+	confirm, _ := reader.ReadString('\n')
+	if strings.ToLower(strings.TrimSpace(confirm)) != "y" {
+		promptColor.Println("\nOperation cancelled.")
+		return
+	}
+
+	infoColor.Println("\n⏳ Downloading playlist...")
+
+	destDir, err := bettersync.DownloadSpotify(ctx, bettersync.DownloadOptions{
+		URL:       playlistURL,
+		Name:      playlistName,
+		Directory: downloadPath,
+		Output:    os.Stdout,
+	})
+	if err != nil {
+		errorColor.Printf("\n❌ %v\n", err)
+		return
+	}
+
+	successColor.Printf("\n✅ Download complete! Files saved to: %s\n", destDir)
+
+	promptColor.Print("\n📲 Do you want to upload this playlist to your Garmin device? (y/n): ")
+	uploadConfirm, _ := reader.ReadString('\n')
+	if strings.ToLower(strings.TrimSpace(uploadConfirm)) != "y" {
+		return
+	}
+
+	infoColor.Println("\n🔄 Uploading to Garmin device...")
+	err = runUpload(client, func(ctx context.Context) (*bettersync.UploadResult, error) {
+		return client.UploadDirectory(ctx, bettersync.UploadOptions{Directory: destDir})
+	})
+	if err != nil {
+		util.LogError("%v", err)
+		return
+	}
+	successColor.Printf("\n✅ Successfully uploaded Spotify playlist '%s' to your Garmin device\n", playlistName)
+}
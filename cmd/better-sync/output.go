@@ -0,0 +1,90 @@
+// cmd/better-sync/output.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/schachte/better-sync/pkg/output"
+)
+
+var (
+	// outputFormat is chosen with the global --output flag
+	outputFormat = output.Text
+	// resultOutput receives structured results. With a structured format every other
+	// message goes to stderr, so stdout only holds the result.
+	resultOutput io.Writer = os.Stdout
+)
+
+// structuredCommands can write their result with --output
+var structuredCommands = map[string]bool{
+	"songs ls":          true,
+	"songs put":         true,
+	"songs rm":          true,
+	"rm":                true,
+	"playlists ls":      true,
+	"playlists create":  true,
+	"playlists rm":      true,
+	"playlists rename":  true,
+	"playlists copy":    true,
+	"playlists refresh": true,
+	"playlists doctor":  true,
+	"playlist rename":   true,
+	"playlist copy":     true,
+	"playlist refresh":  true,
+	"playlist doctor":   true,
+	"upload-dir":        true,
+	"upload":            true,
+	"import-playlist":   true,
+	"import-library":    true,
+	"export":            true,
+	"orphans":           true,
+	"cleanup-empty":     true,
+	"spotify download":  true,
+	"trash list":        true,
+	"trash restore":     true,
+	"trash empty":       true,
+	"prune":             true,
+	"config show":       true,
+	"fs ls":             true,
+	"fs get":            true,
+	"fs put":            true,
+	"fs rm":             true,
+	"fs mkdir":          true,
+	"fs mv":             true,
+}
+
+// setOutputFormat selects the result format; a structured format moves console
+// messages, prompts and progress bars to stderr
+func setOutputFormat(value string) error {
+	format, err := output.ParseFormat(value)
+	if err != nil {
+		return &exitError{code: exitUsage, err: err}
+	}
+
+	outputFormat = format
+	if format.Structured() {
+		resultOutput = os.Stdout
+		os.Stdout = os.Stderr
+		color.Output = os.Stderr
+	}
+	return nil
+}
+
+// checkOutputSupported rejects --output for commands that only print text
+func checkOutputSupported(args []string) error {
+	if !outputFormat.Structured() {
+		return nil
+	}
+	if structuredCommands[args[0]] || (len(args) >= 2 && structuredCommands[args[0]+" "+args[1]]) {
+		return nil
+	}
+	return &exitError{code: exitUsage, err: fmt.Errorf("--output %s is not supported by %q", outputFormat, args[0])}
+}
+
+// writeResult writes a command result in the structured format; see output.Write
+func writeResult(kind string, value, rows interface{}) error {
+	return output.Write(resultOutput, outputFormat, kind, value, rows)
+}
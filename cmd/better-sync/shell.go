@@ -0,0 +1,359 @@
+// cmd/better-sync/shell.go
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/schachte/better-sync/pkg/bettersync"
+	"github.com/schachte/better-sync/pkg/device"
+	"github.com/schachte/better-sync/pkg/operations"
+	"github.com/schachte/better-sync/pkg/output"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+const fsUsage = "fs <ls|get|put|rm|mkdir|mv> [--storage id|name] ..."
+
+const shellHelp = `Commands:
+  ls [path]                  list a folder with sizes and dates
+  cd [path]                  change folder; without a path go to /
+  pwd                        print the current folder
+  get <path> [local]         download a file or folder, by default into .
+  put <local> [folder]       upload a file or folder, by default into the current folder
+  rm [-r] [--yes] <path>     delete a file, or a folder with -r
+  mkdir <path>               create a folder and the folders above it
+  mv <path> <target>         move or rename a file or folder
+  storage [id|name]          list the storages or switch to another
+  help                       show this help
+  exit                       leave the shell (or Ctrl-D)
+Paths are relative to the current folder; quote names with spaces.`
+
+// fsSession is the storage and folder that fs commands resolve device paths against;
+// the shell keeps one across commands, the fs command uses the root of a storage
+type fsSession struct {
+	client  *bettersync.Client
+	storage device.StorageInfo
+	cwd     string
+}
+
+// path resolves a device path given on the command line
+func (s *fsSession) path(devicePath string) string {
+	return operations.CleanDevicePath(s.cwd, devicePath)
+}
+
+func newFsSession(client *bettersync.Client, storage string) (*fsSession, error) {
+	info, err := client.Storage(storage)
+	if err != nil {
+		return nil, err
+	}
+	return &fsSession{client: client, storage: info, cwd: "/"}, nil
+}
+
+// runFsCommand runs one fs command against the root of a storage
+func runFsCommand(client *bettersync.Client, args []string) error {
+	if len(args) == 0 {
+		return usageErrorf(fsUsage)
+	}
+	session, err := newFsSession(client, "")
+	if err != nil {
+		return err
+	}
+	return session.run(args)
+}
+
+// run executes an fs command in the session's storage and folder; --storage runs it at
+// the root of another storage instead
+func (s *fsSession) run(args []string) error {
+	ctx := context.Background()
+
+	flags := flag.NewFlagSet("fs "+args[0], flag.ContinueOnError)
+	storage := flags.String("storage", "", "Storage ID or description (default: the first storage)")
+	var recursive, assumeYes *bool
+	if args[0] == "rm" {
+		recursive = flags.Bool("r", false, "Delete a folder and everything in it")
+		assumeYes = flags.Bool("yes", false, "Delete without asking for confirmation")
+	}
+	if err := parseFlags(flags, args[1:]); err != nil {
+		return err
+	}
+	if *storage != "" {
+		session, err := newFsSession(s.client, *storage)
+		if err != nil {
+			return err
+		}
+		s = session
+	}
+	sid := s.storage.StorageID
+	operands := flags.Args()
+
+	switch args[0] {
+	case "ls":
+		if len(operands) > 1 {
+			return usageErrorf("fs ls [path]")
+		}
+		target := ""
+		if len(operands) == 1 {
+			target = operands[0]
+		}
+
+		entries, err := s.client.List(ctx, sid, s.path(target))
+		if err != nil {
+			return err
+		}
+		if outputFormat.Structured() {
+			records := output.DeviceFiles(entries)
+			return writeResult("files", records, records)
+		}
+		operations.DisplayDeviceEntries(entries)
+	case "get":
+		if len(operands) == 0 || len(operands) > 2 {
+			return usageErrorf("fs get <device-path> [local-path]")
+		}
+		localPath := "."
+		if len(operands) == 2 {
+			localPath = util.ExpandPath(operands[1])
+		}
+
+		interruptCtx, stop := interruptContext()
+		result, err := s.client.Download(interruptCtx, sid, s.path(operands[0]), localPath)
+		stop()
+		if writeErr := writeTransferResult(result); writeErr != nil {
+			return writeErr
+		}
+		return err
+	case "put":
+		if len(operands) == 0 || len(operands) > 2 {
+			return usageErrorf("fs put <local-path> [device-folder]")
+		}
+		folder := s.cwd
+		if len(operands) == 2 {
+			folder = s.path(operands[1])
+		}
+
+		interruptCtx, stop := interruptContext()
+		result, err := s.client.Upload(interruptCtx, sid, util.ExpandPath(operands[0]), folder)
+		stop()
+		if writeErr := writeTransferResult(result); writeErr != nil {
+			return writeErr
+		}
+		return err
+	case "rm":
+		if len(operands) != 1 {
+			return usageErrorf("fs rm [-r] [--yes] <device-path>")
+		}
+		entry, err := s.client.Stat(ctx, sid, s.path(operands[0]))
+		if err != nil {
+			return err
+		}
+		if entry.IsDir && !*recursive {
+			return usageErrorf("%s is a folder, use rm -r", entry.Path)
+		}
+		if err := requireConfirmation(*assumeYes); err != nil {
+			return err
+		}
+		if !*assumeYes {
+			what := entry.Path
+			if entry.IsDir {
+				what = "folder " + entry.Path + " and everything in it"
+			}
+			fmt.Printf("Delete %s? (y/n): ", what)
+			scanner := bufio.NewScanner(os.Stdin)
+			scanner.Scan()
+			if strings.ToLower(strings.TrimSpace(scanner.Text())) != "y" {
+				fmt.Println("Operation cancelled.")
+				return nil
+			}
+		}
+
+		interruptCtx, stop := interruptContext()
+		_, err = s.client.Delete(interruptCtx, sid, entry.Path)
+		stop()
+		if err != nil {
+			return err
+		}
+		if outputFormat.Structured() {
+			record := output.DeviceFileOf(entry)
+			return writeResult("fileDeletion", record, record)
+		}
+		color.HiGreen("Deleted %s", entry.Path)
+	case "mkdir":
+		if len(operands) != 1 {
+			return usageErrorf("fs mkdir <device-path>")
+		}
+		entry, err := s.client.MakeFolder(ctx, sid, s.path(operands[0]))
+		if err != nil {
+			return err
+		}
+		if outputFormat.Structured() {
+			record := output.DeviceFileOf(entry)
+			return writeResult("folder", record, record)
+		}
+		color.HiGreen("Created %s", entry.Path)
+	case "mv":
+		if len(operands) != 2 {
+			return usageErrorf("fs mv <device-path> <target>")
+		}
+		entry, err := s.client.Move(ctx, sid, s.path(operands[0]), s.path(operands[1]))
+		if err != nil {
+			return err
+		}
+		if outputFormat.Structured() {
+			record := output.DeviceFileOf(entry)
+			return writeResult("move", record, record)
+		}
+		color.HiGreen("Moved %s to %s", s.path(operands[0]), entry.Path)
+	default:
+		return unknownCommandError("fs command", args[0])
+	}
+
+	return nil
+}
+
+// writeTransferResult writes the result of get or put with --output, or shows it
+func writeTransferResult(result *bettersync.TransferResult) error {
+	if result == nil {
+		return nil
+	}
+	if outputFormat.Structured() {
+		record := output.TransferOf(result)
+		return writeResult("transfer", record, record)
+	}
+	operations.DisplayTransferResult(result)
+	return nil
+}
+
+// runShell reads fs commands from the terminal until exit, keeping a current storage
+// and folder between them
+func runShell(client *bettersync.Client) error {
+	if !util.StdinIsTerminal() {
+		return &exitError{code: exitUsage, err: errors.New("the shell needs a terminal, use the fs commands instead")}
+	}
+	session, err := newFsSession(client, "")
+	if err != nil {
+		return err
+	}
+
+	promptColor := color.New(color.FgHiCyan, color.Bold).SprintFunc()
+	fmt.Println("Type 'help' for a list of commands.")
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Printf("%s %s ", promptColor(session.storage.StorageDescription+":"+session.cwd), color.HiWhiteString("❯"))
+		if !scanner.Scan() {
+			fmt.Println()
+			return scanner.Err()
+		}
+
+		args, err := splitShellWords(scanner.Text())
+		if err != nil {
+			util.LogError("%v", err)
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+		if args[0] == "exit" || args[0] == "quit" {
+			return nil
+		}
+
+		if err := session.runShellCommand(args); err != nil && !errors.Is(err, flag.ErrHelp) {
+			util.LogError("%v", err)
+		}
+	}
+}
+
+// runShellCommand runs the commands that only make sense in the shell, and any fs command
+func (s *fsSession) runShellCommand(args []string) error {
+	switch args[0] {
+	case "help":
+		fmt.Println(shellHelp)
+	case "pwd":
+		fmt.Println(s.cwd)
+	case "cd":
+		if len(args) > 2 {
+			return usageErrorf("cd [path]")
+		}
+		target := "/"
+		if len(args) == 2 {
+			target = s.path(args[1])
+		}
+		entry, err := s.client.Stat(context.Background(), s.storage.StorageID, target)
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir {
+			return fmt.Errorf("%s is not a folder", entry.Path)
+		}
+		s.cwd = entry.Path
+	case "storage":
+		if len(args) > 2 {
+			return usageErrorf("storage [id|name]")
+		}
+		if len(args) == 1 {
+			for _, storage := range device.ListStorages(s.client.Storages()) {
+				marker := " "
+				if storage.StorageID == s.storage.StorageID {
+					marker = "*"
+				}
+				fmt.Printf("%s %d  %s\n", marker, storage.StorageID, storage.StorageDescription)
+			}
+			return nil
+		}
+		storage, err := s.client.Storage(args[1])
+		if err != nil {
+			return err
+		}
+		s.storage, s.cwd = storage, "/"
+	default:
+		return s.run(args)
+	}
+	return nil
+}
+
+// splitShellWords splits a shell line into words. Single and double quotes group words
+// with spaces, and a backslash outside single quotes keeps the next character as is.
+func splitShellWords(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case r == '\\' && quote != '\'' && i+1 < len(runes):
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,147 @@
+// Package bettersync is the Go API of better-sync. A Client manages the music on a
+// connected watch: its methods take their input as options, never prompt, and return
+// errors instead of printing them. Progress, such as upload progress bars, goes to
+// ConnectOptions.Output, and cancelling the context stops long operations between files.
+package bettersync
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/schachte/better-sync/pkg/config"
+	"github.com/schachte/better-sync/pkg/device"
+	"github.com/schachte/better-sync/pkg/model"
+	"github.com/schachte/better-sync/pkg/operations"
+)
+
+// Client manages the music of one connected device
+type Client struct {
+	dev      *mtp.Device
+	storages interface{}
+	// output receives the progress of operations; nil leaves it on standard output
+	output io.Writer
+}
+
+// ConnectOptions controls Connect
+type ConnectOptions struct {
+	// Timeout bounds device initialization; zero uses the configured timeout
+	Timeout time.Duration
+	// Output receives progress and status messages, such as upload progress bars; nil
+	// discards them
+	Output io.Writer
+}
+
+// Connect opens the first MTP device and reads its storages
+func Connect(ctx context.Context, options ConnectOptions) (*Client, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	timeout := options.Timeout
+	if timeout == 0 {
+		timeout = config.Timeout()
+	}
+
+	dev, err := device.Initialize(timeout)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing device: %w", err)
+	}
+	if _, serial, err := device.Identify(dev); err == nil && serial != "" {
+		config.SelectDevice(serial)
+	}
+
+	storages, err := device.FetchStorages(dev, timeout)
+	if err != nil {
+		dev.Close()
+		return nil, fmt.Errorf("error fetching storages: %w", err)
+	}
+	client := New(dev, storages)
+	client.output = options.Output
+	if client.output == nil {
+		client.output = io.Discard
+	}
+	return client, nil
+}
+
+// New wraps a device that is already open, with the storages read by
+// device.FetchStorages. Its operations write their progress to standard output.
+func New(dev *mtp.Device, storages interface{}) *Client {
+	return &Client{dev: dev, storages: storages}
+}
+
+// context returns ctx with the client's output attached for the operations it runs
+func (c *Client) context(ctx context.Context) context.Context {
+	if c.output == nil {
+		return ctx
+	}
+	return operations.WithConsole(ctx, c.output)
+}
+
+// Close releases the device
+func (c *Client) Close() {
+	c.dev.Close()
+}
+
+// Device returns the underlying device, for operations the client does not cover
+func (c *Client) Device() *mtp.Device {
+	return c.dev
+}
+
+// Storages returns the storages of the device as read by device.FetchStorages
+func (c *Client) Storages() interface{} {
+	return c.storages
+}
+
+// Songs lists the songs on every storage
+func (c *Client) Songs(ctx context.Context) ([]model.Song, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return operations.GetSongs(c.dev, c.storages)
+}
+
+// SongDurations reads the length of each song by object ID; songs whose length cannot
+// be read are left out
+func (c *Client) SongDurations(ctx context.Context, songs []model.Song) (map[uint32]time.Duration, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return operations.SongDurations(c.context(ctx), c.dev, songs)
+}
+
+// Playlists lists the playlists on every storage
+func (c *Client) Playlists(ctx context.Context) ([]model.PlaylistInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return operations.GetPlaylists(c.dev, c.storages)
+}
+
+// PlaylistsWithSongs lists the playlists on every storage with the songs they list
+func (c *Client) PlaylistsWithSongs(ctx context.Context) (*model.DevicePlaylistData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return operations.GetPlaylistsWithSongs(c.dev, c.storages)
+}
+
+// DeleteSongs deletes every song matching the selector, updating the playlists that
+// list them. When ctx stops it early, the result lists the songs that were kept and the
+// error wraps ErrInterrupted.
+func (c *Client) DeleteSongs(ctx context.Context, selector operations.DeleteSelector) (*operations.BulkDeleteResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return operations.RunBulkDelete(c.context(ctx), c.dev, c.storages, selector, operations.BulkDeleteOptions{AssumeYes: true})
+}
+
+// Wipe deletes every song and playlist in the Music folder, keeping the folder itself
+func (c *Client) Wipe(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return operations.WipeMusic(c.context(ctx), c.dev, c.storages)
+}
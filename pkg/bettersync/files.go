@@ -0,0 +1,99 @@
+package bettersync
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/schachte/better-sync/pkg/device"
+	"github.com/schachte/better-sync/pkg/operations"
+)
+
+// DeviceEntry is a file or folder anywhere on a storage
+type DeviceEntry = operations.DeviceEntry
+
+// TransferResult counts the files and folders copied by Download and Upload
+type TransferResult = operations.TransferResult
+
+// Storage finds a storage by ID or description, ignoring case; an empty name selects
+// the first storage
+func (c *Client) Storage(name string) (device.StorageInfo, error) {
+	storages := device.ListStorages(c.storages)
+	if len(storages) == 0 {
+		return device.StorageInfo{}, fmt.Errorf("no storage found on device")
+	}
+	if name == "" {
+		return storages[0], nil
+	}
+
+	for _, storage := range storages {
+		if strconv.FormatUint(uint64(storage.StorageID), 10) == name || strings.EqualFold(storage.StorageDescription, name) {
+			return storage, nil
+		}
+	}
+	return device.StorageInfo{}, fmt.Errorf("no storage %q on device", name)
+}
+
+// Stat looks up the file or folder at a device path; names match regardless of case
+func (c *Client) Stat(ctx context.Context, storageID uint32, path string) (*DeviceEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return operations.StatDevicePath(c.dev, storageID, path)
+}
+
+// List lists a folder, folders first, or returns the file at path
+func (c *Client) List(ctx context.Context, storageID uint32, path string) ([]DeviceEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return operations.ListDevicePath(c.dev, storageID, path)
+}
+
+// Download copies a file or folder from the device to localPath, or into it when it is
+// an existing directory. Stopping ctx leaves the remaining files on the device and the
+// error wraps ErrInterrupted.
+func (c *Client) Download(ctx context.Context, storageID uint32, path, localPath string) (*TransferResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return operations.DownloadDevicePath(c.context(ctx), c.dev, storageID, path, localPath)
+}
+
+// Upload copies a local file or directory into a device folder. Files are sent as they
+// are, without tags being read or the upload history being updated, and existing files
+// are not replaced.
+func (c *Client) Upload(ctx context.Context, storageID uint32, localPath, folder string) (*TransferResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return operations.UploadLocalPath(c.context(ctx), c.dev, storageID, localPath, folder)
+}
+
+// Delete deletes a file, or a folder with everything in it; the root and /Music
+// folders are refused. Stopping ctx keeps the files not deleted yet and the error wraps
+// ErrInterrupted.
+func (c *Client) Delete(ctx context.Context, storageID uint32, path string) (*DeviceEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return operations.DeleteDevicePath(c.context(ctx), c.dev, storageID, path)
+}
+
+// MakeFolder creates a folder and any missing folders above it
+func (c *Client) MakeFolder(ctx context.Context, storageID uint32, path string) (*DeviceEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return operations.MakeDeviceFolder(c.dev, storageID, path)
+}
+
+// Move moves or renames a file or folder; a target that is an existing folder receives
+// the source under its own name
+func (c *Client) Move(ctx context.Context, storageID uint32, source, target string) (*DeviceEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return operations.MoveDevicePath(c.dev, storageID, source, target)
+}
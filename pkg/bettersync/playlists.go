@@ -0,0 +1,98 @@
+package bettersync
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/schachte/better-sync/pkg/model"
+	"github.com/schachte/better-sync/pkg/operations"
+)
+
+// PlaylistOptions controls CreatePlaylist
+type PlaylistOptions struct {
+	Name string
+	// Songs are device paths such as /Music/ARTIST/ALBUM/SONG.MP3 and must match a song
+	// exactly
+	Songs []string
+	// Replace overwrites a playlist with the same name instead of failing
+	Replace bool
+}
+
+// CreatePlaylist writes a playlist of songs already on the device
+func (c *Client) CreatePlaylist(ctx context.Context, options PlaylistOptions) (*model.Playlist, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if options.Name == "" {
+		return nil, fmt.Errorf("no playlist name")
+	}
+	return operations.CreateDevicePlaylist(c.context(ctx), c.dev, c.storages, options.Name, options.Songs, options.Replace)
+}
+
+// AddToPlaylist appends songs already on the device to a playlist, creating it when it
+// does not exist; songs it lists already are not added twice
+func (c *Client) AddToPlaylist(ctx context.Context, name string, songs []string) (*model.Playlist, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no playlist name")
+	}
+	return operations.AddToDevicePlaylist(c.context(ctx), c.dev, c.storages, name, songs)
+}
+
+// DeletePlaylistOptions controls DeletePlaylist
+type DeletePlaylistOptions struct {
+	Name string
+	// WithSongs also deletes the songs of the playlist that no other playlist uses
+	WithSongs bool
+	// Plan is a plan from PlanPlaylistDeletion that was already shown, so the songs
+	// deleted are the ones that were listed; it is made when WithSongs is set without it
+	Plan *operations.PlaylistDeletionPlan
+}
+
+// PlaylistDeletion is a deleted playlist with the songs deleted along with it
+type PlaylistDeletion struct {
+	Playlist model.PlaylistInfo
+	// Plan is nil unless the songs were deleted too
+	Plan         *operations.PlaylistDeletionPlan
+	DeletedSongs []string
+}
+
+// PlanPlaylistDeletion lists the songs that deleting a playlist with its songs would
+// delete, and the ones kept because another playlist uses them
+func (c *Client) PlanPlaylistDeletion(ctx context.Context, name string) (*operations.PlaylistDeletionPlan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return operations.PlanPlaylistDeletion(c.context(ctx), c.dev, c.storages, name)
+}
+
+// DeletePlaylist deletes a playlist by name, and with WithSongs the songs no other
+// playlist uses
+func (c *Client) DeletePlaylist(ctx context.Context, options DeletePlaylistOptions) (*PlaylistDeletion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if !options.WithSongs {
+		playlist, err := operations.DeleteDevicePlaylist(c.context(ctx), c.dev, c.storages, options.Name)
+		if err != nil {
+			return nil, err
+		}
+		return &PlaylistDeletion{Playlist: *playlist}, nil
+	}
+
+	plan := options.Plan
+	if plan == nil {
+		var err error
+		if plan, err = c.PlanPlaylistDeletion(ctx, options.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	deletion := &PlaylistDeletion{Playlist: plan.Playlist, Plan: plan}
+	deletedSongs, err := operations.ExecutePlaylistDeletion(c.context(ctx), c.dev, c.storages, plan)
+	deletion.DeletedSongs = deletedSongs
+	return deletion, err
+}
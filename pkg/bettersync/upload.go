@@ -0,0 +1,146 @@
+package bettersync
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/schachte/better-sync/pkg/config"
+	"github.com/schachte/better-sync/pkg/operations"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+// UploadResult lists the uploaded files, the playlist created for them and the errors
+// of the files that were not uploaded
+type UploadResult = operations.UploadResult
+
+// UploadOptions controls UploadDirectory and UploadFiles
+type UploadOptions struct {
+	// Directory is searched for MP3 files, including its subfolders, by UploadDirectory
+	Directory string
+	// Files are the local files uploaded by UploadFiles, in order
+	Files []string
+	// Playlist names the playlist of the uploaded songs. UploadDirectory defaults to the
+	// directory name; UploadFiles only creates a playlist when it is set.
+	Playlist string
+}
+
+// ErrInterrupted is wrapped by the error of an upload or deletion stopped by its context
+var ErrInterrupted = operations.ErrInterrupted
+
+// WithGracefulStop returns a context for uploads and deletions that finish the file in
+// progress and then stop once stop is done. Cancelling ctx itself aborts the file in
+// progress.
+func WithGracefulStop(ctx, stop context.Context) context.Context {
+	return operations.WithGracefulStop(ctx, stop)
+}
+
+// uploadError turns the errors recorded in an upload result into one
+func uploadError(result *UploadResult) error {
+	if result.Interrupted {
+		return fmt.Errorf("upload %w: %d files were not uploaded", ErrInterrupted, len(result.NotUploaded))
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("upload finished with %d errors", len(result.Errors))
+	}
+	return nil
+}
+
+// UploadDirectory uploads every MP3 file below a local directory and creates a
+// playlist of the uploaded songs. The result is returned together with the error when
+// some files failed.
+//
+// Cancelling ctx aborts the file in progress and deletes what was written of it; use
+// WithGracefulStop to let it finish instead. Either way the playlist is not
+// written, and the error wraps ErrInterrupted; WriteUploadPlaylist writes it for the
+// files that were uploaded.
+func (c *Client) UploadDirectory(ctx context.Context, options UploadOptions) (*UploadResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if options.Directory == "" {
+		return nil, fmt.Errorf("no directory to upload")
+	}
+
+	ctx = c.context(ctx)
+	storageID, musicFolderID, err := operations.SelectStorageAndMusicFolder(ctx, c.dev, c.storages)
+	if err != nil {
+		return nil, fmt.Errorf("error selecting storage: %w", err)
+	}
+
+	result := operations.UploadDirectory(ctx, c.dev, storageID, musicFolderID, util.ExpandPath(options.Directory), options.Playlist)
+	return result, uploadError(result)
+}
+
+// UploadFiles uploads local files, and creates a playlist of them when
+// options.Playlist is set
+func (c *Client) UploadFiles(ctx context.Context, options UploadOptions) (*UploadResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(options.Files) == 0 {
+		return nil, fmt.Errorf("no files to upload")
+	}
+
+	ctx = c.context(ctx)
+	storageID, musicFolderID, err := operations.SelectStorageAndMusicFolder(ctx, c.dev, c.storages)
+	if err != nil {
+		return nil, fmt.Errorf("error selecting storage: %w", err)
+	}
+
+	result := operations.UploadFiles(ctx, c.dev, storageID, musicFolderID, options.Files, options.Playlist)
+	return result, uploadError(result)
+}
+
+// WriteUploadPlaylist writes the playlist of an interrupted upload, listing the files
+// that were uploaded
+func (c *Client) WriteUploadPlaylist(ctx context.Context, result *UploadResult) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return operations.WriteUploadPlaylist(c.context(ctx), c.dev, result)
+}
+
+// DownloadOptions controls DownloadSpotify
+type DownloadOptions struct {
+	// URL is the Spotify playlist URL or ID
+	URL string
+	// Name is the folder the tracks are saved in, by default the playlist name on Spotify
+	Name string
+	// Directory holds the playlist folder, by default the configured download directory
+	Directory string
+	// Output receives the output of spotdl; nil discards it
+	Output io.Writer
+}
+
+// DownloadSpotify downloads a Spotify playlist with spotdl and returns the folder the
+// tracks were saved in. It needs no device; upload the folder with UploadDirectory.
+// Cancelling ctx stops spotdl.
+func DownloadSpotify(ctx context.Context, options DownloadOptions) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(options.URL) == "" {
+		return "", fmt.Errorf("no Spotify playlist URL")
+	}
+
+	return operations.DownloadSpotify(operations.WithConsole(ctx, options.Output), options.URL, operations.SpotifyDownloadOptions{
+		Name:      options.Name,
+		Directory: options.Directory,
+	})
+}
+
+// SpotifyPlaylistName looks up the name of a Spotify playlist with the configured
+// client credentials
+func SpotifyPlaylistName(ctx context.Context, url string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	name, err := util.GetSpotifyPlaylistName(url, config.SpotifyCredentials())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(name), nil
+}
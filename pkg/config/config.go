@@ -0,0 +1,393 @@
+// Package config resolves the tunable settings of better-sync from flags, the
+// environment and config.toml in the better-sync config directory
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+const configFile = "config.toml"
+
+// Setting keys, as written in config.toml
+const (
+	KeyTimeout             = "timeout"
+	KeyPathStyle           = "path_style"
+	KeyMaxFileSize         = "max_file_size"
+	KeyDownloadDir         = "download_dir"
+	KeyLogDir              = "log_dir"
+	KeySpotifyClientID     = "spotify_client_id"
+	KeySpotifyClientSecret = "spotify_client_secret"
+)
+
+// Where a value came from, from lowest to highest precedence
+const (
+	SourceDefault = "default"
+	SourceFile    = "config file"
+	SourceDotEnv  = ".env"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// setting describes one tunable: where it can be set and how its value is checked
+type setting struct {
+	key  string
+	env  string
+	flag string
+	// fallback is the value used when nothing sets the setting
+	fallback string
+	// perDevice allows a [devices."SERIAL"] table to override the setting
+	perDevice bool
+	// secret hides the value in config show
+	secret bool
+	check  func(string) error
+}
+
+var settings = []setting{
+	{key: KeyTimeout, env: "BETTER_SYNC_TIMEOUT", flag: "timeout", fallback: "30s", check: checkTimeout},
+	{key: KeyPathStyle, env: "BETTER_SYNC_PATH_STYLE", flag: "path-style", fallback: "auto", perDevice: true, check: checkPathStyle},
+	{key: KeyMaxFileSize, env: "BETTER_SYNC_MAX_FILE_SIZE", flag: "max-file-size", fallback: "10MB", perDevice: true, check: checkSize},
+	{key: KeyDownloadDir, env: "BETTER_SYNC_DOWNLOAD_DIR", flag: "download-dir", fallback: "~/Documents/music", perDevice: true},
+	{key: KeyLogDir, env: "BETTER_SYNC_LOG_DIR", flag: "log-dir", fallback: "logs"},
+	{key: KeySpotifyClientID, env: "SPOTIFY_CLIENT_ID"},
+	{key: KeySpotifyClientSecret, env: "SPOTIFY_CLIENT_SECRET", secret: true},
+}
+
+// Value is the effective value of a setting and where it came from
+type Value struct {
+	Key    string
+	Value  string
+	Source string
+	Secret bool
+}
+
+// Display returns the value as it is shown, with a secret masked
+func (v Value) Display() string {
+	if v.Secret && v.Value != "" {
+		return "********"
+	}
+	return v.Value
+}
+
+var (
+	fileValues   = map[string]string{}
+	deviceValues = map[string]map[string]string{}
+	dotEnv       = map[string]string{}
+	flagValues   = map[string]string{}
+	device       string
+)
+
+// Path returns where the config file is read from
+func Path() (string, error) {
+	dir, err := util.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, configFile), nil
+}
+
+func findSetting(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// Load reads config.toml and the .env file in the working directory, and checks the
+// values they and the environment set. A missing file is not an error.
+func Load() error {
+	values, err := godotenv.Read()
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error reading .env file: %w", err)
+	}
+	dotEnv = values
+
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := loadFile(path); err != nil {
+		return err
+	}
+
+	for _, s := range settings {
+		if value, source := envValue(s); source != "" && s.check != nil {
+			if err := s.check(value); err != nil {
+				return fmt.Errorf("%s %s: %w", source, s.env, err)
+			}
+		}
+	}
+	return nil
+}
+
+func loadFile(path string) error {
+	var raw map[string]interface{}
+	if _, err := toml.DecodeFile(path, &raw); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	fileValues = map[string]string{}
+	deviceValues = map[string]map[string]string{}
+	for key, value := range raw {
+		if key != "devices" {
+			text, err := settingValue(key, value, false)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			fileValues[key] = text
+			continue
+		}
+
+		devices, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: devices must be a table of device serial numbers", path)
+		}
+		for serial, table := range devices {
+			overrides, ok := table.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s: devices.%q must be a table", path, serial)
+			}
+			deviceValues[serial] = map[string]string{}
+			for key, value := range overrides {
+				text, err := settingValue(key, value, true)
+				if err != nil {
+					return fmt.Errorf("%s: devices.%q: %w", path, serial, err)
+				}
+				deviceValues[serial][key] = text
+			}
+		}
+	}
+	return nil
+}
+
+// settingValue checks a value read from the config file and returns it as text
+func settingValue(key string, value interface{}, perDevice bool) (string, error) {
+	s, ok := findSetting(key)
+	if !ok {
+		return "", fmt.Errorf("unknown setting %q", key)
+	}
+	if perDevice && !s.perDevice {
+		return "", fmt.Errorf("%s cannot be set per device", key)
+	}
+
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = v
+	case int64:
+		text = strconv.FormatInt(v, 10)
+	default:
+		return "", fmt.Errorf("%s: expected a string or a number", key)
+	}
+
+	if s.check != nil {
+		if err := s.check(text); err != nil {
+			return "", fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return text, nil
+}
+
+// SetFlag records a value given on the command line; flag is the flag name without
+// dashes
+func SetFlag(flag, value string) error {
+	for _, s := range settings {
+		if s.flag != flag {
+			continue
+		}
+		if s.check != nil {
+			if err := s.check(value); err != nil {
+				return fmt.Errorf("--%s: %w", flag, err)
+			}
+		}
+		flagValues[s.key] = value
+		return nil
+	}
+	return fmt.Errorf("--%s is not a setting", flag)
+}
+
+// SelectDevice applies the [devices."SERIAL"] overrides of the connected device
+func SelectDevice(serial string) {
+	device = serial
+	if len(deviceValues[serial]) > 0 {
+		util.LogInfo("Using config overrides for device %s", serial)
+	}
+}
+
+// Device returns the serial number whose overrides apply, if any
+func Device() string {
+	return device
+}
+
+func envValue(s setting) (string, string) {
+	if s.env == "" {
+		return "", ""
+	}
+	if value, ok := os.LookupEnv(s.env); ok && value != "" {
+		return value, SourceEnv
+	}
+	if value := dotEnv[s.env]; value != "" {
+		return value, SourceDotEnv
+	}
+	return "", ""
+}
+
+// lookup resolves a setting: flags win over the environment and .env, which win over
+// the device overrides and then the rest of the config file
+func lookup(s setting) Value {
+	result := Value{Key: s.key, Secret: s.secret}
+
+	if value, ok := flagValues[s.key]; ok {
+		result.Value, result.Source = value, SourceFlag+" --"+s.flag
+		return result
+	}
+	if value, source := envValue(s); source != "" {
+		result.Value, result.Source = value, source+" "+s.env
+		return result
+	}
+	if value, ok := deviceValues[device][s.key]; ok && device != "" {
+		result.Value, result.Source = value, fmt.Sprintf("%s [devices.%q]", SourceFile, device)
+		return result
+	}
+	if value, ok := fileValues[s.key]; ok {
+		result.Value, result.Source = value, SourceFile
+		return result
+	}
+	result.Value, result.Source = s.fallback, SourceDefault
+	return result
+}
+
+// Lookup returns the effective value of the setting with the given key
+func Lookup(key string) Value {
+	s, ok := findSetting(key)
+	if !ok {
+		return Value{Key: key, Source: SourceDefault}
+	}
+	return lookup(s)
+}
+
+// Values returns the effective value of every setting, in a fixed order
+func Values() []Value {
+	values := make([]Value, 0, len(settings))
+	for _, s := range settings {
+		values = append(values, lookup(s))
+	}
+	return values
+}
+
+// Devices returns the serial numbers that have overrides in the config file
+func Devices() []string {
+	var serials []string
+	for serial := range deviceValues {
+		serials = append(serials, serial)
+	}
+	sort.Strings(serials)
+	return serials
+}
+
+// Timeout is how long to wait for the device to answer while connecting
+func Timeout() time.Duration {
+	timeout, err := parseTimeout(Lookup(KeyTimeout).Value)
+	if err != nil {
+		return 30 * time.Second
+	}
+	return timeout
+}
+
+// PathStyle returns the playlist path style that was set and where it was set, or 0
+// when it is left to the device
+func PathStyle() (int, string) {
+	value := Lookup(KeyPathStyle)
+	style, err := parsePathStyle(value.Value)
+	if err != nil {
+		return 0, ""
+	}
+	return style, value.Source
+}
+
+// MaxFileSize is the size of the largest file that is uploaded
+func MaxFileSize() int64 {
+	size, err := util.ParseSize(Lookup(KeyMaxFileSize).Value)
+	if err != nil || size <= 0 {
+		return 10 * 1024 * 1024
+	}
+	return size
+}
+
+// DownloadDir is where spotdl saves downloads and where local sources are searched
+func DownloadDir() string {
+	return util.ExpandPath(Lookup(KeyDownloadDir).Value)
+}
+
+// LogDir is the directory log files are written to
+func LogDir() string {
+	return util.ExpandPath(Lookup(KeyLogDir).Value)
+}
+
+// SpotifyCredentials returns the client ID and secret used for the Spotify API
+func SpotifyCredentials() util.SpotifyCredentials {
+	return util.SpotifyCredentials{
+		ClientID:     Lookup(KeySpotifyClientID).Value,
+		ClientSecret: Lookup(KeySpotifyClientSecret).Value,
+	}
+}
+
+// parseTimeout accepts a duration such as 45s or 2m, or a number of seconds
+func parseTimeout(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return time.ParseDuration(value)
+}
+
+func checkTimeout(value string) error {
+	timeout, err := parseTimeout(value)
+	if err != nil || timeout <= 0 {
+		return fmt.Errorf("invalid timeout %q, expected e.g. 30s or 2m", value)
+	}
+	return nil
+}
+
+// parsePathStyle accepts a path style number, or auto for 0
+func parsePathStyle(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(value, "auto") || value == "0" {
+		return 0, nil
+	}
+	style, err := strconv.Atoi(value)
+	if err != nil || style < util.PathStyleDrive || style > util.PathStyleMixedCase {
+		return 0, fmt.Errorf("invalid path style %q, expected auto or 1-%d", value, len(util.PathStyles))
+	}
+	return style, nil
+}
+
+func checkPathStyle(value string) error {
+	_, err := parsePathStyle(value)
+	return err
+}
+
+func checkSize(value string) error {
+	size, err := util.ParseSize(value)
+	if err != nil {
+		return err
+	}
+	if size <= 0 {
+		return fmt.Errorf("size must be larger than 0")
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package device
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+)
+
+// modelPathStyles maps MTP model names (lower case) to a playlist path style confirmed
+// on that model. No model has been confirmed yet, so every device uses the default
+// style until `probe --select` saves the one that works for it. Only add a model once a
+// probe on it has shown which style it resolves.
+var modelPathStyles = map[string]int{}
+
+// ProfilePathStyle returns the path style listed for a model name
+func ProfilePathStyle(model string) (int, bool) {
+	style, ok := modelPathStyles[strings.ToLower(strings.TrimSpace(model))]
+	return style, ok
+}
+
+// Identify returns the model name and serial number the device reports
+func Identify(dev *mtp.Device) (string, string, error) {
+	var info mtp.DeviceInfo
+	if err := dev.GetDeviceInfo(&info); err != nil {
+		return "", "", fmt.Errorf("error reading device info: %w", err)
+	}
+	return strings.TrimSpace(info.Model), strings.TrimSpace(info.SerialNumber), nil
+}
@@ -18,7 +18,6 @@ type StorageInfo struct {
 
 func FetchStorages(dev *mtp.Device, timeout time.Duration) (interface{}, error) {
 	util.LogVerbose("Fetching storages with timeout of %v...", timeout)
-	fmt.Println("Fetching device storage information...")
 
 	type storageResult struct {
 		storages interface{}
@@ -70,6 +69,28 @@ func FetchStorages(dev *mtp.Device, timeout time.Duration) (interface{}, error)
 	}
 }
 
+// ListStorages returns the ID and description of each storage read by FetchStorages
+func ListStorages(storagesRaw interface{}) []StorageInfo {
+	var storages []StorageInfo
+	storagesValue := reflect.ValueOf(storagesRaw)
+	if storagesValue.Kind() != reflect.Slice {
+		return nil
+	}
+
+	for i := 0; i < storagesValue.Len(); i++ {
+		storage := storagesValue.Index(i).Interface()
+		desc := extractStringField(storage, "StorageDescription")
+		if desc == "" {
+			desc = extractStringField(storage, "Description")
+		}
+		storages = append(storages, StorageInfo{
+			StorageID:          extractUint32Field(storage, "Sid"),
+			StorageDescription: desc,
+		})
+	}
+	return storages
+}
+
 func SelectStorage(dev *mtp.Device, storagesRaw interface{}) (uint32, error) {
 
 	storagesValue := reflect.ValueOf(storagesRaw)
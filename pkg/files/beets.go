@@ -0,0 +1,297 @@
+package files
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+// BeetsItem is a track from a beets library with the tags beets has stored for it
+type BeetsItem struct {
+	Path    string
+	Artist  string
+	Album   string
+	Title   string
+	Track   int
+	Seconds int
+	Fields  map[string]string
+}
+
+// beetsDefaultFields are searched by query terms without a field name, as in beets
+var beetsDefaultFields = []string{"artist", "albumartist", "album", "title", "genre", "comments"}
+
+type beetsTerm struct {
+	field   string
+	negate  bool
+	exact   string
+	pattern *regexp.Regexp
+	low     *float64
+	high    *float64
+	text    string
+}
+
+// BeetsQuery is a parsed beets-style query such as "genre:running year:2020.."
+type BeetsQuery struct {
+	terms []beetsTerm
+}
+
+// ParseBeetsQuery parses space separated beets query terms. Supported forms are
+// "value" (any default field), "field:value" (substring), "field:=value" (exact),
+// "field::regex", "field:a..b" (numeric range, either end optional) and a leading
+// "-" or "^" to negate a term. All terms must match.
+func ParseBeetsQuery(terms []string) (*BeetsQuery, error) {
+	query := &BeetsQuery{}
+
+	for _, raw := range terms {
+		for _, part := range strings.Fields(raw) {
+			term := beetsTerm{}
+			if strings.HasPrefix(part, "-") || strings.HasPrefix(part, "^") {
+				term.negate = true
+				part = part[1:]
+			}
+
+			value := part
+			if colon := strings.Index(part, ":"); colon > 0 {
+				term.field = strings.ToLower(part[:colon])
+				value = part[colon+1:]
+			}
+
+			switch {
+			case strings.HasPrefix(value, ":"):
+				pattern, err := regexp.Compile("(?i)" + value[1:])
+				if err != nil {
+					return nil, fmt.Errorf("invalid regular expression in %q: %w", part, err)
+				}
+				term.pattern = pattern
+			case strings.HasPrefix(value, "="):
+				term.exact = value[1:]
+			case strings.Contains(value, ".."):
+				bounds := strings.SplitN(value, "..", 2)
+				for i, bound := range bounds {
+					if bound == "" {
+						continue
+					}
+					number, err := strconv.ParseFloat(bound, 64)
+					if err != nil {
+						return nil, fmt.Errorf("invalid range in %q", part)
+					}
+					if i == 0 {
+						term.low = &number
+					} else {
+						term.high = &number
+					}
+				}
+			default:
+				term.text = value
+			}
+
+			query.terms = append(query.terms, term)
+		}
+	}
+
+	return query, nil
+}
+
+func (t beetsTerm) matchValue(value string) bool {
+	switch {
+	case t.pattern != nil:
+		return t.pattern.MatchString(value)
+	case t.exact != "":
+		return value == t.exact
+	case t.low != nil || t.high != nil:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return false
+		}
+		return (t.low == nil || number >= *t.low) && (t.high == nil || number <= *t.high)
+	default:
+		// Numeric fields compare by value, everything else by case-insensitive substring
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			if wanted, err := strconv.ParseFloat(t.text, 64); err == nil {
+				return number == wanted
+			}
+		}
+		return strings.Contains(strings.ToLower(value), strings.ToLower(t.text))
+	}
+}
+
+// Match reports whether an item satisfies every term of the query
+func (q *BeetsQuery) Match(item BeetsItem) bool {
+	for _, term := range q.terms {
+		fields := beetsDefaultFields
+		if term.field != "" {
+			fields = []string{term.field}
+		}
+
+		matched := false
+		for _, field := range fields {
+			if value, ok := item.Fields[field]; ok && term.matchValue(value) {
+				matched = true
+				break
+			}
+		}
+
+		if matched == term.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// beetsValue converts a SQLite column value to the text form used in queries
+func beetsValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// readBeetsRows loads every row of a beets table as field maps keyed by id
+func readBeetsRows(db *sql.DB, table string) (map[string]map[string]string, error) {
+	rows, err := db.Query("SELECT * FROM " + table)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]map[string]string)
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", table, err)
+		}
+
+		fields := make(map[string]string, len(columns))
+		for i, column := range columns {
+			fields[strings.ToLower(column)] = beetsValue(values[i])
+		}
+		result[fields["id"]] = fields
+	}
+
+	return result, rows.Err()
+}
+
+// addBeetsAttributes merges flexible attributes (item_attributes/album_attributes)
+func addBeetsAttributes(db *sql.DB, table string, entities map[string]map[string]string) error {
+	rows, err := db.Query("SELECT entity_id, key, value FROM " + table)
+	if err != nil {
+		// Older libraries may not have flexible attributes
+		util.LogVerbose("Skipping %s: %v", table, err)
+		return nil
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entityID, key, value interface{}
+		if err := rows.Scan(&entityID, &key, &value); err != nil {
+			return fmt.Errorf("error reading %s: %w", table, err)
+		}
+		if fields, ok := entities[beetsValue(entityID)]; ok {
+			fields[strings.ToLower(beetsValue(key))] = beetsValue(value)
+		}
+	}
+	return rows.Err()
+}
+
+// QueryBeetsLibrary returns the items of a beets library database that match the
+// query, ordered by album artist, album, disc and track. Album fields are used
+// for items that do not have a value of their own.
+func QueryBeetsLibrary(dbPath string, query *BeetsQuery) ([]BeetsItem, error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("beets library not found: %w", err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening beets library: %w", err)
+	}
+	defer db.Close()
+
+	items, err := readBeetsRows(db, "items")
+	if err != nil {
+		return nil, err
+	}
+	albums, err := readBeetsRows(db, "albums")
+	if err != nil {
+		return nil, err
+	}
+	if err := addBeetsAttributes(db, "item_attributes", items); err != nil {
+		return nil, err
+	}
+	if err := addBeetsAttributes(db, "album_attributes", albums); err != nil {
+		return nil, err
+	}
+
+	var matched []BeetsItem
+	for _, fields := range items {
+		if album, ok := albums[fields["album_id"]]; ok {
+			for key, value := range album {
+				if key != "id" && fields[key] == "" {
+					fields[key] = value
+				}
+			}
+		}
+
+		item := BeetsItem{
+			Path:   fields["path"],
+			Artist: fields["artist"],
+			Album:  fields["album"],
+			Title:  fields["title"],
+			Fields: fields,
+		}
+		if item.Artist == "" {
+			item.Artist = fields["albumartist"]
+		}
+		item.Track, _ = strconv.Atoi(fields["track"])
+		if length, err := strconv.ParseFloat(fields["length"], 64); err == nil {
+			item.Seconds = int(length + 0.5)
+		}
+
+		if item.Path != "" && query.Match(item) {
+			matched = append(matched, item)
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		a, b := matched[i].Fields, matched[j].Fields
+		for _, key := range []string{"albumartist", "album"} {
+			if a[key] != b[key] {
+				return strings.ToLower(a[key]) < strings.ToLower(b[key])
+			}
+		}
+		for _, key := range []string{"disc", "track"} {
+			x, _ := strconv.Atoi(a[key])
+			y, _ := strconv.Atoi(b[key])
+			if x != y {
+				return x < y
+			}
+		}
+		return matched[i].Path < matched[j].Path
+	})
+
+	util.LogVerbose("Beets query matched %d items in %s", len(matched), dbPath)
+	return matched, nil
+}
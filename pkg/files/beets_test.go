@@ -0,0 +1,107 @@
+package files
+
+import (
+	"testing"
+)
+
+func TestBeetsQueryRanges(t *testing.T) {
+	tests := []struct {
+		name  string
+		terms []string
+		year  string
+		want  bool
+	}{
+		{"inside a closed range", []string{"year:2010..2019"}, "2015", true},
+		{"low bound included", []string{"year:2010..2019"}, "2010", true},
+		{"high bound included", []string{"year:2010..2019"}, "2019", true},
+		{"below a closed range", []string{"year:2010..2019"}, "2009", false},
+		{"above a closed range", []string{"year:2010..2019"}, "2020", false},
+		{"open high end", []string{"year:2020.."}, "2024", true},
+		{"below an open high end", []string{"year:2020.."}, "2019", false},
+		{"open low end", []string{"year:..1999"}, "1985", true},
+		{"above an open low end", []string{"year:..1999"}, "2000", false},
+		{"decimal bounds", []string{"year:2010.5..2011.5"}, "2011", true},
+		{"not a number", []string{"year:2010..2019"}, "unknown", false},
+		{"missing field", []string{"year:2010..2019"}, "", false},
+		{"negated range", []string{"-year:2010..2019"}, "2015", false},
+		{"outside a negated range", []string{"-year:2010..2019"}, "2005", true},
+		{"caret negates too", []string{"^year:2010..2019"}, "2005", true},
+		{"field name ignores case", []string{"YEAR:2010..2019"}, "2015", true},
+		{"with another term", []string{"year:2010.. genre:run"}, "2015", true},
+		{"another term failing", []string{"year:2010..", "genre:jazz"}, "2015", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := ParseBeetsQuery(tt.terms)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			item := BeetsItem{Fields: map[string]string{"genre": "Running"}}
+			if tt.year != "" {
+				item.Fields["year"] = tt.year
+			}
+			if got := query.Match(item); got != tt.want {
+				t.Errorf("%v matches year %q = %v, want %v", tt.terms, tt.year, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBeetsQueryTerms(t *testing.T) {
+	item := BeetsItem{Fields: map[string]string{
+		"artist":   "The Chemical Brothers",
+		"album":    "Dig Your Own Hole",
+		"title":    "Setting Sun",
+		"genre":    "Electronic",
+		"bpm":      "128",
+		"comments": "",
+	}}
+
+	tests := []struct {
+		name  string
+		terms []string
+		want  bool
+	}{
+		{"bare word in a default field", []string{"chemical"}, true},
+		{"bare word not in a default field", []string{"jazz"}, false},
+		{"substring", []string{"album:own hole"}, true},
+		{"exact", []string{"genre:=Electronic"}, true},
+		{"exact is case sensitive", []string{"genre:=electronic"}, false},
+		{"regular expression", []string{"title::^set.*sun$"}, true},
+		{"number compared by value", []string{"bpm:128.0"}, true},
+		{"number not a substring", []string{"bpm:12"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := ParseBeetsQuery(tt.terms)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := query.Match(item); got != tt.want {
+				t.Errorf("%v = %v, want %v", tt.terms, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBeetsQueryErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		terms []string
+	}{
+		{"range with text", []string{"year:2010..later"}},
+		{"range with text low end", []string{"year:early..2019"}},
+		{"bad regular expression", []string{"title::("}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseBeetsQuery(tt.terms); err == nil {
+				t.Errorf("ParseBeetsQuery(%v) succeeded, want an error", tt.terms)
+			}
+		})
+	}
+}
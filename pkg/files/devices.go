@@ -0,0 +1,72 @@
+package files
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/schachte/better-sync/pkg/model"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+// deviceSettingsFile holds what `probe --select` remembers per device. A path_style set
+// in config.toml, globally or for the device's serial number, takes precedence over it.
+const deviceSettingsFile = "devices.json"
+
+func deviceSettingsPath() (string, error) {
+	dir, err := util.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, deviceSettingsFile), nil
+}
+
+// LoadDeviceSettings reads the remembered settings of every device by serial number
+func LoadDeviceSettings() (map[string]model.DeviceSettings, error) {
+	path, err := deviceSettingsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]model.DeviceSettings{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading device settings: %w", err)
+	}
+
+	settings := make(map[string]model.DeviceSettings)
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+
+	return settings, nil
+}
+
+// SaveDeviceSettings remembers the settings of one device
+func SaveDeviceSettings(serial string, deviceSettings model.DeviceSettings) error {
+	settings, err := LoadDeviceSettings()
+	if err != nil {
+		return err
+	}
+	settings[serial] = deviceSettings
+
+	path, err := deviceSettingsPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding device settings: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing device settings: %w", err)
+	}
+
+	util.LogVerbose("Saved settings for device %s to %s", serial, path)
+	return nil
+}
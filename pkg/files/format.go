@@ -0,0 +1,90 @@
+package files
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	PlaylistFormatM3U = "m3u8"
+	PlaylistFormatPLS = "pls"
+)
+
+// PlaylistFormatForName picks the playlist format from a file name's extension
+func PlaylistFormatForName(name string) string {
+	if strings.EqualFold(filepath.Ext(name), ".pls") {
+		return PlaylistFormatPLS
+	}
+	return PlaylistFormatM3U
+}
+
+// ParsePlaylistFormat validates a user supplied format name
+func ParsePlaylistFormat(format string) (string, error) {
+	switch strings.ToLower(strings.TrimPrefix(strings.TrimSpace(format), ".")) {
+	case "", "m3u", "m3u8":
+		return PlaylistFormatM3U, nil
+	case "pls":
+		return PlaylistFormatPLS, nil
+	}
+	return "", fmt.Errorf("unsupported playlist format %q: use m3u8 or pls", format)
+}
+
+// PlaylistFormatOf detects the format of playlist content, using the file name
+// when the content itself is ambiguous
+func PlaylistFormatOf(name, content string) string {
+	trimmed := strings.TrimSpace(strings.TrimPrefix(content, "\uFEFF"))
+	if strings.HasPrefix(strings.ToLower(trimmed), "[playlist]") {
+		return PlaylistFormatPLS
+	}
+	if strings.HasPrefix(trimmed, "#EXTM3U") {
+		return PlaylistFormatM3U
+	}
+	return PlaylistFormatForName(name)
+}
+
+// ParsePlaylist reads playlist entries in whichever format the content is in
+func ParsePlaylist(name, content string) []PlaylistEntry {
+	if PlaylistFormatOf(name, content) == PlaylistFormatPLS {
+		return ParsePLS(content)
+	}
+	return ParseM3U(content)
+}
+
+// AppendPlaylist adds entries to the end of playlist content, in the content's format.
+// M3U content is kept byte for byte; PLS is renumbered, keeping the values of its entries.
+func AppendPlaylist(name, content string, entries []PlaylistEntry) string {
+	if PlaylistFormatOf(name, content) == PlaylistFormatPLS {
+		return FormatPLS(append(ParsePLS(content), entries...))
+	}
+	if strings.TrimSpace(content) == "" {
+		return FormatM3U(entries)
+	}
+
+	added := strings.TrimPrefix(FormatM3U(entries), "#EXTM3U\n")
+	if strings.Contains(content, "\r\n") {
+		added = strings.ReplaceAll(added, "\n", "\r\n")
+	}
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content + added
+}
+
+// FormatPlaylist renders entries in the given format
+func FormatPlaylist(format string, entries []PlaylistEntry) string {
+	if format == PlaylistFormatPLS {
+		return FormatPLS(entries)
+	}
+	return FormatM3U(entries)
+}
+
+// ReadPlaylistFile reads a local M3U, M3U8 or PLS playlist
+func ReadPlaylistFile(path string) ([]PlaylistEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading playlist file: %w", err)
+	}
+	return ParsePlaylist(path, string(data)), nil
+}
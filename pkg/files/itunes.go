@@ -0,0 +1,189 @@
+package files
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LibraryPlaylist is a playlist read from an iTunes or Music.app library export
+type LibraryPlaylist struct {
+	Name    string
+	Entries []PlaylistEntry
+}
+
+// decodePlistValue reads the plist value that starts with the given element into
+// map[string]interface{}, []interface{}, string, int64 or bool
+func decodePlistValue(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := make(map[string]interface{})
+		key := ""
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := token.(type) {
+			case xml.StartElement:
+				if t.Name.Local == "key" {
+					var name string
+					if err := decoder.DecodeElement(&name, &t); err != nil {
+						return nil, err
+					}
+					key = name
+					continue
+				}
+				value, err := decodePlistValue(decoder, t)
+				if err != nil {
+					return nil, err
+				}
+				dict[key] = value
+			case xml.EndElement:
+				return dict, nil
+			}
+		}
+	case "array":
+		var array []interface{}
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := token.(type) {
+			case xml.StartElement:
+				value, err := decodePlistValue(decoder, t)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, value)
+			case xml.EndElement:
+				return array, nil
+			}
+		}
+	case "true", "false":
+		if err := decoder.Skip(); err != nil {
+			return nil, err
+		}
+		return start.Name.Local == "true", nil
+	case "integer":
+		var text string
+		if err := decoder.DecodeElement(&text, &start); err != nil {
+			return nil, err
+		}
+		return strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	default:
+		// string, date, real and data are kept as text
+		var text string
+		if err := decoder.DecodeElement(&text, &start); err != nil {
+			return nil, err
+		}
+		return text, nil
+	}
+}
+
+// parsePlist decodes the top level value of an XML property list
+func parsePlist(r io.Reader) (interface{}, error) {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local != "plist" {
+			return decodePlistValue(decoder, start)
+		}
+	}
+}
+
+func plistString(dict map[string]interface{}, key string) string {
+	value, _ := dict[key].(string)
+	return value
+}
+
+func plistInt(dict map[string]interface{}, key string) int64 {
+	value, _ := dict[key].(int64)
+	return value
+}
+
+func plistBool(dict map[string]interface{}, key string) bool {
+	value, _ := dict[key].(bool)
+	return value
+}
+
+// ParseITunesLibrary reads the user playlists of an iTunes/Music.app "Library.xml"
+// export, resolving each playlist item to the Location of its track. The library
+// itself, folders and built-in playlists such as Podcasts are skipped.
+func ParseITunesLibrary(r io.Reader) ([]LibraryPlaylist, error) {
+	root, err := parsePlist(r)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing library: %w", err)
+	}
+
+	library, ok := root.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("error parsing library: not a property list dictionary")
+	}
+
+	tracks, _ := library["Tracks"].(map[string]interface{})
+	playlistValues, _ := library["Playlists"].([]interface{})
+
+	var playlists []LibraryPlaylist
+	for _, value := range playlistValues {
+		playlist, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if plistBool(playlist, "Master") || plistBool(playlist, "Folder") ||
+			plistInt(playlist, "Distinguished Kind") != 0 {
+			continue
+		}
+
+		libraryPlaylist := LibraryPlaylist{Name: plistString(playlist, "Name")}
+		items, _ := playlist["Playlist Items"].([]interface{})
+		for _, itemValue := range items {
+			item, ok := itemValue.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			track, ok := tracks[strconv.FormatInt(plistInt(item, "Track ID"), 10)].(map[string]interface{})
+			if !ok || plistString(track, "Location") == "" {
+				continue
+			}
+
+			entry := PlaylistEntry{
+				Location: plistString(track, "Location"),
+				Title:    plistString(track, "Name"),
+				Seconds:  -1,
+			}
+			if artist := plistString(track, "Artist"); artist != "" && entry.Title != "" {
+				entry.Title = artist + " - " + entry.Title
+			}
+			if totalTime := plistInt(track, "Total Time"); totalTime > 0 {
+				entry.Seconds = int(totalTime / 1000)
+			}
+
+			libraryPlaylist.Entries = append(libraryPlaylist.Entries, entry)
+		}
+
+		playlists = append(playlists, libraryPlaylist)
+	}
+
+	return playlists, nil
+}
+
+// ReadITunesLibrary reads an iTunes/Music.app library export from disk
+func ReadITunesLibrary(path string) ([]LibraryPlaylist, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening library: %w", err)
+	}
+	defer file.Close()
+
+	return ParseITunesLibrary(file)
+}
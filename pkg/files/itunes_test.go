@@ -0,0 +1,111 @@
+package files
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testLibrary = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Major Version</key><integer>1</integer>
+	<key>Tracks</key>
+	<dict>
+		<key>101</key>
+		<dict>
+			<key>Track ID</key><integer>101</integer>
+			<key>Name</key><string>Café</string>
+			<key>Artist</key><string>Bob &amp; Co</string>
+			<key>Total Time</key><integer>215500</integer>
+			<key>Location</key><string>file://localhost/Users/me/Music/Bob%20&amp;%20Co/Caf%C3%A9.mp3</string>
+		</dict>
+		<key>102</key>
+		<dict>
+			<key>Track ID</key><integer>102</integer>
+			<key>Name</key><string>Stream</string>
+		</dict>
+		<key>103</key>
+		<dict>
+			<key>Track ID</key><integer>103</integer>
+			<key>Location</key><string>file:///Users/me/Music/100%25%20Pure%20%231.mp3</string>
+		</dict>
+	</dict>
+	<key>Playlists</key>
+	<array>
+		<dict>
+			<key>Name</key><string>Library</string>
+			<key>Master</key><true/>
+			<key>Playlist Items</key>
+			<array><dict><key>Track ID</key><integer>101</integer></dict></array>
+		</dict>
+		<dict>
+			<key>Name</key><string>Podcasts</string>
+			<key>Distinguished Kind</key><integer>10</integer>
+		</dict>
+		<dict>
+			<key>Name</key><string>Workouts</string>
+			<key>Folder</key><true/>
+		</dict>
+		<dict>
+			<key>Name</key><string>Running</string>
+			<key>Playlist Items</key>
+			<array>
+				<dict><key>Track ID</key><integer>101</integer></dict>
+				<dict><key>Track ID</key><integer>102</integer></dict>
+				<dict><key>Track ID</key><integer>103</integer></dict>
+				<dict><key>Track ID</key><integer>999</integer></dict>
+			</array>
+		</dict>
+	</array>
+</dict>
+</plist>`
+
+func TestParseITunesLibrary(t *testing.T) {
+	playlists, err := ParseITunesLibrary(strings.NewReader(testLibrary))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []LibraryPlaylist{{
+		Name: "Running",
+		Entries: []PlaylistEntry{
+			{Location: "file://localhost/Users/me/Music/Bob%20&%20Co/Caf%C3%A9.mp3", Title: "Bob & Co - Café", Seconds: 215},
+			{Location: "file:///Users/me/Music/100%25%20Pure%20%231.mp3", Seconds: -1},
+		},
+	}}
+	if !reflect.DeepEqual(playlists, want) {
+		t.Fatalf("ParseITunesLibrary() = %#v, want %#v", playlists, want)
+	}
+}
+
+func TestResolveLibraryLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		location string
+		want     string
+	}{
+		{"localhost host", "file://localhost/Users/me/Music/Bob%20&%20Co/Caf%C3%A9.mp3", "/Users/me/Music/Bob & Co/Café.mp3"},
+		{"empty host", "file:///Users/me/Music/100%25%20Pure%20%231.mp3", "/Users/me/Music/100% Pure #1.mp3"},
+		{"upper case scheme", "FILE:///Users/me/Music/A%20B.mp3", "/Users/me/Music/A B.mp3"},
+		{"plus is not a space", "file:///Users/me/Music/A+B.mp3", "/Users/me/Music/A+B.mp3"},
+		{"plain path", "/Users/me/Music/A B.mp3", "/Users/me/Music/A B.mp3"},
+		{"relative path", "Music/A.mp3", "/library/Music/A.mp3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveLocalPath("/library/Library.xml", tt.location); got != tt.want {
+				t.Errorf("ResolveLocalPath(%q) = %q, want %q", tt.location, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseITunesLibraryRejectsNonDictionary(t *testing.T) {
+	library := `<?xml version="1.0"?><plist version="1.0"><array></array></plist>`
+	if _, err := ParseITunesLibrary(strings.NewReader(library)); err == nil {
+		t.Fatal("expected an error for a library that is not a dictionary")
+	}
+}
@@ -0,0 +1,96 @@
+package files
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+// PlaylistEntry is one track of a playlist read from the local filesystem
+type PlaylistEntry struct {
+	Location string
+	Title    string
+	Seconds  int
+}
+
+// ParseM3U reads the entries of an M3U or M3U8 playlist, keeping the #EXTINF
+// title and duration that precede each entry
+func ParseM3U(content string) []PlaylistEntry {
+	content = strings.TrimPrefix(content, "\uFEFF")
+
+	var entries []PlaylistEntry
+	pending := PlaylistEntry{Seconds: -1}
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+
+		if strings.HasPrefix(line, "#EXTINF:") {
+			info := strings.TrimPrefix(line, "#EXTINF:")
+			pending = PlaylistEntry{Seconds: -1}
+			if comma := strings.Index(info, ","); comma >= 0 {
+				pending.Title = strings.TrimSpace(info[comma+1:])
+				info = info[:comma]
+			}
+			// Extended attributes such as tvg-id="..." follow the duration
+			if fields := strings.Fields(info); len(fields) > 0 {
+				if seconds, err := strconv.Atoi(fields[0]); err == nil {
+					pending.Seconds = seconds
+				}
+			}
+			continue
+		}
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pending.Location = line
+		entries = append(entries, pending)
+		pending = PlaylistEntry{Seconds: -1}
+	}
+
+	return entries
+}
+
+// ResolveLocalPath turns a playlist location into a local file path. Relative
+// locations are resolved against the folder of the playlist file.
+func ResolveLocalPath(playlistPath, location string) string {
+	location = strings.TrimSpace(location)
+
+	if strings.HasPrefix(strings.ToLower(location), "file://") {
+		if parsed, err := url.Parse(location); err == nil {
+			location = parsed.Path
+		}
+	}
+
+	if filepath.Separator != '\\' {
+		location = strings.ReplaceAll(location, "\\", "/")
+	}
+	location = filepath.FromSlash(location)
+
+	location = util.ExpandPath(location)
+
+	if !filepath.IsAbs(location) {
+		location = filepath.Join(filepath.Dir(playlistPath), location)
+	}
+
+	return filepath.Clean(location)
+}
+
+// FormatM3U renders entries as an extended M3U playlist
+func FormatM3U(entries []PlaylistEntry) string {
+	var content strings.Builder
+	content.WriteString("#EXTM3U\n")
+
+	for _, entry := range entries {
+		content.WriteString(fmt.Sprintf("#EXTINF:%d,%s\n", entry.Seconds, entry.Title))
+		content.WriteString(entry.Location)
+		content.WriteString("\n")
+	}
+
+	return content.String()
+}
@@ -0,0 +1,335 @@
+package files
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// MP3Info describes the audio stream of an MP3 file
+type MP3Info struct {
+	Duration time.Duration
+	// Bitrate is the average bitrate in kbit/s
+	Bitrate     int
+	SampleRate  int
+	ChannelMode string
+	VBR         bool
+	Frames      int
+	// Estimated is set when the duration was derived from the first frame's bitrate
+	// instead of a VBR header or a frame count
+	Estimated bool
+}
+
+// Seconds returns the duration rounded to whole seconds, as written in #EXTINF lines
+func (i *MP3Info) Seconds() int {
+	return int(i.Duration.Round(time.Second) / time.Second)
+}
+
+var mp3Bitrates = map[[2]int][]int{
+	{1, 1}: {0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+	{1, 2}: {0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+	{1, 3}: {0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	{2, 1}: {0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+	{2, 2}: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	{2, 3}: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+}
+
+var mp3SampleRates = map[int][]int{
+	1: {44100, 48000, 32000},
+	2: {22050, 24000, 16000},
+	3: {11025, 12000, 8000},
+}
+
+var mp3ChannelModes = []string{"stereo", "joint stereo", "dual channel", "mono"}
+
+// mp3Frame is a decoded MPEG audio frame header
+type mp3Frame struct {
+	version     int // 1 = MPEG 1, 2 = MPEG 2, 3 = MPEG 2.5
+	layer       int
+	bitrate     int
+	sampleRate  int
+	channelMode int
+	samples     int
+	size        int
+}
+
+// parseMP3FrameHeader decodes a 4 byte frame header, reporting false for anything that
+// is not a valid frame (free format bitrates are not supported)
+func parseMP3FrameHeader(header []byte) (mp3Frame, bool) {
+	if len(header) < 4 || header[0] != 0xFF || header[1]&0xE0 != 0xE0 {
+		return mp3Frame{}, false
+	}
+
+	frame := mp3Frame{}
+	switch (header[1] >> 3) & 0x03 {
+	case 0:
+		frame.version = 3
+	case 2:
+		frame.version = 2
+	case 3:
+		frame.version = 1
+	default:
+		return mp3Frame{}, false
+	}
+
+	frame.layer = 4 - int((header[1]>>1)&0x03)
+	if frame.layer == 4 {
+		return mp3Frame{}, false
+	}
+
+	bitrateIndex := int(header[2] >> 4)
+	sampleRateIndex := int((header[2] >> 2) & 0x03)
+	if bitrateIndex == 0 || bitrateIndex == 15 || sampleRateIndex == 3 {
+		return mp3Frame{}, false
+	}
+
+	tableVersion := frame.version
+	if tableVersion == 3 {
+		tableVersion = 2
+	}
+	frame.bitrate = mp3Bitrates[[2]int{tableVersion, frame.layer}][bitrateIndex]
+	frame.sampleRate = mp3SampleRates[frame.version][sampleRateIndex]
+	frame.channelMode = int(header[3] >> 6)
+	padding := int((header[2] >> 1) & 0x01)
+
+	switch {
+	case frame.layer == 1:
+		frame.samples = 384
+		frame.size = (12*frame.bitrate*1000/frame.sampleRate + padding) * 4
+	case frame.layer == 3 && frame.version != 1:
+		frame.samples = 576
+		frame.size = 72*frame.bitrate*1000/frame.sampleRate + padding
+	default:
+		frame.samples = 1152
+		frame.size = 144*frame.bitrate*1000/frame.sampleRate + padding
+	}
+
+	return frame, true
+}
+
+// id3v2Size returns the size of an ID3v2 tag at the start of the data, or 0
+func id3v2Size(header []byte) int64 {
+	if len(header) < 10 || string(header[:3]) != "ID3" {
+		return 0
+	}
+	size := int64(header[6])<<21 | int64(header[7])<<14 | int64(header[8])<<7 | int64(header[9])
+	if header[5]&0x10 != 0 {
+		// Footer present
+		size += 10
+	}
+	return size + 10
+}
+
+// findFirstFrame returns the offset of the first audio frame after any ID3v2 tag.
+// A candidate only counts when the next frame header follows where it should.
+func findFirstFrame(r io.ReaderAt, size int64) (int64, mp3Frame, error) {
+	header := make([]byte, 10)
+	if _, err := r.ReadAt(header, 0); err != nil && err != io.EOF {
+		return 0, mp3Frame{}, err
+	}
+	start := id3v2Size(header)
+
+	const searchLimit = 64 * 1024
+	buf := make([]byte, searchLimit)
+	n, err := r.ReadAt(buf, start)
+	if err != nil && err != io.EOF {
+		return 0, mp3Frame{}, err
+	}
+	buf = buf[:n]
+
+	for i := 0; i+4 <= len(buf); i++ {
+		frame, ok := parseMP3FrameHeader(buf[i:])
+		if !ok {
+			continue
+		}
+
+		next := i + frame.size
+		if start+int64(next) >= size || next+4 > len(buf) {
+			return start + int64(i), frame, nil
+		}
+		if _, ok := parseMP3FrameHeader(buf[next:]); ok {
+			return start + int64(i), frame, nil
+		}
+	}
+
+	return 0, mp3Frame{}, fmt.Errorf("no MPEG audio frames found")
+}
+
+// readVBRHeader reads the frame count and stream size from a Xing/Info or VBRI header
+// stored in the first frame
+func readVBRHeader(r io.ReaderAt, offset int64, frame mp3Frame) (frames, bytes int64, vbr bool) {
+	data := make([]byte, 160)
+	n, _ := r.ReadAt(data, offset)
+	data = data[:n]
+
+	sideInfo := 32
+	switch {
+	case frame.version == 1 && frame.channelMode == 3:
+		sideInfo = 17
+	case frame.version != 1 && frame.channelMode == 3:
+		sideInfo = 9
+	case frame.version != 1:
+		sideInfo = 17
+	}
+
+	xing := 4 + sideInfo
+	if len(data) >= xing+16 {
+		tag := string(data[xing : xing+4])
+		if tag == "Xing" || tag == "Info" {
+			flags := binary.BigEndian.Uint32(data[xing+4:])
+			position := xing + 8
+			if flags&0x01 != 0 {
+				frames = int64(binary.BigEndian.Uint32(data[position:]))
+				position += 4
+			}
+			if flags&0x02 != 0 && len(data) >= position+4 {
+				bytes = int64(binary.BigEndian.Uint32(data[position:]))
+			}
+			return frames, bytes, tag == "Xing"
+		}
+	}
+
+	vbri := 4 + 32
+	if len(data) >= vbri+18 && string(data[vbri:vbri+4]) == "VBRI" {
+		bytes = int64(binary.BigEndian.Uint32(data[vbri+10:]))
+		frames = int64(binary.BigEndian.Uint32(data[vbri+14:]))
+		return frames, bytes, true
+	}
+
+	return 0, 0, false
+}
+
+// audioEnd returns where the audio stream ends, excluding a trailing ID3v1 tag
+func audioEnd(r io.ReaderAt, size int64) int64 {
+	if size < 128 {
+		return size
+	}
+	tag := make([]byte, 3)
+	if _, err := r.ReadAt(tag, size-128); err == nil && string(tag) == "TAG" {
+		return size - 128
+	}
+	return size
+}
+
+func newMP3Info(first mp3Frame, frames, samples, bytes int64) *MP3Info {
+	info := &MP3Info{
+		SampleRate:  first.sampleRate,
+		ChannelMode: mp3ChannelModes[first.channelMode],
+		Frames:      int(frames),
+		Bitrate:     first.bitrate,
+	}
+	info.Duration = time.Duration(samples) * time.Second / time.Duration(first.sampleRate)
+	if info.Duration > 0 && bytes > 0 {
+		info.Bitrate = int((bytes*8*int64(time.Second)/int64(info.Duration) + 500) / 1000)
+	}
+	return info
+}
+
+// scanMP3Frames walks every frame from offset to end, counting frames and samples
+func scanMP3Frames(r io.ReaderAt, offset, end int64, first mp3Frame) (*MP3Info, error) {
+	reader := bufio.NewReaderSize(io.NewSectionReader(r, offset, end-offset), 64*1024)
+
+	var frames, samples, bytes int64
+	vbr := false
+	for {
+		header, err := reader.Peek(4)
+		if err != nil {
+			break
+		}
+
+		frame, ok := parseMP3FrameHeader(header)
+		if !ok {
+			// Lost sync, e.g. junk between frames
+			if _, err := reader.Discard(1); err != nil {
+				break
+			}
+			continue
+		}
+
+		discarded, err := reader.Discard(frame.size)
+		bytes += int64(discarded)
+		if err != nil {
+			break
+		}
+		if frame.bitrate != first.bitrate {
+			vbr = true
+		}
+		frames++
+		samples += int64(frame.samples)
+	}
+
+	if frames == 0 {
+		return nil, fmt.Errorf("no MPEG audio frames found")
+	}
+
+	info := newMP3Info(first, frames, samples, bytes)
+	info.VBR = vbr
+	return info, nil
+}
+
+// parseMP3 reads the stream information of MP3 data. Durations come from a Xing/Info
+// or VBRI header when present; otherwise every frame is counted when scan is set, or
+// the duration is estimated from the first frame's bitrate.
+func parseMP3(r io.ReaderAt, size int64, scan bool) (*MP3Info, error) {
+	offset, first, err := findFirstFrame(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	end := audioEnd(r, size)
+	frames, bytes, vbr := readVBRHeader(r, offset, first)
+	if frames > 0 {
+		if bytes == 0 {
+			bytes = end - offset
+		}
+		info := newMP3Info(first, frames, frames*int64(first.samples), bytes)
+		info.VBR = vbr
+		return info, nil
+	}
+
+	if scan {
+		return scanMP3Frames(r, offset, end, first)
+	}
+
+	bytes = end - offset
+	estimatedFrames := bytes / int64(first.size)
+	info := newMP3Info(first, estimatedFrames, 0, 0)
+	info.Duration = time.Duration(bytes*8*int64(time.Second)) / time.Duration(first.bitrate*1000)
+	info.Estimated = true
+	return info, nil
+}
+
+// ParseMP3Info reads the duration, bitrate, sample rate and channel mode of MP3 data,
+// counting frames when there is no VBR header
+func ParseMP3Info(r io.ReaderAt, size int64) (*MP3Info, error) {
+	return parseMP3(r, size, true)
+}
+
+// ParseMP3Header is like ParseMP3Info but never scans the whole stream: without a VBR
+// header the duration is estimated from the first frame. Use it when reads are expensive.
+func ParseMP3Header(r io.ReaderAt, size int64) (*MP3Info, error) {
+	return parseMP3(r, size, false)
+}
+
+// ReadMP3Info reads the stream information of a local MP3 file
+func ReadMP3Info(path string) (*MP3Info, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %w", path, err)
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := ParseMP3Info(file, fileInfo.Size())
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	return info, nil
+}
@@ -0,0 +1,266 @@
+package files
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+var (
+	// MPEG 1 Layer III, 128 kbit/s, 44100 Hz, stereo: 417 byte frames
+	mpeg1Layer3 = []byte{0xFF, 0xFB, 0x90, 0x00}
+	// MPEG 1 Layer III, 192 kbit/s, 44100 Hz, stereo: 626 byte frames
+	mpeg1Layer3At192 = []byte{0xFF, 0xFB, 0xB0, 0x00}
+	// MPEG 2 Layer III, 64 kbit/s, 22050 Hz, mono: 208 byte frames
+	mpeg2Layer3 = []byte{0xFF, 0xF3, 0x80, 0xC0}
+	// MPEG 1 Layer I, 384 kbit/s, 44100 Hz, stereo: 416 byte frames
+	mpeg1Layer1 = []byte{0xFF, 0xFF, 0xC0, 0x00}
+)
+
+// mp3Frames returns count frames with the given header and silent audio
+func mp3Frames(header []byte, count int) []byte {
+	frame, ok := parseMP3FrameHeader(header)
+	if !ok {
+		panic("invalid test frame header")
+	}
+
+	var data []byte
+	for i := 0; i < count; i++ {
+		data = append(data, header...)
+		data = append(data, make([]byte, frame.size-len(header))...)
+	}
+	return data
+}
+
+// xingFrame returns a first frame holding a Xing or Info header with a frame and byte count
+func xingFrame(tag string, frames, size uint32) []byte {
+	data := mp3Frames(mpeg1Layer3, 1)
+	offset := 4 + 32
+	copy(data[offset:], tag)
+	binary.BigEndian.PutUint32(data[offset+4:], 0x03)
+	binary.BigEndian.PutUint32(data[offset+8:], frames)
+	binary.BigEndian.PutUint32(data[offset+12:], size)
+	return data
+}
+
+// vbriFrame returns a first frame holding a VBRI header with a frame and byte count
+func vbriFrame(frames, size uint32) []byte {
+	data := mp3Frames(mpeg1Layer3, 1)
+	offset := 4 + 32
+	copy(data[offset:], "VBRI")
+	binary.BigEndian.PutUint32(data[offset+10:], size)
+	binary.BigEndian.PutUint32(data[offset+14:], frames)
+	return data
+}
+
+// id3v2Tag returns an ID3v2 tag with size bytes of (empty) frames
+func id3v2Tag(size int) []byte {
+	header := []byte{'I', 'D', '3', 3, 0, 0,
+		byte(size >> 21 & 0x7F), byte(size >> 14 & 0x7F), byte(size >> 7 & 0x7F), byte(size & 0x7F)}
+	return append(header, make([]byte, size)...)
+}
+
+// id3v1Tag returns a trailing ID3v1 tag
+func id3v1Tag() []byte {
+	tag := make([]byte, 128)
+	copy(tag, "TAG")
+	return tag
+}
+
+func concat(parts ...[]byte) []byte {
+	var data []byte
+	for _, part := range parts {
+		data = append(data, part...)
+	}
+	return data
+}
+
+func TestParseMP3FrameHeader(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     []byte
+		valid      bool
+		version    int
+		layer      int
+		bitrate    int
+		sampleRate int
+		samples    int
+		size       int
+	}{
+		{"MPEG 1 Layer III", mpeg1Layer3, true, 1, 3, 128, 44100, 1152, 417},
+		{"MPEG 1 Layer III padded", []byte{0xFF, 0xFB, 0x92, 0x00}, true, 1, 3, 128, 44100, 1152, 418},
+		{"MPEG 1 Layer III 48 kHz", []byte{0xFF, 0xFB, 0x94, 0x00}, true, 1, 3, 128, 48000, 1152, 384},
+		{"MPEG 1 Layer II", []byte{0xFF, 0xFD, 0x90, 0x00}, true, 1, 2, 160, 44100, 1152, 522},
+		{"MPEG 2 Layer III", mpeg2Layer3, true, 2, 3, 64, 22050, 576, 208},
+		{"MPEG 2 Layer III padded", []byte{0xFF, 0xF3, 0x82, 0xC0}, true, 2, 3, 64, 22050, 576, 209},
+		{"MPEG 2.5 Layer III", []byte{0xFF, 0xE3, 0x48, 0xC0}, true, 3, 3, 32, 8000, 576, 288},
+		{"MPEG 2 Layer II", []byte{0xFF, 0xF5, 0x80, 0x00}, true, 2, 2, 64, 22050, 1152, 417},
+		{"MPEG 1 Layer I", mpeg1Layer1, true, 1, 1, 384, 44100, 384, 416},
+		{"MPEG 1 Layer I padded", []byte{0xFF, 0xFF, 0xC2, 0x00}, true, 1, 1, 384, 44100, 384, 420},
+		{"MPEG 2 Layer I", []byte{0xFF, 0xF7, 0x80, 0x00}, true, 2, 1, 128, 22050, 384, 276},
+		{"no sync", []byte{0xFF, 0x1B, 0x90, 0x00}, false, 0, 0, 0, 0, 0, 0},
+		{"reserved version", []byte{0xFF, 0xEB, 0x90, 0x00}, false, 0, 0, 0, 0, 0, 0},
+		{"reserved layer", []byte{0xFF, 0xF9, 0x90, 0x00}, false, 0, 0, 0, 0, 0, 0},
+		{"free format bitrate", []byte{0xFF, 0xFB, 0x00, 0x00}, false, 0, 0, 0, 0, 0, 0},
+		{"bad bitrate", []byte{0xFF, 0xFB, 0xF0, 0x00}, false, 0, 0, 0, 0, 0, 0},
+		{"reserved sample rate", []byte{0xFF, 0xFB, 0x9C, 0x00}, false, 0, 0, 0, 0, 0, 0},
+		{"too short", []byte{0xFF, 0xFB, 0x90}, false, 0, 0, 0, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, ok := parseMP3FrameHeader(tt.header)
+			if ok != tt.valid {
+				t.Fatalf("valid = %v, want %v", ok, tt.valid)
+			}
+			if !ok {
+				return
+			}
+			if frame.version != tt.version || frame.layer != tt.layer {
+				t.Errorf("version %d layer %d, want version %d layer %d", frame.version, frame.layer, tt.version, tt.layer)
+			}
+			if frame.bitrate != tt.bitrate || frame.sampleRate != tt.sampleRate {
+				t.Errorf("%d kbit/s at %d Hz, want %d kbit/s at %d Hz", frame.bitrate, frame.sampleRate, tt.bitrate, tt.sampleRate)
+			}
+			if frame.samples != tt.samples || frame.size != tt.size {
+				t.Errorf("%d samples in %d bytes, want %d samples in %d bytes", frame.samples, frame.size, tt.samples, tt.size)
+			}
+		})
+	}
+}
+
+func TestParseMP3Info(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		scan     bool
+		duration time.Duration
+		bitrate  int
+		frames   int
+		vbr      bool
+		// estimated is set when the duration comes from the first frame's bitrate
+		estimated bool
+		channels  string
+	}{
+		{
+			name:     "CBR counted",
+			data:     mp3Frames(mpeg1Layer3, 100),
+			scan:     true,
+			duration: 115200 * time.Second / 44100,
+			bitrate:  128,
+			frames:   100,
+			channels: "stereo",
+		},
+		{
+			name:      "CBR estimated",
+			data:      mp3Frames(mpeg1Layer3, 100),
+			duration:  41700 * 8 * time.Second / 128000,
+			bitrate:   128,
+			frames:    100,
+			estimated: true,
+			channels:  "stereo",
+		},
+		{
+			name:     "CBR between ID3v2 and ID3v1 tags",
+			data:     concat(id3v2Tag(100), mp3Frames(mpeg1Layer3, 100), id3v1Tag()),
+			scan:     true,
+			duration: 115200 * time.Second / 44100,
+			bitrate:  128,
+			frames:   100,
+			channels: "stereo",
+		},
+		{
+			name:     "VBR counted",
+			data:     concat(mp3Frames(mpeg1Layer3, 50), mp3Frames(mpeg1Layer3At192, 50)),
+			scan:     true,
+			duration: 115200 * time.Second / 44100,
+			bitrate:  160,
+			frames:   100,
+			vbr:      true,
+			channels: "stereo",
+		},
+		{
+			name:     "Xing header",
+			data:     concat(xingFrame("Xing", 1000, 500000), mp3Frames(mpeg1Layer3, 10)),
+			duration: 1000 * 1152 * time.Second / 44100,
+			bitrate:  153,
+			frames:   1000,
+			vbr:      true,
+			channels: "stereo",
+		},
+		{
+			name:     "Info header",
+			data:     concat(xingFrame("Info", 1000, 417000), mp3Frames(mpeg1Layer3, 10)),
+			scan:     true,
+			duration: 1000 * 1152 * time.Second / 44100,
+			bitrate:  128,
+			frames:   1000,
+			channels: "stereo",
+		},
+		{
+			name:     "VBRI header",
+			data:     concat(vbriFrame(500, 250000), mp3Frames(mpeg1Layer3, 10)),
+			duration: 500 * 1152 * time.Second / 44100,
+			bitrate:  153,
+			frames:   500,
+			vbr:      true,
+			channels: "stereo",
+		},
+		{
+			name:     "MPEG 2 Layer III",
+			data:     mp3Frames(mpeg2Layer3, 50),
+			scan:     true,
+			duration: 50 * 576 * time.Second / 22050,
+			bitrate:  64,
+			frames:   50,
+			channels: "mono",
+		},
+		{
+			name:     "MPEG 1 Layer I",
+			data:     mp3Frames(mpeg1Layer1, 40),
+			scan:     true,
+			duration: 40 * 384 * time.Second / 44100,
+			// without padding the frames are a little short of the nominal 384 kbit/s
+			bitrate:  382,
+			frames:   40,
+			channels: "stereo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parse := ParseMP3Header
+			if tt.scan {
+				parse = ParseMP3Info
+			}
+
+			info, err := parse(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := info.Duration - tt.duration; diff < -time.Millisecond || diff > time.Millisecond {
+				t.Errorf("duration = %v, want %v", info.Duration, tt.duration)
+			}
+			if info.Bitrate != tt.bitrate {
+				t.Errorf("bitrate = %d, want %d", info.Bitrate, tt.bitrate)
+			}
+			if info.Frames != tt.frames {
+				t.Errorf("frames = %d, want %d", info.Frames, tt.frames)
+			}
+			if info.VBR != tt.vbr || info.Estimated != tt.estimated {
+				t.Errorf("VBR %v estimated %v, want VBR %v estimated %v", info.VBR, info.Estimated, tt.vbr, tt.estimated)
+			}
+			if info.ChannelMode != tt.channels {
+				t.Errorf("channel mode = %q, want %q", info.ChannelMode, tt.channels)
+			}
+		})
+	}
+}
+
+func TestParseMP3InfoWithoutFrames(t *testing.T) {
+	data := concat(id3v2Tag(100), make([]byte, 2000))
+	if _, err := ParseMP3Info(bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Fatal("expected an error for data without MPEG frames")
+	}
+}
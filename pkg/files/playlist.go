@@ -86,6 +86,32 @@ func UploadPlaylistToDevice(dev *mtp.Device, storageID, parentFolderID uint32, p
 	return objectID, nil
 }
 
+// UploadPlaylistData creates a playlist object named fileName from in-memory content
+func UploadPlaylistData(dev *mtp.Device, storageID, parentFolderID uint32, fileName string, data []byte) (uint32, error) {
+
+	info := mtp.ObjectInfo{
+		StorageID:        storageID,
+		ObjectFormat:     0xBA05,
+		ParentObject:     parentFolderID,
+		Filename:         fileName,
+		CompressedSize:   uint32(len(data)),
+		ModificationDate: time.Now(),
+	}
+
+	_, _, objectID, err := dev.SendObjectInfo(storageID, parentFolderID, &info)
+	if err != nil {
+		return 0, fmt.Errorf("error sending playlist info: %w", err)
+	}
+
+	err = dev.SendObject(bytes.NewReader(data), int64(len(data)), EmptyProgressFunc)
+	if err != nil {
+		dev.DeleteObject(objectID)
+		return 0, fmt.Errorf("error sending playlist data: %w", err)
+	}
+
+	return objectID, nil
+}
+
 func RetryUploadPlaylist(dev *mtp.Device, storageID, parentFolderID uint32, playlistName string, songs []string, pathStyle int) error {
 
 	var content strings.Builder
@@ -0,0 +1,98 @@
+package files
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParsePLS reads the entries of a PLS playlist. Entries are ordered by their
+// number, and Title/Length keys are attached to the File key with the same number.
+func ParsePLS(content string) []PlaylistEntry {
+	content = strings.TrimPrefix(content, "\uFEFF")
+
+	entriesByNumber := make(map[int]*PlaylistEntry)
+	entryFor := func(number int) *PlaylistEntry {
+		entry, ok := entriesByNumber[number]
+		if !ok {
+			entry = &PlaylistEntry{Seconds: -1}
+			entriesByNumber[number] = entry
+		}
+		return entry
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "[") || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		equals := strings.Index(line, "=")
+		if equals < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:equals]))
+		value := strings.TrimSpace(line[equals+1:])
+
+		for _, prefix := range []string{"file", "title", "length"} {
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+
+			number, err := strconv.Atoi(key[len(prefix):])
+			if err != nil {
+				// NumberOfEntries, Version and unknown keys
+				break
+			}
+
+			entry := entryFor(number)
+			switch prefix {
+			case "file":
+				entry.Location = value
+			case "title":
+				entry.Title = value
+			case "length":
+				if seconds, err := strconv.Atoi(value); err == nil {
+					entry.Seconds = seconds
+				}
+			}
+			break
+		}
+	}
+
+	numbers := make([]int, 0, len(entriesByNumber))
+	for number := range entriesByNumber {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+
+	var entries []PlaylistEntry
+	for _, number := range numbers {
+		if entry := entriesByNumber[number]; entry.Location != "" {
+			entries = append(entries, *entry)
+		}
+	}
+
+	return entries
+}
+
+// FormatPLS renders entries as a version 2 PLS playlist
+func FormatPLS(entries []PlaylistEntry) string {
+	var content strings.Builder
+	content.WriteString("[playlist]\n")
+
+	for i, entry := range entries {
+		number := i + 1
+		content.WriteString(fmt.Sprintf("File%d=%s\n", number, entry.Location))
+		if entry.Title != "" {
+			content.WriteString(fmt.Sprintf("Title%d=%s\n", number, entry.Title))
+		}
+		content.WriteString(fmt.Sprintf("Length%d=%d\n", number, entry.Seconds))
+	}
+
+	content.WriteString(fmt.Sprintf("NumberOfEntries=%d\n", len(entries)))
+	content.WriteString("Version=2\n")
+
+	return content.String()
+}
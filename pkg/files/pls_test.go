@@ -0,0 +1,142 @@
+package files
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePLS(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []PlaylistEntry
+	}{
+		{
+			name: "version 2",
+			content: "[playlist]\nFile1=/Music/A/ONE.MP3\nTitle1=One\nLength1=200\n" +
+				"File2=/Music/A/TWO.MP3\nLength2=-1\nNumberOfEntries=2\nVersion=2\n",
+			want: []PlaylistEntry{
+				{Location: "/Music/A/ONE.MP3", Title: "One", Seconds: 200},
+				{Location: "/Music/A/TWO.MP3", Seconds: -1},
+			},
+		},
+		{
+			name:    "ordered by number, not by line",
+			content: "[playlist]\nFile10=ten.mp3\nFile2=two.mp3\nTitle2=Two\nFile1=one.mp3\nNumberOfEntries=3\n",
+			want: []PlaylistEntry{
+				{Location: "one.mp3", Seconds: -1},
+				{Location: "two.mp3", Title: "Two", Seconds: -1},
+				{Location: "ten.mp3", Seconds: -1},
+			},
+		},
+		{
+			name:    "titles and lengths before their file",
+			content: "[playlist]\nTitle1=One\nLength1=61\nFile1=one.mp3\n",
+			want:    []PlaylistEntry{{Location: "one.mp3", Title: "One", Seconds: 61}},
+		},
+		{
+			name:    "numbers with gaps",
+			content: "[playlist]\nFile3=three.mp3\nFile7=seven.mp3\n",
+			want: []PlaylistEntry{
+				{Location: "three.mp3", Seconds: -1},
+				{Location: "seven.mp3", Seconds: -1},
+			},
+		},
+		{
+			name:    "keys in any case, CRLF and a byte order mark",
+			content: "\uFEFF[playlist]\r\nFILE1 = one.mp3\r\ntitle1=One\r\nLENGTH1=abc\r\n",
+			want:    []PlaylistEntry{{Location: "one.mp3", Title: "One", Seconds: -1}},
+		},
+		{
+			name:    "title without a file",
+			content: "[playlist]\nFile1=one.mp3\nTitle2=Orphan\nNumberOfEntries=1\n",
+			want:    []PlaylistEntry{{Location: "one.mp3", Seconds: -1}},
+		},
+		{
+			name:    "comments and unknown keys",
+			content: "; comment\n# comment\n[playlist]\nFile1=one.mp3\nFileX=bad.mp3\nVersion=2\n",
+			want:    []PlaylistEntry{{Location: "one.mp3", Seconds: -1}},
+		},
+		{
+			name:    "no entries",
+			content: "[playlist]\nNumberOfEntries=0\nVersion=2\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParsePLS(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePLS() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatPLS(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []PlaylistEntry
+		want    string
+	}{
+		{
+			name: "numbered from one",
+			entries: []PlaylistEntry{
+				{Location: "/Music/A/ONE.MP3", Title: "One", Seconds: 200},
+				{Location: "/Music/A/TWO.MP3", Seconds: -1},
+				{Location: "/Music/B/THREE.MP3", Title: "Three", Seconds: 61},
+			},
+			want: "[playlist]\n" +
+				"File1=/Music/A/ONE.MP3\nTitle1=One\nLength1=200\n" +
+				"File2=/Music/A/TWO.MP3\nLength2=-1\n" +
+				"File3=/Music/B/THREE.MP3\nTitle3=Three\nLength3=61\n" +
+				"NumberOfEntries=3\nVersion=2\n",
+		},
+		{
+			name: "empty",
+			want: "[playlist]\nNumberOfEntries=0\nVersion=2\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FormatPLS(tt.entries)
+			if got != tt.want {
+				t.Errorf("FormatPLS() = %q, want %q", got, tt.want)
+			}
+			if parsed := ParsePLS(got); !reflect.DeepEqual(parsed, tt.entries) {
+				t.Errorf("ParsePLS(FormatPLS()) = %#v, want %#v", parsed, tt.entries)
+			}
+		})
+	}
+}
+
+func TestAppendPLS(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		added   []PlaylistEntry
+		want    string
+	}{
+		{
+			name:    "renumbers after a gap",
+			content: "[playlist]\nFile2=one.mp3\nFile5=two.mp3\nNumberOfEntries=2\nVersion=2\n",
+			added:   []PlaylistEntry{{Location: "three.mp3", Title: "Three", Seconds: 30}},
+			want: "[playlist]\nFile1=one.mp3\nLength1=-1\nFile2=two.mp3\nLength2=-1\n" +
+				"File3=three.mp3\nTitle3=Three\nLength3=30\nNumberOfEntries=3\nVersion=2\n",
+		},
+		{
+			name:    "empty playlist",
+			content: "[playlist]\nNumberOfEntries=0\nVersion=2\n",
+			added:   []PlaylistEntry{{Location: "one.mp3", Seconds: 10}},
+			want:    "[playlist]\nFile1=one.mp3\nLength1=10\nNumberOfEntries=1\nVersion=2\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AppendPlaylist("RUN.PLS", tt.content, tt.added); got != tt.want {
+				t.Errorf("AppendPlaylist() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
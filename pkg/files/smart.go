@@ -0,0 +1,64 @@
+package files
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/schachte/better-sync/pkg/model"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+const smartPlaylistsFile = "smart_playlists.json"
+
+func smartPlaylistsPath() (string, error) {
+	dir, err := util.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, smartPlaylistsFile), nil
+}
+
+// LoadSmartPlaylists reads the stored smart playlist definitions
+func LoadSmartPlaylists() ([]model.SmartPlaylist, error) {
+	path, err := smartPlaylistsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return []model.SmartPlaylist{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading smart playlists: %w", err)
+	}
+
+	var playlists []model.SmartPlaylist
+	if err := json.Unmarshal(data, &playlists); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+
+	return playlists, nil
+}
+
+// SaveSmartPlaylists replaces the stored smart playlist definitions
+func SaveSmartPlaylists(playlists []model.SmartPlaylist) error {
+	path, err := smartPlaylistsPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(playlists, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding smart playlists: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing smart playlists: %w", err)
+	}
+
+	util.LogVerbose("Saved %d smart playlist definitions to %s", len(playlists), path)
+	return nil
+}
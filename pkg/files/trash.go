@@ -0,0 +1,158 @@
+package files
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/schachte/better-sync/pkg/model"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+const trashIndexFile = "trash.json"
+
+// TrashDir returns the directory holding copies of deleted device objects
+func TrashDir() (string, error) {
+	dir, err := util.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	trashDir := filepath.Join(dir, "trash")
+	if err := os.MkdirAll(trashDir, 0755); err != nil {
+		return "", fmt.Errorf("error creating trash directory: %w", err)
+	}
+	return trashDir, nil
+}
+
+// LoadTrash reads the entries of the local trash, oldest first
+func LoadTrash() ([]model.TrashEntry, error) {
+	dir, err := TrashDir()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, trashIndexFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading trash: %w", err)
+	}
+
+	var entries []model.TrashEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("error parsing trash index: %w", err)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].DeletedAt.Before(entries[j].DeletedAt)
+	})
+	return entries, nil
+}
+
+func saveTrash(entries []model.TrashEntry) error {
+	dir, err := TrashDir()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding trash index: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, trashIndexFile), data, 0644); err != nil {
+		return fmt.Errorf("error writing trash index: %w", err)
+	}
+	return nil
+}
+
+// AddToTrash stores the content of a device object in the trash and records the entry,
+// assigning it the next free ID
+func AddToTrash(entry model.TrashEntry, content io.Reader) (model.TrashEntry, error) {
+	entries, err := LoadTrash()
+	if err != nil {
+		return entry, err
+	}
+
+	dir, err := TrashDir()
+	if err != nil {
+		return entry, err
+	}
+
+	entry.ID = 1
+	for _, existing := range entries {
+		if existing.ID >= entry.ID {
+			entry.ID = existing.ID + 1
+		}
+	}
+	entry.File = fmt.Sprintf("%d-%s", entry.ID, path.Base(entry.DevicePath))
+
+	file, err := os.Create(filepath.Join(dir, entry.File))
+	if err != nil {
+		return entry, fmt.Errorf("error creating trash file: %w", err)
+	}
+
+	size, err := io.Copy(file, content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(filepath.Join(dir, entry.File))
+		return entry, fmt.Errorf("error writing trash file: %w", err)
+	}
+	entry.Size = size
+
+	if err := saveTrash(append(entries, entry)); err != nil {
+		os.Remove(filepath.Join(dir, entry.File))
+		return entry, err
+	}
+
+	util.LogVerbose("Moved %s to trash as #%d", entry.DevicePath, entry.ID)
+	return entry, nil
+}
+
+// TrashFilePath returns where the copy of a trash entry is stored
+func TrashFilePath(entry model.TrashEntry) (string, error) {
+	dir, err := TrashDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, entry.File), nil
+}
+
+// RemoveFromTrash deletes trash entries and their copies
+func RemoveFromTrash(ids ...int) error {
+	entries, err := LoadTrash()
+	if err != nil {
+		return err
+	}
+
+	dir, err := TrashDir()
+	if err != nil {
+		return err
+	}
+
+	remove := make(map[int]bool)
+	for _, id := range ids {
+		remove[id] = true
+	}
+
+	var kept []model.TrashEntry
+	for _, entry := range entries {
+		if !remove[entry.ID] {
+			kept = append(kept, entry)
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.File)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing trash file %s: %w", entry.File, err)
+		}
+	}
+
+	return saveTrash(kept)
+}
@@ -0,0 +1,78 @@
+package files
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/schachte/better-sync/pkg/model"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+const uploadHistoryFile = "uploads.json"
+
+func uploadHistoryPath() (string, error) {
+	dir, err := util.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, uploadHistoryFile), nil
+}
+
+// UploadHistoryKey normalizes a device path ("0:/Music/A.mp3" or "/MUSIC/A.MP3") into
+// the key used by the upload history
+func UploadHistoryKey(devicePath string) string {
+	devicePath = strings.TrimPrefix(devicePath, "0:")
+	return strings.ToUpper(filepath.ToSlash(devicePath))
+}
+
+// LoadUploadHistory reads the source of every uploaded track, keyed by UploadHistoryKey
+func LoadUploadHistory() (map[string]model.UploadRecord, error) {
+	path, err := uploadHistoryPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]model.UploadRecord{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading upload history: %w", err)
+	}
+
+	history := make(map[string]model.UploadRecord)
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+
+	return history, nil
+}
+
+// RecordUpload remembers the source of an uploaded track, replacing any earlier record
+// for the same device path
+func RecordUpload(record model.UploadRecord) error {
+	history, err := LoadUploadHistory()
+	if err != nil {
+		return err
+	}
+	history[UploadHistoryKey(record.DevicePath)] = record
+
+	path, err := uploadHistoryPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding upload history: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing upload history: %w", err)
+	}
+
+	return nil
+}
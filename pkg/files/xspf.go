@@ -0,0 +1,61 @@
+package files
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type xspfPlaylist struct {
+	Title  string      `xml:"title"`
+	Tracks []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Locations []string `xml:"location"`
+	Title     string   `xml:"title"`
+	Creator   string   `xml:"creator"`
+	Duration  int      `xml:"duration"`
+}
+
+// ParseXSPF reads the title and tracks of an XSPF playlist. A track title is written
+// as "Creator - Title" when both are present, and durations are converted from ms.
+func ParseXSPF(data []byte) (string, []PlaylistEntry, error) {
+	var playlist xspfPlaylist
+	if err := xml.Unmarshal(data, &playlist); err != nil {
+		return "", nil, fmt.Errorf("error parsing XSPF playlist: %w", err)
+	}
+
+	var entries []PlaylistEntry
+	for _, track := range playlist.Tracks {
+		if len(track.Locations) == 0 {
+			continue
+		}
+
+		entry := PlaylistEntry{
+			Location: strings.TrimSpace(track.Locations[0]),
+			Title:    strings.TrimSpace(track.Title),
+			Seconds:  -1,
+		}
+		if creator := strings.TrimSpace(track.Creator); creator != "" && entry.Title != "" {
+			entry.Title = creator + " - " + entry.Title
+		}
+		if track.Duration > 0 {
+			entry.Seconds = track.Duration / 1000
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return strings.TrimSpace(playlist.Title), entries, nil
+}
+
+// ReadXSPFFile reads a local XSPF playlist
+func ReadXSPFFile(path string) (string, []PlaylistEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("error reading playlist file: %w", err)
+	}
+	return ParseXSPF(data)
+}
@@ -0,0 +1,93 @@
+package files
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseXSPF(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		title   string
+		entries []PlaylistEntry
+	}{
+		{
+			name: "titles, creators and durations",
+			data: `<?xml version="1.0" encoding="UTF-8"?>
+<playlist version="1" xmlns="http://xspf.org/ns/0/">
+  <title> Evening Run </title>
+  <trackList>
+    <track>
+      <location>file:///home/me/Music/Bob%20%26%20Co/Caf%C3%A9.mp3</location>
+      <title>Café</title>
+      <creator>Bob &amp; Co</creator>
+      <duration>215500</duration>
+    </track>
+    <track>
+      <location>Music/B.mp3</location>
+      <location>file:///home/me/Music/ignored.mp3</location>
+      <creator>Nobody</creator>
+    </track>
+    <track>
+      <title>No location</title>
+    </track>
+  </trackList>
+</playlist>`,
+			title: "Evening Run",
+			entries: []PlaylistEntry{
+				{Location: "file:///home/me/Music/Bob%20%26%20Co/Caf%C3%A9.mp3", Title: "Bob & Co - Café", Seconds: 215},
+				{Location: "Music/B.mp3", Seconds: -1},
+			},
+		},
+		{
+			name:  "empty track list",
+			data:  `<playlist version="1" xmlns="http://xspf.org/ns/0/"><trackList/></playlist>`,
+			title: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, entries, err := ParseXSPF([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if title != tt.title {
+				t.Errorf("title = %q, want %q", title, tt.title)
+			}
+			if !reflect.DeepEqual(entries, tt.entries) {
+				t.Errorf("entries = %#v, want %#v", entries, tt.entries)
+			}
+		})
+	}
+}
+
+func TestResolveXSPFLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		location string
+		want     string
+	}{
+		{"escaped characters", "file:///home/me/Music/Bob%20%26%20Co/Caf%C3%A9.mp3", "/home/me/Music/Bob & Co/Café.mp3"},
+		{"escaped hash and percent", "file:///home/me/Music/%23100%25.mp3", "/home/me/Music/#100%.mp3"},
+		{"localhost host", "file://localhost/home/me/Music/A.mp3", "/home/me/Music/A.mp3"},
+		{"surrounding space", "  file:///home/me/Music/A%20B.mp3 ", "/home/me/Music/A B.mp3"},
+		{"relative to the playlist", "Music/B.mp3", "/playlists/Music/B.mp3"},
+		{"parent folder", "../Music/B.mp3", "/Music/B.mp3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveLocalPath("/playlists/run.xspf", tt.location); got != tt.want {
+				t.Errorf("ResolveLocalPath(%q) = %q, want %q", tt.location, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseXSPFRejectsInvalidXML(t *testing.T) {
+	if _, _, err := ParseXSPF([]byte("<playlist><trackList>")); err == nil {
+		t.Fatal("expected an error for a truncated playlist")
+	}
+}
@@ -1,6 +1,10 @@
 package model
 
-import "github.com/ganeshrvel/go-mtpfs/mtp"
+import (
+	"time"
+
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+)
 
 const (
 	PARENT_ROOT    uint32 = 0
@@ -20,6 +24,27 @@ type Playlist struct {
 	StorageID   uint32
 	StorageDesc string
 	SongPaths   []string
+	Validation  *PlaylistValidation
+}
+
+// PlaylistValidation is the result of reading an uploaded playlist back from the device
+// and resolving its entries
+type PlaylistValidation struct {
+	ObjectID      uint32
+	Name          string
+	ExpectedBytes int
+	ActualBytes   int
+	ContentMatch  bool
+	// FirstDifference is the offset of the first differing byte, -1 when the content matches
+	FirstDifference int
+	Entries         int
+	Missing         []string
+	Error           string
+}
+
+// OK reports whether the playlist was stored as generated and every entry resolves
+func (v *PlaylistValidation) OK() bool {
+	return v.Error == "" && v.ContentMatch && len(v.Missing) == 0
 }
 
 func EmptyProgressFunc(_ int64) error {
@@ -111,4 +136,63 @@ type MP3File struct {
 	ParentID    uint32
 	StorageID   uint32
 	DisplayName string
+	LocalPath   string
+	Duration    time.Duration
+}
+
+// Track is a song together with the metadata used to filter and sort it
+type Track struct {
+	Song
+	Artist   string
+	Album    string
+	Title    string
+	Genre    string
+	Year     int
+	Duration time.Duration
+	Size     int64
+	Added    time.Time
+}
+
+// DeviceSettings are remembered for a device, keyed by its serial number
+type DeviceSettings struct {
+	Model     string `json:"model"`
+	PathStyle int    `json:"pathStyle"`
+}
+
+// SmartRule is a single condition of a smart playlist, e.g. artist=Foo or year>=2020
+type SmartRule struct {
+	Field    string `json:"field"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+}
+
+// SmartPlaylist is a locally stored playlist definition evaluated against the device library
+type SmartPlaylist struct {
+	Name     string      `json:"name"`
+	MatchAny bool        `json:"match_any,omitempty"`
+	Rules    []SmartRule `json:"rules"`
+	Sort     string      `json:"sort,omitempty"`
+	Limit    int         `json:"limit,omitempty"`
+	Format   string      `json:"format,omitempty"`
+}
+
+// UploadRecord remembers where an uploaded track came from and how large it was
+type UploadRecord struct {
+	DevicePath string    `json:"devicePath"`
+	LocalPath  string    `json:"localPath"`
+	Size       int64     `json:"size"`
+	UploadedAt time.Time `json:"uploadedAt"`
+}
+
+// TrashEntry is a device object copied to the local trash before it was deleted
+type TrashEntry struct {
+	ID           int       `json:"id"`
+	DevicePath   string    `json:"devicePath"`
+	StorageID    uint32    `json:"storageId"`
+	ObjectFormat uint16    `json:"objectFormat"`
+	Size         int64     `json:"size"`
+	ModifiedAt   time.Time `json:"modifiedAt"`
+	DeletedAt    time.Time `json:"deletedAt"`
+	// File is the name of the copy inside the trash directory
+	File string `json:"file"`
 }
@@ -0,0 +1,126 @@
+package operations
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/schachte/better-sync/pkg/files"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+// BeetsUploadOptions controls an upload from a beets library
+type BeetsUploadOptions struct {
+	// Playlist is the device playlist written for the matching tracks; empty uploads only
+	Playlist string
+	// Replace overwrites a device playlist with the same name
+	Replace bool
+	// Format is the playlist format written to the device, m3u8 or pls
+	Format string
+	// AssumeYes skips the confirmation before uploading
+	AssumeYes bool
+}
+
+// beetsEntries turns beets items into import entries that carry the beets tags, so
+// uploads are placed by the library's artist and album instead of the file's ID3 tags
+func beetsEntries(items []files.BeetsItem) []ImportedEntry {
+	var entries []ImportedEntry
+	for _, item := range items {
+		entry := ImportedEntry{
+			Entry:     item.Path,
+			Title:     item.Title,
+			Seconds:   -1,
+			LocalPath: util.ExpandPath(item.Path),
+			tags:      &trackTags{Artist: item.Artist, Album: item.Album},
+		}
+		if item.Artist != "" && item.Title != "" {
+			entry.Title = item.Artist + " - " + item.Title
+		}
+		if item.Seconds > 0 {
+			entry.Seconds = item.Seconds
+		}
+
+		checkLocalFile(&entry)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// UploadFromBeets uploads the tracks of a beets library that match a beets-style query
+// (e.g. "genre:running year:2020..") and optionally writes them to a playlist. Stopping
+// ctx works as in ImportTracks.
+func UploadFromBeets(ctx context.Context, dev *mtp.Device, storagesRaw interface{}, dbPath string, terms []string, options BeetsUploadOptions) (*PlaylistImportResult, error) {
+	query, err := files.ParseBeetsQuery(terms)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := files.QueryBeetsLibrary(util.ExpandPath(dbPath), query)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no items in the beets library match '%s'", strings.Join(terms, " "))
+	}
+
+	entries := beetsEntries(items)
+
+	fmt.Printf("\n%d tracks match the query:\n", len(entries))
+	for _, entry := range entries {
+		if entry.Status == ImportStatusUnresolved {
+			fmt.Printf("  ? %s (%s)\n", entry.Entry, entry.Reason)
+		} else {
+			fmt.Printf("  %s\n", entry.Title)
+		}
+	}
+
+	if !options.AssumeYes {
+		scanner := bufio.NewScanner(os.Stdin)
+		fmt.Printf("\nUpload %d tracks? (y/n): ", len(entries))
+		scanner.Scan()
+		if strings.ToLower(strings.TrimSpace(scanner.Text())) != "y" {
+			fmt.Println("Operation cancelled.")
+			return nil, nil
+		}
+	}
+
+	importOptions := ImportOptions{
+		Name:    options.Playlist,
+		Replace: options.Replace,
+		Format:  options.Format,
+	}
+	return ImportTracks(ctx, dev, storagesRaw, options.Playlist, entries, importOptions)
+}
+
+func UploadBeetsLibrary(dev *mtp.Device, storagesRaw interface{}) {
+	fmt.Println("\n=== Upload From Beets Library ===")
+
+	scanner := bufio.NewScanner(os.Stdin)
+	fmt.Print("Enter path to beets library.db: ")
+	scanner.Scan()
+	dbPath := strings.Trim(strings.TrimSpace(scanner.Text()), "\"'")
+	if dbPath == "" {
+		fmt.Println("No library path provided")
+		return
+	}
+
+	fmt.Print("Enter beets query (e.g. genre:running year:2020..): ")
+	scanner.Scan()
+	terms := strings.Fields(scanner.Text())
+
+	fmt.Print("Enter playlist name (leave empty to only upload): ")
+	scanner.Scan()
+	playlistName := strings.TrimSpace(scanner.Text())
+
+	result, err := UploadFromBeets(context.Background(), dev, storagesRaw, dbPath, terms, BeetsUploadOptions{Playlist: playlistName})
+	if result != nil {
+		DisplayImportResult(result)
+	}
+	if err != nil {
+		util.LogError("Error uploading from beets: %v", err)
+	}
+}
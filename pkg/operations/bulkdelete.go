@@ -0,0 +1,363 @@
+package operations
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+// DeleteSelector picks the songs removed by RunBulkDelete; every field that is set
+// must match
+type DeleteSelector struct {
+	Artist string
+	Album  string
+	// PathGlob matches the device path, e.g. /MUSIC/PODCAST*/**. * and ? stay within
+	// one folder, ** spans folders. Matching ignores case.
+	PathGlob string
+	// OlderThan matches songs last modified on the device longer ago than this
+	OlderThan time.Duration
+	// LargerThan matches songs bigger than this many bytes
+	LargerThan int64
+	// Paths matches songs by their exact device path; every path must exist
+	Paths []string
+}
+
+// IsEmpty reports whether no selector is set, which would match every song
+func (s DeleteSelector) IsEmpty() bool {
+	return s.Artist == "" && s.Album == "" && s.PathGlob == "" && s.OlderThan == 0 && s.LargerThan == 0 &&
+		len(s.Paths) == 0
+}
+
+// BulkDeleteOptions controls RunBulkDelete
+type BulkDeleteOptions struct {
+	AssumeYes bool
+}
+
+// MatchedSong is a song selected for deletion
+type MatchedSong struct {
+	Path      string
+	ObjectID  uint32
+	StorageID uint32
+	Size      int64
+	ModTime   time.Time
+}
+
+// BulkDeleteResult summarises a bulk deletion
+type BulkDeleteResult struct {
+	Matched   []MatchedSong
+	Size      int64
+	Deleted   int
+	Freed     int64
+	Failed    []string
+	Playlists int
+	// NotDeleted are the matched songs left alone because the deletion was interrupted
+	NotDeleted []string
+}
+
+// compilePathGlob turns a path glob into a case-insensitive regular expression
+func compilePathGlob(pattern string) (*regexp.Regexp, error) {
+	pattern = normalizePath(strings.ReplaceAll(strings.TrimSpace(pattern), "\\", "/"))
+
+	var expr strings.Builder
+	expr.WriteString("(?i)^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "/**/"):
+			expr.WriteString("/(.*/)?")
+			i += 3
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid path glob %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+// nameMatches compares an artist or album with a tag, or with a folder name, which the
+// uploader writes in upper case with underscores
+func nameMatches(value, name string) bool {
+	clean := func(value string) string {
+		return strings.Join(strings.Fields(strings.ReplaceAll(value, "_", " ")), " ")
+	}
+	return strings.EqualFold(clean(value), clean(name))
+}
+
+// objectArtistAlbum reads the artist and album the device reports for a song, falling
+// back to its /MUSIC/ARTIST/ALBUM folders for the ones it does not report
+func objectArtistAlbum(dev *mtp.Device, objectID uint32, path string) (string, string) {
+	artist, album := artistAlbumFromPath(path)
+	if tag := readStringProp(dev, objectID, mtp.OPC_Artist); tag != "" {
+		artist = tag
+	}
+	if tag := readStringProp(dev, objectID, mtp.OPC_AlbumName); tag != "" {
+		album = tag
+	}
+	return artist, album
+}
+
+// FindSongsToDelete walks every storage once and returns the audio files that match
+// the selector
+func FindSongsToDelete(dev *mtp.Device, storagesRaw interface{}, selector DeleteSelector) ([]MatchedSong, error) {
+	if selector.IsEmpty() {
+		return nil, fmt.Errorf("refusing to delete every song: give at least one selector")
+	}
+
+	var glob *regexp.Regexp
+	if selector.PathGlob != "" {
+		var err error
+		if glob, err = compilePathGlob(selector.PathGlob); err != nil {
+			return nil, err
+		}
+	}
+
+	storagesValue := reflect.ValueOf(storagesRaw)
+	if storagesValue.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("invalid storages data format: not a slice")
+	}
+
+	paths := make(map[string]bool)
+	for _, path := range selector.Paths {
+		paths[pathKey(path)] = false
+	}
+
+	cutoff := time.Now().Add(-selector.OlderThan)
+	indexes := newDeviceIndexes(dev, "/Music")
+	var matched []MatchedSong
+
+	for i := 0; i < storagesValue.Len(); i++ {
+		storageID := extractUint32Field(storagesValue.Index(i).Interface(), "Sid")
+
+		index, err := indexes.Get(storageID)
+		if err != nil {
+			util.LogVerbose("Skipping storage %d: %v", storageID, err)
+			continue
+		}
+
+		for _, object := range index.Objects {
+			if object.IsDir || !audioExtensions[strings.ToLower(filepath.Ext(object.Path))] {
+				continue
+			}
+
+			switch {
+			case glob != nil && !glob.MatchString(normalizePath(object.Path)):
+				continue
+			case selector.OlderThan > 0 && !object.ModTime.Before(cutoff):
+				continue
+			case selector.LargerThan > 0 && object.Size <= selector.LargerThan:
+				continue
+			}
+			// Tags are read last, since that takes a request per song
+			if selector.Artist != "" || selector.Album != "" {
+				artist, album := objectArtistAlbum(dev, object.ObjectID, object.Path)
+				if selector.Artist != "" && !nameMatches(artist, selector.Artist) {
+					continue
+				}
+				if selector.Album != "" && !nameMatches(album, selector.Album) {
+					continue
+				}
+			}
+			if len(paths) > 0 {
+				if _, ok := paths[pathKey(object.Path)]; !ok {
+					continue
+				}
+				paths[pathKey(object.Path)] = true
+			}
+
+			matched = append(matched, MatchedSong{
+				Path:      object.Path,
+				ObjectID:  object.ObjectID,
+				StorageID: storageID,
+				Size:      object.Size,
+				ModTime:   object.ModTime,
+			})
+		}
+	}
+
+	var missing []string
+	for _, path := range selector.Paths {
+		if !paths[pathKey(path)] {
+			missing = append(missing, path)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("no matching song at %s", strings.Join(missing, ", "))
+	}
+
+	util.LogInfo("%d songs match the delete selectors", len(matched))
+	return matched, nil
+}
+
+func DisplayBulkDeleteResult(result *BulkDeleteResult) {
+	fmt.Println("\n==== Delete Summary ====")
+	fmt.Printf("Matched:  %d songs (%s)\n", len(result.Matched), util.FormatSize(result.Size))
+	fmt.Printf("Deleted:  %d songs, freed %s\n", result.Deleted, util.FormatSize(result.Freed))
+	if result.Playlists > 0 {
+		fmt.Printf("Updated:  %d playlists\n", result.Playlists)
+	}
+	if len(result.NotDeleted) > 0 {
+		color.HiYellow("Stopped:  %d songs not deleted (interrupted)", len(result.NotDeleted))
+	}
+	if len(result.Failed) > 0 {
+		color.HiRed("Failed:   %d songs", len(result.Failed))
+		for _, path := range result.Failed {
+			fmt.Printf("  %s\n", path)
+		}
+	}
+}
+
+// RunBulkDelete shows the songs matching the selector with their total size, asks once,
+// then deletes them in a batch, updating the playlists that listed them. Once ctx asks
+// to stop, the remaining songs are kept and the playlists are updated for the ones
+// already deleted.
+func RunBulkDelete(ctx context.Context, dev *mtp.Device, storagesRaw interface{}, selector DeleteSelector, options BulkDeleteOptions) (*BulkDeleteResult, error) {
+	songs, err := FindSongsToDelete(dev, storagesRaw, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	out := consoleOf(ctx)
+	result := &BulkDeleteResult{Matched: songs}
+	if len(songs) == 0 {
+		fmt.Fprintln(out, "No songs match.")
+		return result, nil
+	}
+
+	pathColor := color.New(color.Faint).SprintFunc()
+	fmt.Fprintf(out, "\n%d songs match:\n", len(songs))
+	var objectIDs []uint32
+	for _, song := range songs {
+		fmt.Fprintf(out, "  %-70s %10s  %s\n", song.Path, util.FormatSize(song.Size), pathColor(song.ModTime.Format("2006-01-02")))
+		result.Size += song.Size
+		objectIDs = append(objectIDs, song.ObjectID)
+	}
+	fmt.Fprintf(out, "\nTotal: %d songs, %s\n", len(songs), util.FormatSize(result.Size))
+
+	references, err := FindPlaylistReferences(dev, storagesRaw, objectIDs)
+	if err != nil {
+		return result, fmt.Errorf("error checking playlists: %w", err)
+	}
+	DisplayPlaylistReferences(out, references)
+
+	if !options.AssumeYes {
+		fmt.Printf("\nDelete %d songs (%s)? (y/n): ", len(songs), util.FormatSize(result.Size))
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Scan()
+		if strings.ToLower(strings.TrimSpace(scanner.Text())) != "y" {
+			fmt.Println("Operation cancelled.")
+			return result, nil
+		}
+	}
+
+	deleted := make(map[uint32]bool)
+	for i, song := range songs {
+		if stopRequested(ctx) {
+			for _, rest := range songs[i:] {
+				result.NotDeleted = append(result.NotDeleted, rest.Path)
+			}
+			break
+		}
+
+		fmt.Fprintf(out, "[%d/%d] Deleting %s\n", i+1, len(songs), song.Path)
+
+		untrash, err := trashObject(dev, song.StorageID, song.ObjectID, song.Path)
+		if err != nil {
+			util.LogError("Skipping %s: %v", song.Path, err)
+			result.Failed = append(result.Failed, song.Path)
+			continue
+		}
+		if err := deleteWithRetries(dev, song.StorageID, song.ObjectID, song.Path); err != nil {
+			untrash()
+			util.LogError("Could not delete %s: %v", song.Path, err)
+			result.Failed = append(result.Failed, song.Path)
+			continue
+		}
+		deleted[song.ObjectID] = true
+		result.Deleted++
+		result.Freed += song.Size
+	}
+
+	if references = referencesToDeleted(references, deleted); len(references) > 0 {
+		if err := RemovePlaylistReferences(dev, references); err != nil {
+			util.LogError("Error updating playlists: %v", err)
+		} else {
+			result.Playlists = len(references)
+		}
+	}
+
+	pruneAfterDelete(ctx, dev, storagesRaw)
+
+	if len(result.Failed) > 0 {
+		return result, fmt.Errorf("%d songs could not be deleted", len(result.Failed))
+	}
+	if len(result.NotDeleted) > 0 {
+		return result, fmt.Errorf("deletion %w: %d songs were not deleted", ErrInterrupted, len(result.NotDeleted))
+	}
+	return result, nil
+}
+
+// DeleteMatchingSongs is the interactive menu entry for RunBulkDelete
+func DeleteMatchingSongs(dev *mtp.Device, storagesRaw interface{}) {
+	fmt.Println("\n=== Delete Songs Matching Filters ===")
+	fmt.Println("Leave a filter empty to skip it. Songs must match every filter given.")
+
+	scanner := bufio.NewScanner(os.Stdin)
+	ask := func(prompt string) string {
+		fmt.Print(prompt)
+		scanner.Scan()
+		return strings.TrimSpace(scanner.Text())
+	}
+
+	selector := DeleteSelector{
+		Artist:   ask("Artist: "),
+		Album:    ask("Album: "),
+		PathGlob: ask("Device path glob (e.g. /MUSIC/PODCAST*/**): "),
+	}
+
+	if value := ask("Older than (e.g. 30d): "); value != "" {
+		age, err := ParseAge(value)
+		if err != nil {
+			util.LogError("%v", err)
+			return
+		}
+		selector.OlderThan = age
+	}
+
+	if value := ask("Larger than (e.g. 8MB): "); value != "" {
+		size, err := util.ParseSize(value)
+		if err != nil {
+			util.LogError("%v", err)
+			return
+		}
+		selector.LargerThan = size
+	}
+
+	result, err := RunBulkDelete(context.Background(), dev, storagesRaw, selector, BulkDeleteOptions{})
+	if result != nil && result.Deleted+len(result.Failed) > 0 {
+		DisplayBulkDeleteResult(result)
+	}
+	if err != nil {
+		util.LogError("Error deleting songs: %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package operations
+
+import "testing"
+
+func TestCompilePathGlob(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"double star spans folders", "/MUSIC/**/*.mp3", "/MUSIC/ABBA/GOLD/01.mp3", true},
+		{"double star matches no folder", "/MUSIC/**/*.mp3", "/MUSIC/01.mp3", true},
+		{"double star keeps the folder boundary", "/MUSIC/**/*.mp3", "/MUSICAL/01.mp3", false},
+		{"trailing double star", "/MUSIC/**", "/MUSIC/ABBA/GOLD/01.mp3", true},
+		{"leading double star", "**/*.flac", "/MUSIC/ABBA/01.flac", true},
+		{"star within a folder", "/MUSIC/*/01.mp3", "/MUSIC/ABBA/01.mp3", true},
+		{"star stops at a slash", "/MUSIC/*/01.mp3", "/MUSIC/ABBA/GOLD/01.mp3", false},
+		{"star matches nothing", "/MUSIC/ABBA*/01.mp3", "/MUSIC/ABBA/01.mp3", true},
+		{"whole path anchored", "/MUSIC/*.mp3", "/MUSIC/01.mp3.bak", false},
+		{"question mark is one character", "/MUSIC/TRACK?.mp3", "/MUSIC/TRACK1.mp3", true},
+		{"question mark is not two", "/MUSIC/TRACK?.mp3", "/MUSIC/TRACK10.mp3", false},
+		{"question mark is not a slash", "/MUSIC/TRACK?.mp3", "/MUSIC/TRACK/.mp3", false},
+		{"case insensitive", "/music/abba/*.MP3", "/MUSIC/ABBA/01.mp3", true},
+		{"without a leading slash", "MUSIC/*.mp3", "/MUSIC/01.mp3", true},
+		{"backslashes", `\MUSIC\ABBA\*.mp3`, "/MUSIC/ABBA/01.mp3", true},
+		{"doubled slashes", "/MUSIC//ABBA/*.mp3", "/MUSIC/ABBA/01.mp3", true},
+		{"regular expression characters are literal", "/MUSIC/(1)+[a].mp3", "/MUSIC/(1)+[a].mp3", true},
+		{"dot is literal", "/MUSIC/01.mp3", "/MUSIC/01xmp3", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			glob, err := compilePathGlob(tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := glob.MatchString(tt.path); got != tt.want {
+				t.Errorf("%q matches %q = %v, want %v (%s)", tt.pattern, tt.path, got, tt.want, glob)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrInterrupted is returned when work stopped early because it was asked to
+var ErrInterrupted = errors.New("interrupted")
+
+type gracefulStopKey struct{}
+
+// WithGracefulStop returns a context for uploads and deletions that finish the file
+// in progress and then stop once stop is done. Cancelling ctx itself also aborts the
+// file in progress.
+func WithGracefulStop(ctx, stop context.Context) context.Context {
+	return context.WithValue(ctx, gracefulStopKey{}, stop)
+}
+
+// stopRequested reports whether work should stop before the next file
+func stopRequested(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return true
+	}
+	stop, ok := ctx.Value(gracefulStopKey{}).(context.Context)
+	return ok && stop.Err() != nil
+}
+
+// contextReader fails once its context is cancelled, which aborts a transfer reading
+// from it
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
+
+// contextWriter fails once its context is cancelled, which aborts a transfer writing
+// to it
+type contextWriter struct {
+	ctx    context.Context
+	writer io.Writer
+}
+
+func (w contextWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return w.writer.Write(p)
+}
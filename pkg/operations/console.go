@@ -0,0 +1,28 @@
+package operations
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+type consoleKey struct{}
+
+// WithConsole returns a context whose operations write their progress and messages to
+// w instead of the terminal; a nil w discards them. Operations run with it never
+// prompt.
+func WithConsole(ctx context.Context, w io.Writer) context.Context {
+	if w == nil {
+		w = io.Discard
+	}
+	return context.WithValue(ctx, consoleKey{}, w)
+}
+
+// consoleOf returns where an operation run with ctx writes its progress and messages,
+// by default standard output
+func consoleOf(ctx context.Context) io.Writer {
+	if w, ok := ctx.Value(consoleKey{}).(io.Writer); ok {
+		return w
+	}
+	return os.Stdout
+}
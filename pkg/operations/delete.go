@@ -3,6 +3,8 @@ package operations
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -12,6 +14,7 @@ import (
 
 	"github.com/ganeshrvel/go-mtpfs/mtp"
 	"github.com/ganeshrvel/go-mtpx"
+	"github.com/schachte/better-sync/pkg/files"
 	"github.com/schachte/better-sync/pkg/model"
 	"github.com/schachte/better-sync/pkg/util"
 )
@@ -99,7 +102,14 @@ func DeletePlaylist(dev *mtp.Device, storagesRaw interface{}) {
 		return
 	}
 
-	err := dev.DeleteObject(selected.ObjectID)
+	untrash, err := trashObject(dev, selected.StorageID, selected.ObjectID, selected.Path)
+	if err != nil {
+		util.LogError("Error moving playlist to trash: %v", err)
+		fmt.Println("The playlist was not deleted.")
+		return
+	}
+
+	err = dev.DeleteObject(selected.ObjectID)
 	if err != nil {
 		util.LogError("Error deleting playlist: %v", err)
 		fmt.Printf("Error deleting playlist: %v\n", err)
@@ -107,6 +117,7 @@ func DeletePlaylist(dev *mtp.Device, storagesRaw interface{}) {
 		fmt.Println("Trying alternative deletion method...")
 		err = tryAlternativeDeleteMethod(dev, selected.StorageID, selected.ObjectID)
 		if err != nil {
+			untrash()
 			fmt.Printf("Alternative deletion failed: %v\n", err)
 			return
 		}
@@ -244,20 +255,36 @@ func DeleteSong(dev *mtp.Device, storagesRaw interface{}) {
 		return
 	}
 
-	fmt.Println("\nWARNING: If this song is included in any playlists, those playlists may be affected.")
-	fmt.Print("Continue with deletion? (y/n): ")
-	scanner.Scan()
-	confirmAgain := strings.ToLower(scanner.Text())
-	if confirmAgain != "y" && confirmAgain != "yes" {
-		fmt.Println("Deletion cancelled.")
-		return
+	fmt.Println("\nChecking playlists for this song...")
+	references, err := FindPlaylistReferences(dev, storagesRaw, []uint32{selectedSong.ObjectID})
+	if err != nil {
+		util.LogError("Error checking playlists: %v", err)
+		fmt.Println("\nWARNING: Playlists could not be checked, so any playlist listing this song will keep a broken entry.")
+	}
+	DisplayPlaylistReferences(os.Stdout, references)
+
+	if len(references) > 0 || err != nil {
+		fmt.Print("Continue with deletion? (y/n): ")
+		scanner.Scan()
+		confirmAgain := strings.ToLower(scanner.Text())
+		if confirmAgain != "y" && confirmAgain != "yes" {
+			fmt.Println("Deletion cancelled.")
+			return
+		}
 	}
 
 	util.LogInfo("Deleting song '%s'...", selectedSong.DisplayName)
 	util.LogInfo("Attempting to delete song %s (Path: %s, ObjectID: %d, StorageID: %d)",
 		selectedSong.DisplayName, selectedSong.Path, selectedSong.ObjectID, selectedSong.StorageID)
 
-	err := dev.DeleteObject(selectedSong.ObjectID)
+	untrash, err := trashObject(dev, selectedSong.StorageID, selectedSong.ObjectID, selectedSong.Path)
+	if err != nil {
+		util.LogError("Error moving song to trash: %v", err)
+		fmt.Println("The song was not deleted.")
+		return
+	}
+
+	err = dev.DeleteObject(selectedSong.ObjectID)
 	if err != nil {
 		util.LogError("Error deleting song: %v", err)
 		fmt.Printf("Error deleting song: %v\n", err)
@@ -269,6 +296,7 @@ func DeleteSong(dev *mtp.Device, storagesRaw interface{}) {
 
 			err = tryAlternativeDeleteMethod(dev, selectedSong.StorageID, selectedSong.ObjectID)
 			if err != nil {
+				untrash()
 				util.LogError("Alternative deletion method failed: %v", err)
 				fmt.Printf("Alternative deletion method failed: %v\n", err)
 
@@ -282,13 +310,22 @@ func DeleteSong(dev *mtp.Device, storagesRaw interface{}) {
 				util.LogInfo("Successfully deleted song '%s' using alternative method", selectedSong.DisplayName)
 			}
 		} else {
+			untrash()
 			return
 		}
 	} else {
 		util.LogInfo("Successfully deleted song %s", selectedSong.DisplayName)
 	}
 
-	fmt.Println("\nNote: If the song was part of any playlists, you may need to update those playlists manually.")
+	if len(references) > 0 {
+		if err := RemovePlaylistReferences(dev, references); err != nil {
+			util.LogError("Error updating playlists: %v", err)
+		} else {
+			fmt.Printf("Removed the song from %d playlists\n", len(references))
+		}
+	}
+
+	pruneAfterDelete(context.Background(), dev, storagesRaw)
 }
 
 func tryAlternativeDeleteMethod(dev *mtp.Device, storageID, objectID uint32) error {
@@ -344,27 +381,66 @@ func FindObjectByDirectPath(dev *mtp.Device, storageID uint32, path string) (uin
 	return 0, fmt.Errorf("object not found using direct path: %s", path)
 }
 
-func FindSongByMixedCaseAndRelativePath(dev *mtp.Device, storageID uint32, path string) (uint32, error) {
-	util.LogVerbose("Trying flexible matching for path: %s", path)
+// songMatcher holds the parts of a stale path used for flexible matching
+type songMatcher struct {
+	fileName         string
+	fileNameNoNumber string
+	artistFolder     string
+	albumFolder      string
+}
 
+func newSongMatcher(path string) songMatcher {
 	fileName := filepath.Base(path)
 
-	fileNameNoNumber := stripNumericPrefix(fileName)
+	matcher := songMatcher{
+		fileName:         fileName,
+		fileNameNoNumber: stripNumericPrefix(fileName),
+	}
 
 	folderPath := filepath.Dir(path)
 	pathComponents := strings.Split(folderPath, "/")
 
-	var artistFolder, albumFolder string
 	if len(pathComponents) >= 3 {
 		for i, comp := range pathComponents {
 			if strings.EqualFold(comp, "Music") && i+2 < len(pathComponents) {
-				artistFolder = pathComponents[i+1]
-				albumFolder = pathComponents[i+2]
+				matcher.artistFolder = pathComponents[i+1]
+				matcher.albumFolder = pathComponents[i+2]
 				break
 			}
 		}
 	}
 
+	return matcher
+}
+
+// match returns 0 when itemPath does not match, otherwise a rank where lower is stronger
+func (m songMatcher) match(itemPath string) (int, string) {
+	itemFileName := filepath.Base(itemPath)
+	if strings.EqualFold(itemFileName, m.fileName) {
+		return 1, "exact filename match"
+	}
+
+	itemFileNameNoNumber := stripNumericPrefix(itemFileName)
+	if m.fileNameNoNumber != "" && strings.EqualFold(itemFileNameNoNumber, m.fileNameNoNumber) {
+		return 2, "filename match without numeric prefix"
+	}
+
+	if m.artistFolder != "" && m.albumFolder != "" {
+		if strings.Contains(strings.ToUpper(itemPath), strings.ToUpper(m.artistFolder)) &&
+			strings.Contains(strings.ToUpper(itemPath), strings.ToUpper(m.albumFolder)) &&
+			strings.Contains(strings.ToUpper(itemFileName), strings.ToUpper(m.fileNameNoNumber)) {
+			return 3, "artist/album/name pattern match"
+		}
+	}
+
+	return 0, ""
+}
+
+func FindSongByMixedCaseAndRelativePath(dev *mtp.Device, storageID uint32, path string) (uint32, error) {
+	util.LogVerbose("Trying flexible matching for path: %s", path)
+
+	matcher := newSongMatcher(path)
+
 	var foundObject uint32
 	var found bool
 	var matchReason string
@@ -379,34 +455,13 @@ func FindSongByMixedCaseAndRelativePath(dev *mtp.Device, storageID uint32, path
 				return nil
 			}
 
-			itemFileName := filepath.Base(fi.FullPath)
-			if strings.EqualFold(itemFileName, fileName) {
+			if rank, reason := matcher.match(fi.FullPath); rank > 0 {
 				foundObject = objectID
 				found = true
-				matchReason = "exact filename match"
+				matchReason = reason
 				return fmt.Errorf("found")
 			}
 
-			itemFileNameNoNumber := stripNumericPrefix(itemFileName)
-			if fileNameNoNumber != "" && strings.EqualFold(itemFileNameNoNumber, fileNameNoNumber) {
-				foundObject = objectID
-				found = true
-				matchReason = "filename match without numeric prefix"
-				return fmt.Errorf("found")
-			}
-
-			if artistFolder != "" && albumFolder != "" {
-				itemPath := fi.FullPath
-				if strings.Contains(strings.ToUpper(itemPath), strings.ToUpper(artistFolder)) &&
-					strings.Contains(strings.ToUpper(itemPath), strings.ToUpper(albumFolder)) &&
-					strings.Contains(strings.ToUpper(itemFileName), strings.ToUpper(fileNameNoNumber)) {
-					foundObject = objectID
-					found = true
-					matchReason = "artist/album/name pattern match"
-					return fmt.Errorf("found")
-				}
-			}
-
 			return nil
 		})
 
@@ -480,21 +535,12 @@ func ReadPlaylistContent(dev *mtp.Device, storageID, objectID uint32) ([]string,
 	return ParsePlaylistContent(content), nil
 }
 
+// ParsePlaylistContent returns the song paths of an M3U or PLS playlist
 func ParsePlaylistContent(content string) []string {
 	var songs []string
 
-	lines := strings.Split(content, "\n")
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		songPath := line
-
-		songs = append(songs, songPath)
+	for _, entry := range files.ParsePlaylist("", content) {
+		songs = append(songs, entry.Location)
 	}
 
 	return songs
@@ -545,7 +591,49 @@ func TryAlternativeDeleteMethod(dev *mtp.Device, storageID, objectID uint32) err
 	return nil
 }
 
-func DeleteFolderRecursively(dev *mtp.Device, storageID, folderID uint32, folderPath string, requireConfirmation bool) error {
+// deleteDeviceObject deletes an object, falling back to the alternative method when
+// the device refuses a plain DeleteObject
+func deleteDeviceObject(dev *mtp.Device, storageID, objectID uint32) error {
+	err := dev.DeleteObject(objectID)
+	if err == nil {
+		return nil
+	}
+
+	util.LogVerbose("Error deleting object %d: %v", objectID, err)
+	if altErr := TryAlternativeDeleteMethod(dev, storageID, objectID); altErr != nil {
+		return fmt.Errorf("%v (alternative method: %v)", err, altErr)
+	}
+	return nil
+}
+
+// deleteWithRetries deletes an object, retrying a few times for devices that report
+// busy, then falling back to the alternative delete method
+func deleteWithRetries(dev *mtp.Device, storageID, objectID uint32, path string) error {
+	var err error
+	for attempt := 1; attempt <= 3; attempt++ {
+		err = dev.DeleteObject(objectID)
+		if err == nil {
+			return nil
+		}
+
+		util.LogError("Attempt %d: Error deleting %s (ID: %d): %v", attempt, path, objectID, err)
+
+		if attempt < 3 {
+			time.Sleep(500 * time.Millisecond)
+		}
+	}
+
+	util.LogInfo("Trying alternative deletion method for %s...", path)
+	if altErr := TryAlternativeDeleteMethod(dev, storageID, objectID); altErr != nil {
+		return fmt.Errorf("%v (alternative method: %v)", err, altErr)
+	}
+	return nil
+}
+
+// DeleteFolderRecursively deletes everything in a folder and then the folder itself,
+// unless it is the Music folder. Once ctx asks to stop, the remaining items and the
+// folders holding them are kept and the error wraps ErrInterrupted.
+func DeleteFolderRecursively(ctx context.Context, dev *mtp.Device, storageID, folderID uint32, folderPath string, requireConfirmation bool) error {
 	if folderPath == "/" {
 		return fmt.Errorf("refusing to delete root folder")
 	}
@@ -572,6 +660,10 @@ func DeleteFolderRecursively(dev *mtp.Device, storageID, folderID uint32, folder
 	failedItems := 0
 
 	for i, handle := range handles.Values {
+		if stopRequested(ctx) {
+			return fmt.Errorf("deletion of %s %w: %d items were not deleted", folderPath, ErrInterrupted,
+				len(handles.Values)-i)
+		}
 		util.LogVerbose("[%d/%d] Processing item", i+1, len(handles.Values))
 
 		info := mtp.ObjectInfo{}
@@ -586,7 +678,10 @@ func DeleteFolderRecursively(dev *mtp.Device, storageID, folderID uint32, folder
 
 		if info.ObjectFormat == FILETYPE_FOLDER {
 			util.LogInfo("Processing subfolder: %s", info.Filename)
-			subErr := DeleteFolderRecursively(dev, storageID, handle, itemPath, false)
+			subErr := DeleteFolderRecursively(ctx, dev, storageID, handle, itemPath, false)
+			if errors.Is(subErr, ErrInterrupted) {
+				return subErr
+			}
 			if subErr != nil {
 				util.LogError("Error deleting subfolder %s: %v", itemPath, subErr)
 				failedItems++
@@ -598,32 +693,17 @@ func DeleteFolderRecursively(dev *mtp.Device, storageID, folderID uint32, folder
 
 		util.LogInfo("Deleting file: %s", info.Filename)
 
-		deleteSuccess := false
-		for attempt := 1; attempt <= 3; attempt++ {
-			err = dev.DeleteObject(handle)
-			if err == nil {
-				deleteSuccess = true
-				break
-			}
-
-			util.LogError("Attempt %d: Error deleting file %s (ID: %d): %v",
-				attempt, itemPath, handle, err)
-
-			if attempt < 3 {
-				time.Sleep(500 * time.Millisecond)
-			}
+		untrash, err := trashObject(dev, storageID, handle, itemPath)
+		if err != nil {
+			util.LogError("Skipping %s: %v", itemPath, err)
+			failedItems++
+			continue
 		}
 
-		if !deleteSuccess {
-			util.LogInfo("Trying alternative deletion method...")
-			err = TryAlternativeDeleteMethod(dev, storageID, handle)
-			if err != nil {
-				util.LogError("Alternative deletion method failed for %s: %v", itemPath, err)
-				failedItems++
-			} else {
-				deleteSuccess = true
-				deletedFiles++
-			}
+		if err := deleteWithRetries(dev, storageID, handle, itemPath); err != nil {
+			untrash()
+			util.LogError("Could not delete file %s: %v", itemPath, err)
+			failedItems++
 		} else {
 			deletedFiles++
 		}
@@ -633,31 +713,9 @@ func DeleteFolderRecursively(dev *mtp.Device, storageID, folderID uint32, folder
 	if folderID != 0 && !strings.EqualFold(folderPath, "/music") && !strings.EqualFold(folderPath, "/music/") {
 		util.LogInfo("Deleting folder itself: %s", folderPath)
 
-		deleteSuccess := false
-		for attempt := 1; attempt <= 3; attempt++ {
-			err = dev.DeleteObject(folderID)
-			if err == nil {
-				deleteSuccess = true
-				break
-			}
-
-			util.LogError("Attempt %d: Error deleting folder %s (ID: %d): %v",
-				attempt, folderPath, folderID, err)
-
-			if attempt < 3 {
-				time.Sleep(500 * time.Millisecond)
-			}
-		}
-
-		if !deleteSuccess {
-			util.LogInfo("Trying alternative deletion method for folder...")
-			err = TryAlternativeDeleteMethod(dev, storageID, folderID)
-			if err != nil {
-				util.LogError("Alternative deletion method failed for folder %s: %v", folderPath, err)
-				failedItems++
-			} else {
-				deletedFolders++
-			}
+		if err := deleteWithRetries(dev, storageID, folderID, folderPath); err != nil {
+			util.LogError("Could not delete folder %s: %v", folderPath, err)
+			failedItems++
 		} else {
 			deletedFolders++
 		}
@@ -675,11 +733,21 @@ func DeleteFolderRecursively(dev *mtp.Device, storageID, folderID uint32, folder
 	return nil
 }
 
+// WipeMusic deletes everything in the Music folder of the device, keeping the folder
+// itself. Once ctx asks to stop, the remaining files are kept.
+func WipeMusic(ctx context.Context, dev *mtp.Device, storagesRaw interface{}) error {
+	storageID, musicFolderID, err := SelectStorageAndMusicFolder(ctx, dev, storagesRaw)
+	if err != nil {
+		return fmt.Errorf("error selecting storage: %w", err)
+	}
+	return DeleteFolderRecursively(ctx, dev, storageID, musicFolderID, "/Music", false)
+}
+
 func DeleteFolder(dev *mtp.Device, storagesRaw interface{}) {
 	util.LogInfo("\n=== Delete Folder and Contents ===")
 	util.LogInfo("Starting folder deletion operation")
 
-	storageID, musicFolderID, err := SelectStorageAndMusicFolder(dev, storagesRaw)
+	storageID, musicFolderID, err := SelectStorageAndMusicFolder(context.Background(), dev, storagesRaw)
 	if err != nil {
 		util.LogError("Error selecting storage: %v", err)
 		fmt.Printf("Error: %v\n", err)
@@ -720,7 +788,7 @@ func DeleteFolder(dev *mtp.Device, storagesRaw interface{}) {
 		fmt.Printf("\n!!! WARNING: About to delete the entire %s folder !!!\n", musicFolderPath)
 		fmt.Println("This will erase ALL music, playlists, and folders on your device.")
 
-		err = DeleteFolderRecursively(dev, storageID, musicFolderID, musicFolderPath, true)
+		err = DeleteFolderRecursively(context.Background(), dev, storageID, musicFolderID, musicFolderPath, true)
 		if err != nil {
 			util.LogError("Error deleting Music folder: %v", err)
 			fmt.Printf("Error: %v\n", err)
@@ -784,7 +852,11 @@ func DeleteFolder(dev *mtp.Device, storagesRaw interface{}) {
 
 	fmt.Printf("\n!!! WARNING !!!\n")
 	fmt.Printf("You are about to delete the folder '%s' AND ALL ITS CONTENTS.\n", selectedFolder.Path)
-	fmt.Printf("This operation CANNOT BE UNDONE and will delete ALL files and subfolders.\n")
+	if TrashEnabled() {
+		fmt.Printf("Deleted files are copied to the local trash and can be restored with 'better-sync trash restore'.\n")
+	} else {
+		fmt.Printf("This operation CANNOT BE UNDONE and will delete ALL files and subfolders.\n")
+	}
 	fmt.Print("Type 'DELETE ALL' (all caps) to confirm this operation: ")
 
 	var confirmText string
@@ -797,7 +869,7 @@ func DeleteFolder(dev *mtp.Device, storagesRaw interface{}) {
 		return
 	}
 
-	err = DeleteFolderRecursively(dev, storageID, selectedFolder.ID, selectedFolder.Path, false)
+	err = DeleteFolderRecursively(context.Background(), dev, storageID, selectedFolder.ID, selectedFolder.Path, false)
 	if err != nil {
 		util.LogError("Error during recursive deletion: %v", err)
 		fmt.Printf("Error: %v\n", err)
@@ -805,6 +877,8 @@ func DeleteFolder(dev *mtp.Device, storagesRaw interface{}) {
 	} else {
 		util.LogInfo("Successfully deleted folder '%s' and all its contents.", selectedFolder.Path)
 	}
+
+	pruneAfterDelete(context.Background(), dev, storagesRaw)
 }
 
 func ExtractAndUploadAlbumArt(dev *mtp.Device, storageID uint32, parentID uint32, sourceFilePath string, artistName string, albumName string) (uint32, error) {
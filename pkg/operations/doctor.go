@@ -0,0 +1,306 @@
+package operations
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/schachte/better-sync/pkg/files"
+	"github.com/schachte/better-sync/pkg/model"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+const (
+	EntryOK      = "ok"
+	EntryFixable = "fixable"
+	EntryBroken  = "broken"
+)
+
+// PlaylistEntryCheck is the result of resolving one playlist entry against the device
+type PlaylistEntryCheck struct {
+	Index  int
+	Entry  string
+	Status string
+	Match  string
+	Reason string
+}
+
+// PlaylistCheck holds the entry results for one playlist
+type PlaylistCheck struct {
+	Playlist model.PlaylistInfo
+	Entries  []PlaylistEntryCheck
+	// Error is why the playlist could not be read or repaired
+	Error string
+	// Repaired is set once RunPlaylistDoctor rewrote the playlist
+	Repaired bool
+	format   string
+	entries  []files.PlaylistEntry
+}
+
+func (c *PlaylistCheck) Count(status string) int {
+	count := 0
+	for _, entry := range c.Entries {
+		if entry.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// NeedsRepair reports whether rewriting the playlist would change it
+func (c *PlaylistCheck) NeedsRepair(dropBroken bool) bool {
+	return c.Count(EntryFixable) > 0 || (dropBroken && c.Count(EntryBroken) > 0)
+}
+
+// PlaylistRepairOptions controls how RunPlaylistDoctor applies its fixes
+type PlaylistRepairOptions struct {
+	AssumeYes  bool
+	KeepBroken bool
+}
+
+// CheckPlaylists resolves every entry of the named playlists (all when names is empty)
+// against the files under /Music and proposes replacements for stale entries. Once ctx
+// asks to stop, the error wraps ErrInterrupted.
+func CheckPlaylists(ctx context.Context, dev *mtp.Device, storagesRaw interface{}, names []string) ([]PlaylistCheck, error) {
+	playlists, err := GetPlaylists(dev, storagesRaw)
+	if err != nil {
+		return nil, fmt.Errorf("error getting playlists: %w", err)
+	}
+
+	if len(names) > 0 {
+		var selected []model.PlaylistInfo
+		for _, name := range names {
+			playlist := findPlaylist(playlists, name)
+			if playlist == nil {
+				return nil, fmt.Errorf("playlist '%s' not found", name)
+			}
+			selected = append(selected, *playlist)
+		}
+		playlists = selected
+	}
+
+	indexes := newDeviceIndexes(dev, "/Music")
+	var checks []PlaylistCheck
+
+	for _, playlist := range playlists {
+		if stopRequested(ctx) {
+			return checks, fmt.Errorf("playlist check %w", ErrInterrupted)
+		}
+		check := PlaylistCheck{Playlist: playlist}
+
+		index, err := indexes.Get(playlist.StorageID)
+		if err != nil {
+			check.Error = err.Error()
+			checks = append(checks, check)
+			continue
+		}
+
+		data, err := readObjectData(dev, playlist.ObjectID)
+		if err != nil {
+			check.Error = err.Error()
+			checks = append(checks, check)
+			continue
+		}
+
+		check.format = files.PlaylistFormatOf(playlist.Name, string(data))
+		check.entries = files.ParsePlaylist(playlist.Name, string(data))
+		for i, playlistEntry := range check.entries {
+			entry := strings.TrimSpace(playlistEntry.Location)
+
+			result := PlaylistEntryCheck{Index: i, Entry: entry, Status: EntryOK}
+			if _, ok := index.Lookup(entry); !ok {
+				if object, reason, found := index.FindSimilar(entry); found {
+					result.Status = EntryFixable
+					result.Match = object.Path
+					result.Reason = reason
+				} else {
+					result.Status = EntryBroken
+					result.Reason = "no matching song on the device"
+				}
+			}
+
+			check.Entries = append(check.Entries, result)
+		}
+
+		util.LogVerbose("Checked playlist %s: %d ok, %d fixable, %d broken", playlist.Path,
+			check.Count(EntryOK), check.Count(EntryFixable), check.Count(EntryBroken))
+		checks = append(checks, check)
+	}
+
+	return checks, nil
+}
+
+// formatEntryLike writes a device path in the same style as an existing playlist entry
+func formatEntryLike(original, devicePath string) string {
+	path := normalizePath(devicePath)
+
+	switch {
+	case strings.HasPrefix(original, "0:"):
+		path = "0:" + path
+	case !strings.HasPrefix(original, "/"):
+		path = strings.TrimPrefix(path, "/")
+	}
+
+	if original == strings.ToUpper(original) {
+		path = strings.ToUpper(path)
+	}
+	return path
+}
+
+// repairedPlaylistContent applies the fixes of a check to the playlist entries and
+// renders them in the playlist's original format
+func repairedPlaylistContent(check *PlaylistCheck, dropBroken bool) string {
+	entries := append([]files.PlaylistEntry(nil), check.entries...)
+	removed := make(map[int]bool)
+
+	for _, entry := range check.Entries {
+		switch {
+		case entry.Status == EntryFixable:
+			entries[entry.Index].Location = formatEntryLike(entry.Entry, entry.Match)
+		case entry.Status == EntryBroken && dropBroken:
+			removed[entry.Index] = true
+		}
+	}
+
+	var kept []files.PlaylistEntry
+	for i, entry := range entries {
+		if !removed[i] {
+			kept = append(kept, entry)
+		}
+	}
+	return files.FormatPlaylist(check.format, kept)
+}
+
+// RepairPlaylist rewrites a checked playlist with its fixes applied
+func RepairPlaylist(ctx context.Context, dev *mtp.Device, check *PlaylistCheck, dropBroken bool) error {
+	if !check.NeedsRepair(dropBroken) {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	content := repairedPlaylistContent(check, dropBroken)
+	objectID, err := replacePlaylistData(dev, check.Playlist, []byte(content))
+	if err != nil {
+		return err
+	}
+
+	check.Playlist.ObjectID = objectID
+	util.LogInfo("Repaired playlist %s", check.Playlist.Path)
+	return nil
+}
+
+func DisplayPlaylistChecks(checks []PlaylistCheck) {
+	okColor := color.New(color.FgHiGreen).SprintFunc()
+	fixColor := color.New(color.FgHiYellow).SprintFunc()
+	brokenColor := color.New(color.FgHiRed).SprintFunc()
+
+	fmt.Println("\n==== Playlist Doctor ====")
+	for _, check := range checks {
+		fmt.Printf("\n[%s] %s\n", check.Playlist.Storage, check.Playlist.Path)
+		if check.Error != "" {
+			fmt.Printf("  %s\n", brokenColor("error: "+check.Error))
+			continue
+		}
+
+		fmt.Printf("  %s, %s, %s\n",
+			okColor(fmt.Sprintf("%d ok", check.Count(EntryOK))),
+			fixColor(fmt.Sprintf("%d fixable", check.Count(EntryFixable))),
+			brokenColor(fmt.Sprintf("%d broken", check.Count(EntryBroken))))
+
+		for _, entry := range check.Entries {
+			switch entry.Status {
+			case EntryFixable:
+				fmt.Printf("  %s %s\n      -> %s (%s)\n", fixColor("~"), entry.Entry, entry.Match, entry.Reason)
+			case EntryBroken:
+				fmt.Printf("  %s %s\n", brokenColor("✗"), entry.Entry)
+			}
+		}
+	}
+}
+
+// RunPlaylistDoctor checks playlists, shows the proposed fixes and rewrites the affected
+// playlists once confirmed. Entries that cannot be resolved are dropped unless KeepBroken is set.
+// Once ctx asks to stop, the remaining playlists are left as they are. The checks are
+// returned with the playlists that were repaired.
+func RunPlaylistDoctor(ctx context.Context, dev *mtp.Device, storagesRaw interface{}, names []string, options PlaylistRepairOptions) ([]PlaylistCheck, error) {
+	checks, err := CheckPlaylists(ctx, dev, storagesRaw, names)
+	if err != nil {
+		return checks, err
+	}
+
+	if len(checks) == 0 {
+		fmt.Println("No playlists found on the device")
+		return checks, nil
+	}
+
+	DisplayPlaylistChecks(checks)
+
+	fixable, broken := 0, 0
+	for i := range checks {
+		fixable += checks[i].Count(EntryFixable)
+		broken += checks[i].Count(EntryBroken)
+	}
+
+	if fixable == 0 && broken == 0 {
+		color.HiGreen("\nAll playlist entries resolve to songs on the device.")
+		return checks, nil
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	dropBroken := broken > 0 && !options.KeepBroken
+
+	if !options.AssumeYes {
+		if broken > 0 && !options.KeepBroken {
+			fmt.Printf("\nDrop %d entries that could not be resolved? (y/n): ", broken)
+			scanner.Scan()
+			dropBroken = strings.ToLower(strings.TrimSpace(scanner.Text())) == "y"
+		}
+
+		if fixable == 0 && !dropBroken {
+			fmt.Println("Nothing to change.")
+			return checks, nil
+		}
+
+		if dropBroken {
+			fmt.Printf("\nRewrite playlists with %d fixed and %d dropped entries? (y/n): ", fixable, broken)
+		} else {
+			fmt.Printf("\nRewrite playlists with %d fixed entries? (y/n): ", fixable)
+		}
+		scanner.Scan()
+		if strings.ToLower(strings.TrimSpace(scanner.Text())) != "y" {
+			fmt.Println("Operation cancelled.")
+			return checks, nil
+		}
+	}
+
+	failed := 0
+	for i := range checks {
+		if checks[i].Error != "" || !checks[i].NeedsRepair(dropBroken) {
+			continue
+		}
+		if stopRequested(ctx) {
+			return checks, fmt.Errorf("repair %w: %s and the playlists after it were not repaired", ErrInterrupted,
+				checks[i].Playlist.Path)
+		}
+
+		if err := RepairPlaylist(ctx, dev, &checks[i], dropBroken); err != nil {
+			util.LogError("Error repairing %s: %v", checks[i].Playlist.Path, err)
+			checks[i].Error = err.Error()
+			failed++
+			continue
+		}
+		checks[i].Repaired = true
+		color.HiGreen("✓ Repaired %s", checks[i].Playlist.Path)
+	}
+
+	if failed > 0 {
+		return checks, fmt.Errorf("%d playlists could not be repaired", failed)
+	}
+	return checks, nil
+}
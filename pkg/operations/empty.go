@@ -0,0 +1,345 @@
+package operations
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/schachte/better-sync/pkg/config"
+	"github.com/schachte/better-sync/pkg/files"
+	"github.com/schachte/better-sync/pkg/model"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+const (
+	BrokenReasonEmpty     = "empty"
+	BrokenReasonTruncated = "truncated"
+)
+
+// audioExtensions are the files checked for failed transfers
+var audioExtensions = map[string]bool{
+	".mp3":  true,
+	".m4a":  true,
+	".aac":  true,
+	".wav":  true,
+	".flac": true,
+	".ogg":  true,
+	".wma":  true,
+}
+
+// BrokenTrack is an audio object left behind by a failed or interrupted transfer
+type BrokenTrack struct {
+	Path      string
+	ObjectID  uint32
+	ParentID  uint32
+	StorageID uint32
+	Size      int64
+	// ExpectedSize is the size of the source file when it was uploaded, 0 if unknown
+	ExpectedSize int64
+	Reason       string
+	// LocalPath is the source file found for a re-upload, empty if none was found
+	LocalPath string
+	// Deleted and Reuploaded are set by RunEmptyCleanup as it repairs the track; Error
+	// is why it could not
+	Deleted    bool
+	Reuploaded bool
+	Error      string
+}
+
+// EmptyCleanupReport lists the broken tracks found on the device
+type EmptyCleanupReport struct {
+	Scanned int
+	Tracks  []BrokenTrack
+}
+
+// EmptyCleanupOptions controls RunEmptyCleanup
+type EmptyCleanupOptions struct {
+	// SearchDirs are searched by file name for the source of tracks that have no
+	// recorded upload; defaults to the configured download directory
+	SearchDirs []string
+	// Reupload uploads the found sources again without asking
+	Reupload  bool
+	AssumeYes bool
+}
+
+// defaultSearchDir is where spotdl downloads are saved by default
+func defaultSearchDir() string {
+	return config.DownloadDir()
+}
+
+// sourceNameKey reduces a file name to what survives an upload: no track number prefix
+// and no case. The extension is kept, so a device SONG.MP3 only matches a local
+// song.mp3 and never a song.m4a that would upload as a different file.
+func sourceNameKey(name string) string {
+	return strings.ToUpper(stripNumericPrefix(filepath.Base(name)))
+}
+
+// localSources indexes the audio files below the search directories by name
+type localSources map[string][]string
+
+func findLocalSources(dirs []string) localSources {
+	sources := make(localSources)
+	for _, dir := range dirs {
+		dir = util.ExpandPath(dir)
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if info.IsDir() || !audioExtensions[strings.ToLower(filepath.Ext(path))] {
+				return nil
+			}
+			key := sourceNameKey(path)
+			sources[key] = append(sources[key], path)
+			return nil
+		})
+		if err != nil {
+			util.LogVerbose("Error searching %s: %v", dir, err)
+		}
+	}
+	return sources
+}
+
+// Find returns the local file with the same name as the device file, preferring one
+// whose size matches the recorded upload
+func (s localSources) Find(devicePath string, expectedSize int64) string {
+	candidates := s[sourceNameKey(devicePath)]
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && info.Size() == expectedSize {
+			return candidate
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates[0]
+	}
+	return ""
+}
+
+// FindBrokenTracks looks for audio objects that are empty or smaller than the source
+// file recorded when they were uploaded, and for the local source of each
+func FindBrokenTracks(dev *mtp.Device, storagesRaw interface{}, searchDirs []string) (*EmptyCleanupReport, error) {
+	storagesValue := reflect.ValueOf(storagesRaw)
+	if storagesValue.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("invalid storages data format: not a slice")
+	}
+
+	history, err := files.LoadUploadHistory()
+	if err != nil {
+		util.LogError("Error loading upload history: %v", err)
+		history = map[string]model.UploadRecord{}
+	}
+
+	if len(searchDirs) == 0 {
+		searchDirs = []string{defaultSearchDir()}
+	}
+	sources := findLocalSources(searchDirs)
+
+	report := &EmptyCleanupReport{}
+	indexes := newDeviceIndexes(dev, "/Music")
+	for i := 0; i < storagesValue.Len(); i++ {
+		storageID := extractUint32Field(storagesValue.Index(i).Interface(), "Sid")
+
+		index, err := indexes.Get(storageID)
+		if err != nil {
+			util.LogVerbose("Skipping storage %d: %v", storageID, err)
+			continue
+		}
+
+		for _, object := range index.Objects {
+			if object.IsDir || !audioExtensions[strings.ToLower(filepath.Ext(object.Path))] {
+				continue
+			}
+			report.Scanned++
+
+			record, recorded := history[files.UploadHistoryKey(object.Path)]
+			track := BrokenTrack{
+				Path:      object.Path,
+				ObjectID:  object.ObjectID,
+				ParentID:  object.ParentID,
+				StorageID: storageID,
+				Size:      object.Size,
+			}
+			if recorded {
+				track.ExpectedSize = record.Size
+			}
+
+			switch {
+			case object.Size == 0:
+				track.Reason = BrokenReasonEmpty
+			case recorded && object.Size < record.Size:
+				track.Reason = BrokenReasonTruncated
+			default:
+				continue
+			}
+
+			if recorded {
+				if _, err := os.Stat(record.LocalPath); err == nil {
+					track.LocalPath = record.LocalPath
+				}
+			}
+			if track.LocalPath == "" {
+				track.LocalPath = sources.Find(object.Path, track.ExpectedSize)
+			}
+
+			report.Tracks = append(report.Tracks, track)
+		}
+	}
+
+	util.LogInfo("Found %d broken tracks of %d", len(report.Tracks), report.Scanned)
+	return report, nil
+}
+
+func DisplayBrokenTracks(report *EmptyCleanupReport) {
+	pathColor := color.New(color.Faint).SprintFunc()
+	warnColor := color.New(color.FgHiYellow).SprintFunc()
+
+	fmt.Println("\n==== Empty And Truncated Tracks ====")
+	fmt.Printf("%d audio files checked\n", report.Scanned)
+
+	if len(report.Tracks) == 0 {
+		color.HiGreen("\nNo empty or truncated tracks found.")
+		return
+	}
+
+	fmt.Println()
+	for i, track := range report.Tracks {
+		size := util.FormatSize(track.Size)
+		if track.ExpectedSize > 0 {
+			size = fmt.Sprintf("%s of %s", size, util.FormatSize(track.ExpectedSize))
+		}
+		fmt.Printf("%3d. %-50s %-10s %s\n", i+1, track.Path, track.Reason, size)
+		if track.LocalPath != "" {
+			fmt.Printf("     source: %s\n", pathColor(track.LocalPath))
+		} else {
+			fmt.Printf("     %s\n", warnColor("no local source found"))
+		}
+	}
+}
+
+// reuploadTrack uploads the source of a deleted broken track to the same path
+func reuploadTrack(ctx context.Context, dev *mtp.Device, track BrokenTrack) error {
+	info, err := os.Stat(track.LocalPath)
+	if err != nil {
+		return fmt.Errorf("error accessing %s: %w", track.LocalPath, err)
+	}
+	if limit := config.MaxFileSize(); info.Size() > limit {
+		return fmt.Errorf("%s is too large. Files up to %s are uploaded (max_file_size)", track.LocalPath,
+			util.FormatSize(limit))
+	}
+
+	_, err = sendLocalFile(ctx, dev, track.StorageID, track.ParentID, track.Path, track.LocalPath)
+	return err
+}
+
+// RunEmptyCleanup deletes empty and truncated tracks after one confirmation, then
+// re-uploads the ones whose source was found. Once ctx asks to stop, the remaining
+// tracks are neither deleted nor re-uploaded and the error wraps ErrInterrupted. The
+// report marks what happened to each track.
+func RunEmptyCleanup(ctx context.Context, dev *mtp.Device, storagesRaw interface{}, options EmptyCleanupOptions) (*EmptyCleanupReport, error) {
+	report, err := FindBrokenTracks(dev, storagesRaw, options.SearchDirs)
+	if err != nil {
+		return nil, err
+	}
+
+	DisplayBrokenTracks(report)
+	if len(report.Tracks) == 0 {
+		return report, nil
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	if !options.AssumeYes {
+		fmt.Printf("\nDelete %d broken tracks? (y/n): ", len(report.Tracks))
+		scanner.Scan()
+		if strings.ToLower(strings.TrimSpace(scanner.Text())) != "y" {
+			fmt.Println("Operation cancelled.")
+			return report, nil
+		}
+	}
+
+	deleted := 0
+	var failed []string
+	interrupted := false
+	for i := range report.Tracks {
+		track := &report.Tracks[i]
+		if stopRequested(ctx) {
+			interrupted = true
+			break
+		}
+		fmt.Printf("[%d/%d] Deleting %s\n", i+1, len(report.Tracks), track.Path)
+		if err := deleteDeviceObject(dev, track.StorageID, track.ObjectID); err != nil {
+			util.LogError("Error deleting %s: %v", track.Path, err)
+			track.Error = err.Error()
+			failed = append(failed, track.Path)
+			continue
+		}
+		track.Deleted = true
+		deleted++
+	}
+	color.HiGreen("\n✓ Deleted %d broken tracks", deleted)
+
+	var reuploadable []*BrokenTrack
+	for i := range report.Tracks {
+		if track := &report.Tracks[i]; track.Deleted && track.LocalPath != "" {
+			reuploadable = append(reuploadable, track)
+		}
+	}
+
+	if len(reuploadable) > 0 && !options.Reupload && !options.AssumeYes && !interrupted {
+		fmt.Printf("\nRe-upload %d tracks from their local source? (y/n): ", len(reuploadable))
+		scanner.Scan()
+		options.Reupload = strings.ToLower(strings.TrimSpace(scanner.Text())) == "y"
+	}
+
+	if options.Reupload {
+		uploaded := 0
+		for i, track := range reuploadable {
+			if stopRequested(ctx) {
+				interrupted = true
+				break
+			}
+			fmt.Printf("[%d/%d] Uploading %s\n", i+1, len(reuploadable), track.LocalPath)
+			if err := reuploadTrack(ctx, dev, *track); err != nil {
+				util.LogError("Error re-uploading %s: %v", track.Path, err)
+				track.Error = err.Error()
+				failed = append(failed, track.Path)
+				continue
+			}
+			track.Reuploaded = true
+			uploaded++
+		}
+		color.HiGreen("✓ Re-uploaded %d of %d tracks", uploaded, len(reuploadable))
+	}
+
+	pruneAfterDelete(ctx, dev, storagesRaw)
+
+	if len(failed) > 0 {
+		return report, fmt.Errorf("%d tracks could not be repaired", len(failed))
+	}
+	if interrupted {
+		return report, fmt.Errorf("cleanup %w", ErrInterrupted)
+	}
+	return report, nil
+}
+
+// CleanupEmptyTracks is the interactive menu entry for RunEmptyCleanup
+func CleanupEmptyTracks(dev *mtp.Device, storagesRaw interface{}) {
+	fmt.Println("\n=== Clean Up Empty Tracks ===")
+
+	scanner := bufio.NewScanner(os.Stdin)
+	fmt.Printf("Folder with the original files [default: %s]: ", defaultSearchDir())
+	scanner.Scan()
+
+	options := EmptyCleanupOptions{}
+	if dir := strings.Trim(strings.TrimSpace(scanner.Text()), "\"'"); dir != "" {
+		options.SearchDirs = []string{dir}
+	}
+
+	if _, err := RunEmptyCleanup(context.Background(), dev, storagesRaw, options); err != nil {
+		util.LogError("Error cleaning up empty tracks: %v", err)
+	}
+}
@@ -0,0 +1,336 @@
+package operations
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/schachte/better-sync/pkg/files"
+	"github.com/schachte/better-sync/pkg/model"
+	"github.com/schachte/better-sync/pkg/util"
+	"github.com/schollz/progressbar/v3"
+)
+
+// ExportOptions selects what is copied from the device
+type ExportOptions struct {
+	// Destination is the local directory that receives the files
+	Destination string
+	// Playlists limits the export to these playlists and their tracks;
+	// when empty the whole Music tree is exported
+	Playlists []string
+}
+
+// ExportResult summarises an export
+type ExportResult struct {
+	Downloaded int
+	Skipped    int
+	Bytes      int64
+	Playlists  []string
+	Missing    []string
+	Errors     []string
+	// Interrupted is set when the export stopped before every file was copied
+	Interrupted bool
+
+	exported map[uint32]string
+}
+
+func (r *ExportResult) AddError(msg string) {
+	r.Errors = append(r.Errors, msg)
+	util.LogVerbose("Error: %s", msg)
+}
+
+// localPathForObject mirrors a device path below the destination directory
+func localPathForObject(destination, devicePath string) string {
+	return filepath.Join(destination, filepath.FromSlash(strings.TrimPrefix(normalizePath(devicePath), "/")))
+}
+
+// downloadObject copies an object to localPath, showing a progress bar. The data is
+// written to a temporary file first so an interrupted transfer leaves no partial file.
+func downloadObject(ctx context.Context, dev *mtp.Device, objectID uint32, size int64, localPath string) error {
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return fmt.Errorf("error creating directory: %w", err)
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(localPath), ".download-*")
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	out := consoleOf(ctx)
+	bar := progressbar.NewOptions64(
+		size,
+		progressbar.OptionSetWriter(out),
+		progressbar.OptionSetDescription(fmt.Sprintf("Downloading %s", filepath.Base(localPath))),
+		progressbar.OptionSetWidth(30),
+		progressbar.OptionShowBytes(true),
+		progressbar.OptionShowCount(),
+		progressbar.OptionOnCompletion(func() {
+			fmt.Fprint(out, "\n")
+		}),
+	)
+
+	writer := bufio.NewWriter(tempFile)
+	err = dev.GetObject(objectID, contextWriter{ctx: ctx, writer: writer}, func(received int64) error {
+		return bar.Set64(received)
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil && ctx.Err() != nil {
+		fmt.Fprintln(out)
+		return fmt.Errorf("download of %s aborted: %w", filepath.Base(localPath), ctx.Err())
+	}
+	if err != nil {
+		return fmt.Errorf("error downloading object %d: %w", objectID, err)
+	}
+	bar.Finish()
+
+	if err := os.Rename(tempFile.Name(), localPath); err != nil {
+		return fmt.Errorf("error saving %s: %w", localPath, err)
+	}
+	return nil
+}
+
+// exportObject downloads an object unless an identical copy is already present. Once
+// ctx asks to stop, nothing more is downloaded and the result is marked interrupted.
+func exportObject(ctx context.Context, dev *mtp.Device, object *deviceObject, destination string, result *ExportResult) (string, bool) {
+	if localPath, ok := result.exported[object.ObjectID]; ok {
+		return localPath, localPath != ""
+	}
+	if stopRequested(ctx) {
+		result.Interrupted = true
+		return "", false
+	}
+	if result.exported == nil {
+		result.exported = make(map[uint32]string)
+	}
+
+	localPath := localPathForObject(destination, object.Path)
+	result.exported[object.ObjectID] = localPath
+
+	if info, err := os.Stat(localPath); err == nil && info.Size() == object.Size {
+		util.LogVerbose("Skipping %s, already exported", object.Path)
+		result.Skipped++
+		return localPath, true
+	}
+
+	if err := downloadObject(ctx, dev, object.ObjectID, object.Size, localPath); err != nil {
+		if ctx.Err() != nil {
+			result.Interrupted = true
+			delete(result.exported, object.ObjectID)
+			return "", false
+		}
+		result.AddError(fmt.Sprintf("Failed to export %s: %v", object.Path, err))
+		result.exported[object.ObjectID] = ""
+		return "", false
+	}
+
+	result.Downloaded++
+	result.Bytes += object.Size
+	return localPath, true
+}
+
+// writeLocalPlaylist writes an M3U8 file whose entries are relative to the playlist
+func writeLocalPlaylist(playlistPath string, trackPaths []string) error {
+	var content strings.Builder
+	content.WriteString("#EXTM3U\n")
+
+	for _, trackPath := range trackPaths {
+		relativePath, err := filepath.Rel(filepath.Dir(playlistPath), trackPath)
+		if err != nil {
+			relativePath = trackPath
+		}
+
+		seconds := -1
+		if info, err := files.ReadMP3Info(trackPath); err == nil {
+			seconds = info.Seconds()
+		}
+
+		content.WriteString(fmt.Sprintf("#EXTINF:%d,%s\n", seconds, util.ExtractTrackInfo(filepath.ToSlash(trackPath))))
+		content.WriteString(filepath.ToSlash(relativePath))
+		content.WriteString("\n")
+	}
+
+	if err := os.WriteFile(playlistPath, []byte(content.String()), 0644); err != nil {
+		return fmt.Errorf("error writing playlist %s: %w", playlistPath, err)
+	}
+	return nil
+}
+
+// exportPlaylist downloads the tracks of a playlist and writes a local copy of it; the
+// copy is not written when the export is interrupted
+func exportPlaylist(ctx context.Context, dev *mtp.Device, playlist model.PlaylistInfo, index *deviceIndex, destination string, result *ExportResult) {
+	data, err := readObjectData(dev, playlist.ObjectID)
+	if err != nil {
+		result.AddError(fmt.Sprintf("Failed to read playlist %s: %v", playlist.Path, err))
+		return
+	}
+
+	var trackPaths []string
+	for _, entry := range ParsePlaylistContent(string(data)) {
+		object, ok := index.Lookup(entry)
+		if !ok {
+			result.Missing = append(result.Missing, fmt.Sprintf("%s: %s", playlist.Name, entry))
+			continue
+		}
+
+		if localPath, ok := exportObject(ctx, dev, object, destination, result); ok {
+			trackPaths = append(trackPaths, localPath)
+		}
+		if result.Interrupted {
+			return
+		}
+	}
+
+	fileName := strings.TrimSuffix(playlist.Name, filepath.Ext(playlist.Name)) + ".m3u8"
+	playlistPath := filepath.Join(destination, fileName)
+	if err := writeLocalPlaylist(playlistPath, trackPaths); err != nil {
+		result.AddError(err.Error())
+		return
+	}
+
+	result.Playlists = append(result.Playlists, playlistPath)
+}
+
+// ExportFromDevice copies playlists and their tracks, or the whole Music tree, into a
+// local directory. Tracks keep their device folder layout and playlists are written as
+// M3U8 files with relative paths. Cancelling ctx aborts the file in progress; with
+// WithGracefulStop it finishes first. Either way the error wraps ErrInterrupted.
+func ExportFromDevice(ctx context.Context, dev *mtp.Device, storagesRaw interface{}, options ExportOptions) (*ExportResult, error) {
+	if options.Destination == "" {
+		return nil, fmt.Errorf("no destination directory given")
+	}
+	if err := os.MkdirAll(options.Destination, 0755); err != nil {
+		return nil, fmt.Errorf("error creating destination: %w", err)
+	}
+
+	storageID, _, err := SelectStorageAndMusicFolder(ctx, dev, storagesRaw)
+	if err != nil {
+		return nil, fmt.Errorf("error selecting storage: %w", err)
+	}
+
+	allPlaylists, err := GetPlaylists(dev, storagesRaw)
+	if err != nil {
+		return nil, fmt.Errorf("error getting playlists: %w", err)
+	}
+
+	var playlists []model.PlaylistInfo
+	if len(options.Playlists) > 0 {
+		for _, name := range options.Playlists {
+			playlist := findPlaylist(allPlaylists, name)
+			if playlist == nil {
+				return nil, fmt.Errorf("playlist '%s' not found", name)
+			}
+			playlists = append(playlists, *playlist)
+		}
+	} else {
+		for _, playlist := range allPlaylists {
+			if playlist.StorageID == storageID {
+				playlists = append(playlists, playlist)
+			}
+		}
+	}
+
+	indexes := newDeviceIndexes(dev, "/Music")
+	result := &ExportResult{}
+
+	if len(options.Playlists) == 0 {
+		index, err := indexes.Get(storageID)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range index.Objects {
+			object := &index.Objects[i]
+			if object.IsDir || hasPlaylistExtension(object.Path) {
+				continue
+			}
+			exportObject(ctx, dev, object, options.Destination, result)
+			if result.Interrupted {
+				break
+			}
+		}
+	}
+
+	for _, playlist := range playlists {
+		if result.Interrupted {
+			break
+		}
+		index, err := indexes.Get(playlist.StorageID)
+		if err != nil {
+			result.AddError(fmt.Sprintf("Failed to read storage of %s: %v", playlist.Path, err))
+			continue
+		}
+		exportPlaylist(ctx, dev, playlist, index, options.Destination, result)
+	}
+	if result.Interrupted {
+		return result, fmt.Errorf("export %w after %d files", ErrInterrupted, result.Downloaded)
+	}
+
+	util.LogInfo("Exported %d files (%d already present) and %d playlists to %s",
+		result.Downloaded, result.Skipped, len(result.Playlists), options.Destination)
+	return result, nil
+}
+
+func DisplayExportResult(result *ExportResult) {
+	successColor := color.New(color.FgHiGreen).SprintFunc()
+	warnColor := color.New(color.FgHiYellow).SprintFunc()
+	errorColor := color.New(color.FgHiRed).SprintFunc()
+
+	fmt.Println("\n==== Export Summary ====")
+	fmt.Printf("%s (%.1f MB), %d already present\n",
+		successColor(fmt.Sprintf("%d files downloaded", result.Downloaded)),
+		float64(result.Bytes)/1024/1024, result.Skipped)
+
+	for _, playlist := range result.Playlists {
+		fmt.Printf("  %s %s\n", successColor("✓"), playlist)
+	}
+	for _, missing := range result.Missing {
+		fmt.Printf("  %s %s (not on device)\n", warnColor("?"), missing)
+	}
+	for _, msg := range result.Errors {
+		fmt.Printf("  %s %s\n", errorColor("✗"), msg)
+	}
+	if result.Interrupted {
+		fmt.Println(warnColor("Stopped before every file was copied (interrupted)"))
+	}
+}
+
+func ExportMusic(dev *mtp.Device, storagesRaw interface{}) {
+	fmt.Println("\n=== Export to Computer ===")
+
+	scanner := bufio.NewScanner(os.Stdin)
+	fmt.Print("Enter destination directory: ")
+	scanner.Scan()
+	destination := strings.Trim(strings.TrimSpace(scanner.Text()), "\"'")
+	if destination == "" {
+		fmt.Println("No destination provided")
+		return
+	}
+
+	fmt.Print("Playlists to export (comma separated, empty for the whole Music folder): ")
+	scanner.Scan()
+	var names []string
+	for _, name := range strings.Split(scanner.Text(), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	result, err := ExportFromDevice(context.Background(), dev, storagesRaw, ExportOptions{Destination: destination, Playlists: names})
+	if err != nil {
+		util.LogError("Error exporting: %v", err)
+		return
+	}
+
+	DisplayExportResult(result)
+}
@@ -0,0 +1,464 @@
+package operations
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/schachte/better-sync/pkg/files"
+	"github.com/schachte/better-sync/pkg/model"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+const (
+	ImportStatusUploaded   = "uploaded"
+	ImportStatusExisting   = "already on device"
+	ImportStatusUnresolved = "unresolved"
+	ImportStatusFailed     = "upload failed"
+)
+
+// ImportedEntry is the outcome for one entry of an imported playlist
+type ImportedEntry struct {
+	Entry      string
+	Title      string
+	Seconds    int
+	LocalPath  string
+	DevicePath string
+	Status     string
+	Reason     string
+
+	tags *trackTags
+}
+
+// PlaylistImportResult describes an imported playlist and what happened to each entry
+type PlaylistImportResult struct {
+	Name     string
+	Playlist *model.Playlist
+	Entries  []ImportedEntry
+	Upload   *UploadResult
+}
+
+func (r *PlaylistImportResult) Count(status string) int {
+	count := 0
+	for _, entry := range r.Entries {
+		if entry.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// ImportOptions controls how a local playlist is written to the device
+type ImportOptions struct {
+	// Name overrides the playlist name; by default the local file name is used
+	Name string
+	// Replace overwrites a device playlist with the same name
+	Replace bool
+	// Format is the playlist format written to the device, m3u8 or pls
+	Format string
+}
+
+// findUploadedCopy looks for a file previously uploaded from filePath: same artist and
+// album folder, same name after the track number and the same size
+func findUploadedCopy(index *deviceIndex, filePath string, tags *trackTags) (*deviceObject, bool) {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return nil, false
+	}
+
+	artist, album, fileName := deviceLocationForFile(filePath, tags)
+	folderKey := pathKey(fmt.Sprintf("/MUSIC/%s/%s", artist, album))
+
+	for i, object := range index.Objects {
+		if object.IsDir || pathKey(filepath.Dir(object.Path)) != folderKey {
+			continue
+		}
+
+		name := strings.ToUpper(filepath.Base(object.Path))
+		if len(name) > 3 && isNumeric(name[:2]) && name[2] == ' ' && name[3:] == fileName &&
+			object.Size == fileInfo.Size() {
+			return &index.Objects[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// ImportTracks uploads the local files that are not on the device yet and writes a
+// playlist that lists every resolved file in the given order. With an empty playlist
+// name the files are only uploaded. Once ctx asks to stop no further tracks are uploaded,
+// the playlist is not written and the error wraps ErrInterrupted.
+func ImportTracks(ctx context.Context, dev *mtp.Device, storagesRaw interface{}, playlistName string, entries []ImportedEntry, options ImportOptions) (*PlaylistImportResult, error) {
+	fileName := ""
+	if playlistName != "" {
+		fileName = PlaylistFileNameForFormat(playlistName, options.Format)
+	}
+	result := &PlaylistImportResult{
+		Name:    fileName,
+		Entries: entries,
+		Upload: &UploadResult{
+			UploadedFiles: make([]model.MP3File, 0),
+			Errors:        make([]string, 0),
+		},
+	}
+
+	storageID, musicFolderID, err := SelectStorageAndMusicFolder(ctx, dev, storagesRaw)
+	if err != nil {
+		return nil, fmt.Errorf("error selecting storage: %w", err)
+	}
+
+	var existing *model.PlaylistInfo
+	if fileName != "" {
+		playlists, err := GetPlaylists(dev, storagesRaw)
+		if err != nil {
+			return nil, fmt.Errorf("error getting playlists: %w", err)
+		}
+
+		existing = findPlaylistInStorage(playlists, storageID, fileName)
+		if existing != nil && !options.Replace {
+			return nil, fmt.Errorf("a playlist named '%s' already exists at %s", existing.Name, existing.Path)
+		}
+	}
+
+	index, err := buildDeviceIndex(dev, storageID, "/Music")
+	if err != nil {
+		return nil, err
+	}
+
+	var toUpload []string
+	tags := make(map[string]*trackTags)
+	queued := make(map[string]bool)
+	for i := range result.Entries {
+		entry := &result.Entries[i]
+		if entry.Status == ImportStatusUnresolved || queued[entry.LocalPath] {
+			continue
+		}
+
+		if object, ok := findUploadedCopy(index, entry.LocalPath, entry.tags); ok {
+			entry.Status = ImportStatusExisting
+			entry.DevicePath = "0:" + strings.ToUpper(normalizePath(object.Path))
+			continue
+		}
+		toUpload = append(toUpload, entry.LocalPath)
+		tags[entry.LocalPath] = entry.tags
+		queued[entry.LocalPath] = true
+	}
+
+	if len(toUpload) > 0 {
+		fmt.Fprintf(consoleOf(ctx), "Uploading %d tracks that are not on the device yet\n", len(toUpload))
+		uploadLocalFiles(ctx, dev, storageID, musicFolderID, toUpload, tags, result.Upload)
+	}
+
+	uploaded := make(map[string]string)
+	for _, file := range result.Upload.UploadedFiles {
+		uploaded[file.LocalPath] = file.Path
+	}
+
+	pathStyle := PlaylistPathStyle(dev)
+	var songPaths []string
+	var playlistEntries []files.PlaylistEntry
+	for i := range result.Entries {
+		entry := &result.Entries[i]
+		if entry.Status == "" {
+			if devicePath, ok := uploaded[entry.LocalPath]; ok {
+				entry.Status = ImportStatusUploaded
+				entry.DevicePath = devicePath
+			} else {
+				entry.Status = ImportStatusFailed
+			}
+		}
+
+		if entry.DevicePath == "" {
+			continue
+		}
+
+		if entry.Seconds < 0 {
+			if info, err := files.ReadMP3Info(entry.LocalPath); err == nil {
+				entry.Seconds = info.Seconds()
+			}
+		}
+
+		title := entry.Title
+		if title == "" {
+			title = strings.ToUpper(util.ExtractTrackInfo(entry.DevicePath))
+		}
+		songPaths = append(songPaths, entry.DevicePath)
+		playlistEntries = append(playlistEntries, files.PlaylistEntry{
+			Location: util.FormatPlaylistPath(entry.DevicePath, pathStyle),
+			Title:    title,
+			Seconds:  entry.Seconds,
+		})
+	}
+
+	if result.Upload.Interrupted {
+		return result, fmt.Errorf("import %w before every track was uploaded", ErrInterrupted)
+	}
+	if len(songPaths) == 0 {
+		return result, fmt.Errorf("none of the playlist entries could be resolved or uploaded")
+	}
+
+	if fileName == "" {
+		result.Upload.Success = result.Count(ImportStatusFailed) == 0
+		return result, nil
+	}
+
+	if existing != nil {
+		// replacePlaylistData puts the old content back if the new one cannot be written
+		data := []byte(files.FormatPlaylist(files.PlaylistFormatForName(fileName), playlistEntries))
+		objectID, err := replacePlaylistData(dev, *existing, data)
+		if err != nil {
+			return result, fmt.Errorf("could not replace playlist %s: %w", existing.Path, err)
+		}
+
+		result.Playlist = &model.Playlist{
+			Path:       existing.Name,
+			ObjectID:   objectID,
+			StorageID:  existing.StorageID,
+			SongPaths:  songPaths,
+			Validation: ValidatePlaylistUpload(dev, existing.StorageID, objectID, existing.Name, data),
+		}
+		if !result.Playlist.Validation.OK() {
+			DisplayPlaylistValidation(consoleOf(ctx), result.Playlist.Validation)
+		}
+		result.Upload.Playlist = result.Playlist
+		result.Upload.Success = true
+		return result, nil
+	}
+
+	playlist, err := createPlaylistWithEntries(ctx, dev, storageID, musicFolderID, fileName, songPaths, playlistEntries)
+	if err != nil {
+		return result, fmt.Errorf("playlist creation failed: %w", err)
+	}
+	result.Playlist = &playlist
+	result.Upload.Playlist = &playlist
+	result.Upload.Success = true
+
+	return result, nil
+}
+
+// ImportLocalPlaylist reads an M3U, M3U8, PLS or XSPF playlist from disk, resolving
+// each entry relative to the playlist file, and recreates it on the device
+func ImportLocalPlaylist(ctx context.Context, dev *mtp.Device, storagesRaw interface{}, playlistPath string, options ImportOptions) (*PlaylistImportResult, error) {
+	var title string
+	var localEntries []files.PlaylistEntry
+	var err error
+
+	if strings.EqualFold(filepath.Ext(playlistPath), ".xspf") {
+		title, localEntries, err = files.ReadXSPFFile(playlistPath)
+	} else {
+		localEntries, err = files.ReadPlaylistFile(playlistPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(localEntries) == 0 {
+		return nil, fmt.Errorf("playlist %s has no entries", playlistPath)
+	}
+
+	name := options.Name
+	if name == "" {
+		name = title
+	}
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(playlistPath), filepath.Ext(playlistPath))
+	}
+
+	return ImportTracks(ctx, dev, storagesRaw, name, resolveLocalEntries(playlistPath, localEntries), options)
+}
+
+// ImportLibraryPlaylists imports the named playlists of an iTunes/Music.app library
+// export, each into its own device playlist. Once ctx asks to stop the remaining
+// playlists are skipped and the error wraps ErrInterrupted.
+func ImportLibraryPlaylists(ctx context.Context, dev *mtp.Device, storagesRaw interface{}, libraryPath string, playlists []files.LibraryPlaylist, options ImportOptions) ([]*PlaylistImportResult, error) {
+	var results []*PlaylistImportResult
+	failed := 0
+
+	for i, playlist := range playlists {
+		if stopRequested(ctx) {
+			return results, fmt.Errorf("import %w: %d of %d playlists were not imported", ErrInterrupted, len(playlists)-i, len(playlists))
+		}
+		fmt.Fprintf(consoleOf(ctx), "\n=== Importing %s (%d tracks) ===\n", playlist.Name, len(playlist.Entries))
+
+		result, err := ImportTracks(ctx, dev, storagesRaw, playlist.Name, resolveLocalEntries(libraryPath, playlist.Entries), options)
+		if result != nil {
+			results = append(results, result)
+		}
+		if err != nil {
+			util.LogError("Error importing %s: %v", playlist.Name, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return results, fmt.Errorf("%d of %d playlists could not be imported", failed, len(playlists))
+	}
+	return results, nil
+}
+
+// SelectLibraryPlaylists picks playlists by name; with no names it lists them and asks
+// which ones to import
+func SelectLibraryPlaylists(playlists []files.LibraryPlaylist, names []string, scanner *bufio.Scanner) ([]files.LibraryPlaylist, error) {
+	if len(playlists) == 0 {
+		return nil, fmt.Errorf("no playlists found in the library")
+	}
+
+	if len(names) > 0 {
+		var selected []files.LibraryPlaylist
+		for _, name := range names {
+			found := false
+			for _, playlist := range playlists {
+				if strings.EqualFold(playlist.Name, name) {
+					selected = append(selected, playlist)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("playlist '%s' not found in the library", name)
+			}
+		}
+		return selected, nil
+	}
+
+	fmt.Println("\n==== Library Playlists ====")
+	for i, playlist := range playlists {
+		fmt.Printf("%d. %s (%d tracks)\n", i+1, playlist.Name, len(playlist.Entries))
+	}
+
+	fmt.Print("\nEnter playlist numbers to import (comma separated, e.g. 1,3,5): ")
+	scanner.Scan()
+
+	var selected []files.LibraryPlaylist
+	for _, indexStr := range strings.Split(scanner.Text(), ",") {
+		var index int
+		if _, err := fmt.Sscanf(strings.TrimSpace(indexStr), "%d", &index); err != nil {
+			continue
+		}
+		if index < 1 || index > len(playlists) {
+			continue
+		}
+		selected = append(selected, playlists[index-1])
+	}
+
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no playlists selected")
+	}
+	return selected, nil
+}
+
+// resolveLocalEntries maps playlist locations to local MP3 files, marking the ones
+// that do not exist or cannot be uploaded as unresolved
+func resolveLocalEntries(playlistPath string, localEntries []files.PlaylistEntry) []ImportedEntry {
+	var entries []ImportedEntry
+	for _, localEntry := range localEntries {
+		entry := ImportedEntry{
+			Entry:     localEntry.Location,
+			Title:     localEntry.Title,
+			Seconds:   localEntry.Seconds,
+			LocalPath: files.ResolveLocalPath(playlistPath, localEntry.Location),
+		}
+
+		checkLocalFile(&entry)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// checkLocalFile marks an entry unresolved when its local file does not exist or
+// cannot be uploaded
+func checkLocalFile(entry *ImportedEntry) {
+	if info, err := os.Stat(entry.LocalPath); err != nil {
+		entry.Status = ImportStatusUnresolved
+		entry.Reason = "file not found"
+	} else if info.IsDir() {
+		entry.Status = ImportStatusUnresolved
+		entry.Reason = "is a directory"
+	} else if !strings.EqualFold(filepath.Ext(entry.LocalPath), ".mp3") {
+		entry.Status = ImportStatusUnresolved
+		entry.Reason = "only MP3 files are supported"
+	}
+}
+
+func ImportPlaylist(dev *mtp.Device, storagesRaw interface{}) {
+	fmt.Println("\n=== Import Local Playlist ===")
+
+	fmt.Print("Enter path to playlist (M3U/M3U8/PLS/XSPF) or iTunes Library.xml: ")
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Scan()
+	playlistPath := strings.Trim(strings.TrimSpace(scanner.Text()), "\"'")
+	if playlistPath == "" {
+		fmt.Println("No playlist path provided")
+		return
+	}
+
+	if strings.EqualFold(filepath.Ext(playlistPath), ".xml") {
+		libraryPlaylists, err := files.ReadITunesLibrary(playlistPath)
+		if err != nil {
+			util.LogError("Error reading library: %v", err)
+			return
+		}
+
+		selected, err := SelectLibraryPlaylists(libraryPlaylists, nil, scanner)
+		if err != nil {
+			util.LogError("%v", err)
+			return
+		}
+
+		results, err := ImportLibraryPlaylists(context.Background(), dev, storagesRaw, playlistPath, selected, ImportOptions{})
+		for _, result := range results {
+			DisplayImportResult(result)
+		}
+		if err != nil {
+			util.LogError("Error importing playlists: %v", err)
+		}
+		return
+	}
+
+	result, err := ImportLocalPlaylist(context.Background(), dev, storagesRaw, playlistPath, ImportOptions{})
+	if result != nil {
+		DisplayImportResult(result)
+	}
+	if err != nil {
+		util.LogError("Error importing playlist: %v", err)
+	}
+}
+
+func DisplayImportResult(result *PlaylistImportResult) {
+	successColor := color.New(color.FgHiGreen).SprintFunc()
+	warnColor := color.New(color.FgHiYellow).SprintFunc()
+	errorColor := color.New(color.FgHiRed).SprintFunc()
+
+	if result.Name != "" {
+		fmt.Printf("\n==== Imported %s ====\n", result.Name)
+	} else {
+		fmt.Println("\n==== Uploaded Tracks ====")
+	}
+	fmt.Printf("%s, %s, %s, %s\n",
+		successColor(fmt.Sprintf("%d uploaded", result.Count(ImportStatusUploaded))),
+		successColor(fmt.Sprintf("%d already on device", result.Count(ImportStatusExisting))),
+		errorColor(fmt.Sprintf("%d failed", result.Count(ImportStatusFailed))),
+		warnColor(fmt.Sprintf("%d unresolved", result.Count(ImportStatusUnresolved))))
+
+	for _, entry := range result.Entries {
+		switch entry.Status {
+		case ImportStatusUnresolved:
+			fmt.Printf("  %s %s (%s)\n", warnColor("?"), entry.Entry, entry.Reason)
+		case ImportStatusFailed:
+			fmt.Printf("  %s %s\n", errorColor("✗"), entry.LocalPath)
+		}
+	}
+
+	for _, msg := range result.Upload.Errors {
+		fmt.Printf("  %s %s\n", errorColor("•"), msg)
+	}
+
+	if result.Playlist != nil {
+		fmt.Printf("\nPlaylist written to /Music/%s\n", result.Playlist.Path)
+	}
+}
@@ -0,0 +1,155 @@
+package operations
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/ganeshrvel/go-mtpx"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+// deviceObject is a file or folder found while walking a storage
+type deviceObject struct {
+	ObjectID uint32
+	ParentID uint32
+	Path     string
+	Size     int64
+	ModTime  time.Time
+	IsDir    bool
+}
+
+// deviceIndex holds every object below a folder so that many paths can be
+// resolved after walking the device once
+type deviceIndex struct {
+	StorageID uint32
+	Root      string
+	Objects   []deviceObject
+	byPath    map[string]int
+}
+
+// pathKey normalises a device or playlist path for lookups: no storage prefix, no case
+func pathKey(path string) string {
+	path = strings.ReplaceAll(strings.TrimSpace(path), "\\", "/")
+	return strings.TrimSuffix(strings.ToUpper(normalizePath(path)), "/")
+}
+
+func buildDeviceIndex(dev *mtp.Device, storageID uint32, root string) (*deviceIndex, error) {
+	index := &deviceIndex{
+		StorageID: storageID,
+		Root:      root,
+		byPath:    make(map[string]int),
+	}
+
+	_, _, _, err := mtpx.Walk(dev, storageID, root, true, true, false,
+		func(objectID uint32, fi *mtpx.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+
+			index.byPath[pathKey(fi.FullPath)] = len(index.Objects)
+			index.Objects = append(index.Objects, deviceObject{
+				ObjectID: objectID,
+				ParentID: fi.ParentId,
+				Path:     fi.FullPath,
+				Size:     fi.Size,
+				ModTime:  fi.ModTime,
+				IsDir:    fi.IsDir,
+			})
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("error walking %s: %w", root, err)
+	}
+
+	util.LogVerbose("Indexed %d objects below %s in storage %d", len(index.Objects), root, storageID)
+	return index, nil
+}
+
+func (idx *deviceIndex) Lookup(path string) (*deviceObject, bool) {
+	i, ok := idx.byPath[pathKey(path)]
+	if !ok {
+		return nil, false
+	}
+	return &idx.Objects[i], true
+}
+
+// Songs returns the MP3 files in the index
+func (idx *deviceIndex) Songs() []deviceObject {
+	var songs []deviceObject
+	for _, object := range idx.Objects {
+		if !object.IsDir && strings.EqualFold(filepath.Ext(object.Path), ".mp3") {
+			songs = append(songs, object)
+		}
+	}
+	return songs
+}
+
+// FindSimilar looks for the song a stale path most likely refers to, using the same
+// rules as FindSongByMixedCaseAndRelativePath. Stronger matches win; among equal
+// matches a song in the same folder is preferred.
+func (idx *deviceIndex) FindSimilar(path string) (*deviceObject, string, bool) {
+	matcher := newSongMatcher(path)
+	folderKey := pathKey(filepath.Dir(path))
+
+	best := -1
+	bestRank := 0
+	candidates := 0
+
+	for i, object := range idx.Objects {
+		if object.IsDir || !strings.EqualFold(filepath.Ext(object.Path), ".mp3") {
+			continue
+		}
+
+		rank, _ := matcher.match(object.Path)
+		if rank == 0 {
+			continue
+		}
+
+		switch {
+		case best == -1 || rank < bestRank:
+			best, bestRank, candidates = i, rank, 1
+		case rank == bestRank:
+			candidates++
+			if pathKey(filepath.Dir(object.Path)) == folderKey && pathKey(filepath.Dir(idx.Objects[best].Path)) != folderKey {
+				best = i
+			}
+		}
+	}
+
+	if best == -1 {
+		return nil, "", false
+	}
+
+	_, reason := matcher.match(idx.Objects[best].Path)
+	if candidates > 1 {
+		reason = fmt.Sprintf("%s, 1 of %d candidates", reason, candidates)
+	}
+	return &idx.Objects[best], reason, true
+}
+
+// deviceIndexes builds storage indexes on first use
+type deviceIndexes struct {
+	dev     *mtp.Device
+	root    string
+	indexes map[uint32]*deviceIndex
+}
+
+func newDeviceIndexes(dev *mtp.Device, root string) *deviceIndexes {
+	return &deviceIndexes{dev: dev, root: root, indexes: make(map[uint32]*deviceIndex)}
+}
+
+func (d *deviceIndexes) Get(storageID uint32) (*deviceIndex, error) {
+	if index, ok := d.indexes[storageID]; ok {
+		return index, nil
+	}
+
+	index, err := buildDeviceIndex(d.dev, storageID, d.root)
+	if err != nil {
+		return nil, err
+	}
+	d.indexes[storageID] = index
+	return index, nil
+}
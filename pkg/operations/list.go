@@ -1,6 +1,7 @@
 package operations
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 	"reflect"
@@ -35,8 +36,9 @@ func FindOrCreateMusicFolder(dev *mtp.Device, storageID uint32) (uint32, error)
 	return folderID, nil
 }
 
-func FindObjectByPath(dev *mtp.Device, storageID uint32, path string) (uint32, error) {
-
+// normalizeLookupPath turns a playlist entry into the absolute /Music/... path that
+// FindObjectByPath looks up
+func normalizeLookupPath(path string) string {
 	path = strings.TrimSpace(path)
 
 	path = strings.TrimPrefix(path, "0:")
@@ -48,6 +50,13 @@ func FindObjectByPath(dev *mtp.Device, storageID uint32, path string) (uint32, e
 	path = strings.Replace(path, "/MUSIC/", "/Music/", 1)
 	path = strings.Replace(path, "/music/", "/Music/", 1)
 
+	return path
+}
+
+func FindObjectByPath(dev *mtp.Device, storageID uint32, path string) (uint32, error) {
+
+	path = normalizeLookupPath(path)
+
 	util.LogVerbose("Normalized path for lookup: %s", path)
 
 	objectID, err := FindObjectByPathManual(dev, storageID, path)
@@ -347,84 +356,21 @@ func FindPlaylists(dev *mtp.Device, storageID uint32) ([]string, error) {
 	return playlists, nil
 }
 
-// EnhancedDeletePlaylistAndAllSongs deletes a playlist and all its songs
+// EnhancedDeletePlaylistAndAllSongs deletes a playlist and the songs in it that no
+// other playlist uses. Songs are matched by exact path only.
 func EnhancedDeletePlaylistAndAllSongs(dev *mtp.Device, storagesRaw interface{}, playlistName string) error {
 	fmt.Println("\n=== Delete Playlist ===")
 	util.LogVerbose("Starting playlist deletion operation for %s", playlistName)
 
-	playlists, err := GetPlaylists(dev, storagesRaw)
-	if err != nil {
-		return fmt.Errorf("error getting playlists: %w", err)
-	}
-
-	var targetPlaylist *model.PlaylistInfo
-	for i, playlist := range playlists {
-		util.LogVerbose("Playlist: %s", playlist.Name)
-
-		if strings.EqualFold(playlist.Name, playlistName) ||
-			strings.EqualFold(filepath.Base(playlist.Path), playlistName) {
-			targetPlaylist = &playlists[i]
-			break
-		}
-	}
-
-	if targetPlaylist == nil {
-		util.LogError("Playlist '%s' not found", playlistName)
-		return fmt.Errorf("playlist '%s' not found", playlistName)
-	}
-
-	songs, err := ReadPlaylistContent(dev, targetPlaylist.StorageID, targetPlaylist.ObjectID)
-	if err != nil {
-		return fmt.Errorf("error reading playlist content: %w", err)
-	}
-
-	fmt.Printf("Playlist contains %d songs\n", len(songs))
-	util.LogInfo("Playlist contains %d songs", len(songs))
-
-	deletedSongs := 0
-	for _, songPath := range songs {
-		normalizedPath := strings.TrimPrefix(songPath, "0:")
-
-		if !strings.HasPrefix(normalizedPath, "/") {
-			normalizedPath = "/" + normalizedPath
-		}
-
-		normalizedPath = strings.Replace(normalizedPath, "/MUSIC/", "/Music/", 1)
-		normalizedPath = strings.Replace(normalizedPath, "/music/", "/Music/", 1)
-
-		util.LogVerbose("Original song path: %s", songPath)
-		util.LogVerbose("Normalized song path: %s", normalizedPath)
-
-		util.LogVerbose("Deleting song: %s", normalizedPath)
-		songObjectID, err := FindObjectByPath(dev, targetPlaylist.StorageID, normalizedPath)
-		if err != nil {
-			util.LogError("Could not find song '%s': %v", normalizedPath, err)
-			fmt.Printf("Could not find song: %s\n", normalizedPath)
-			continue
-		}
-
-		err = dev.DeleteObject(songObjectID)
-		if err != nil {
-			util.LogError("Failed to delete song '%s' (ID: %d): %v", normalizedPath, songObjectID, err)
-			fmt.Printf("Failed to delete song: %s\n", normalizedPath)
-		} else {
-			util.LogInfo("Deleted song: %s (ID: %d)", normalizedPath, songObjectID)
-			fmt.Printf("Deleted song: %s\n", normalizedPath)
-			deletedSongs++
-		}
-	}
-
-	err = dev.DeleteObject(targetPlaylist.ObjectID)
+	ctx := context.Background()
+	plan, err := PlanPlaylistDeletion(ctx, dev, storagesRaw, playlistName)
 	if err != nil {
-		return fmt.Errorf("failed to delete playlist '%s' (ID: %d): %w",
-			targetPlaylist.Path, targetPlaylist.ObjectID, err)
+		return err
 	}
 
-	util.LogInfo("Deleted playlist: %s (ID: %d)", targetPlaylist.Path, targetPlaylist.ObjectID)
-	fmt.Printf("Successfully deleted playlist '%s' and %d/%d songs\n",
-		targetPlaylist.Name, deletedSongs, len(songs))
-
-	return nil
+	DisplayPlaylistDeletionPlan(plan)
+	_, err = ExecutePlaylistDeletion(ctx, dev, storagesRaw, plan)
+	return err
 }
 
 func FindMP3Files(dev *mtp.Device, storageID uint32) ([]string, error) {
@@ -668,3 +614,22 @@ func GetParentIDForObject(dev *mtp.Device, objectID uint32) (uint32, error) {
 	}
 	return info.ParentObject, nil
 }
+
+// GetObjectPath builds the full path of an object by following its parents up to the
+// storage root
+func GetObjectPath(dev *mtp.Device, objectID uint32) (string, error) {
+	var names []string
+	for id := objectID; id != 0 && id != 0xFFFFFFFF; {
+		if len(names) > 64 {
+			return "", fmt.Errorf("object %d is nested too deeply", objectID)
+		}
+
+		info, err := util.GetObjectInfoWithRetry(dev, id)
+		if err != nil {
+			return "", fmt.Errorf("failed to get object info: %w", err)
+		}
+		names = append([]string{info.Filename}, names...)
+		id = info.ParentObject
+	}
+	return "/" + strings.Join(names, "/"), nil
+}
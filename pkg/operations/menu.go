@@ -2,12 +2,12 @@ package operations
 
 import (
 	"bufio"
+	"context"
 	"fmt"
-	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/ganeshrvel/go-mtpfs/mtp"
@@ -15,164 +15,6 @@ import (
 	"github.com/schachte/better-sync/pkg/util"
 )
 
-func ShowMenu() int {
-	fmt.Print("\033[H\033[2J")
-
-	titleColor := color.New(color.FgHiCyan, color.Bold).SprintFunc()
-	sectionColor := color.New(color.FgHiYellow).SprintFunc()
-	optionColor := color.New(color.FgHiWhite).SprintFunc()
-	numberColor := color.New(color.FgHiGreen, color.Bold).SprintFunc()
-
-	fmt.Println(titleColor("╔══════════════════════════════════════╗"))
-	fmt.Println(titleColor("║       GARMIN BETTER SYNC CLI         ║"))
-	fmt.Println(titleColor("╚══════════════════════════════════════╝"))
-
-	fmt.Println("\n" + sectionColor("🎧 SPOTIFY MANAGEMENT:"))
-	fmt.Printf("  %s %s\n", numberColor("1."), optionColor("Download Spotify playlist"))
-
-	fmt.Println("\n" + sectionColor("🎵 SONG MANAGEMENT:"))
-	fmt.Printf("  %s %s\n", numberColor("2."), optionColor("Show songs"))
-	fmt.Printf("  %s %s\n", numberColor("3."), optionColor("Upload song"))
-	fmt.Printf("  %s %s\n", numberColor("4."), optionColor("Delete song"))
-
-	fmt.Println("\n" + sectionColor("📋 PLAYLIST MANAGEMENT:"))
-	fmt.Printf("  %s %s\n", numberColor("5."), optionColor("Show playlists"))
-	fmt.Printf("  %s %s\n", numberColor("6."), optionColor("Show playlists and songs"))
-	fmt.Printf("  %s %s\n", numberColor("7."), optionColor("Create and upload playlist"))
-	fmt.Printf("  %s %s\n", numberColor("8."), optionColor("Upload directory and create playlist"))
-	fmt.Printf("  %s %s\n", numberColor("9."), optionColor("Delete playlist"))
-	fmt.Printf("  %s %s\n", numberColor("10."), optionColor("Delete playlist and all its songs"))
-
-	fmt.Println("\n" + sectionColor("📁 FOLDER MANAGEMENT:"))
-	fmt.Printf("  %s %s\n", numberColor("11."), optionColor("Delete all music contents from device"))
-
-	fmt.Println("\n" + sectionColor("🚪 SYSTEM:"))
-	fmt.Printf("  %s %s\n", numberColor("12."), optionColor("Exit"))
-
-	fmt.Print("\n" + color.HiMagentaString("Select an option") + color.HiWhiteString(" ❯ "))
-
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
-	input = strings.TrimSpace(input)
-
-	option := 0
-	fmt.Sscanf(input, "%d", &option)
-	return option
-}
-
-func Execute(dev *mtp.Device, storages interface{}, operation int) {
-	for {
-		op := operation
-		if op == 0 {
-			spotifyPath := os.Getenv("SPOTIFY_DOWNLOAD_PATH")
-			if spotifyPath != "" {
-				os.Setenv("SPOTIFY_DOWNLOAD_PATH", "")
-
-				storageID, musicFolderID, err := SelectStorageAndMusicFolder(dev, storages)
-				if err != nil {
-					util.LogError("Error selecting storage: %v", err)
-					fmt.Printf("Error: %v\n", err)
-				} else {
-					os.Setenv("AUTO_UPLOAD_PATH", spotifyPath)
-					autoPath := os.Getenv("AUTO_UPLOAD_PATH")
-					if autoPath != "" {
-						os.Setenv("AUTO_UPLOAD_PATH", "")
-						UploadDirectoryWithPlaylistFromPath(dev, storageID, musicFolderID, autoPath)
-					} else {
-						UploadDirectoryWithPlaylist(dev, storageID, musicFolderID)
-					}
-				}
-				op = ShowMenu()
-			} else {
-				op = ShowMenu()
-			}
-		}
-
-		switch op {
-		case 0: // Back
-			// Return to previous menu or application state
-			return
-		case 1: // Download Spotify playlist
-			DownloadSpotifyPlaylist()
-		case 2: // Show songs
-			songs, err := GetSongs(dev, storages)
-			if err != nil {
-				util.LogError("Error getting songs: %v", err)
-				break
-			}
-			DisplaySongsToConsole(songs)
-		case 3: // Upload song
-			UploadSong(dev, storages)
-		case 4: // Delete song
-			DeleteSong(dev, storages)
-		case 5: // Show playlists
-			playlists, err := GetPlaylists(dev, storages)
-			if err != nil {
-				util.LogError("Error getting playlists: %v", err)
-				break
-			}
-			DisplayPlaylistsToConsole(playlists)
-		case 6: // Show playlists and songs
-			result, err := GetPlaylistsWithSongs(dev, storages)
-			if err != nil {
-				util.LogError("Error getting playlists and songs: %v", err)
-				break
-			}
-			PrintPlaylistsAndSongs(dev, result)
-		case 7: // Create and upload playlist
-			CreateAndUploadPlaylist(dev, storages)
-		case 8: // Upload directory and create playlist
-			storageID, musicFolderID, err := SelectStorageAndMusicFolder(dev, storages)
-			if err != nil {
-				util.LogError("Error selecting storage: %v", err)
-				fmt.Printf("Error: %v\n", err)
-				break
-			}
-			UploadDirectoryWithPlaylist(dev, storageID, musicFolderID)
-		case 9: // Delete playlist
-			DeletePlaylist(dev, storages)
-		case 10: // Delete playlist and all its songs
-			DeletePlaylistAndAllSongs(dev, storages)
-		case 11: // Delete folder and all its contents
-			storageID, musicFolderID, err := SelectStorageAndMusicFolder(dev, storages)
-			if err != nil {
-				util.LogError("Error selecting storage: %v", err)
-				fmt.Printf("Error: %v\n", err)
-				break
-			}
-
-			fmt.Printf("\n⚠️ WARNING: Are you sure you want to delete this folder and all its contents?\n")
-			fmt.Print("Type 'yes' to confirm: ")
-			var confirmation string
-			fmt.Scanln(&confirmation)
-
-			if strings.ToLower(confirmation) != "yes" {
-				fmt.Println("Operation cancelled.")
-				break
-			}
-
-			err = DeleteFolderRecursively(dev, storageID, musicFolderID, "/Music", false)
-			if err != nil {
-				util.LogError("Error deleting folder: %v", err)
-			}
-		case 12: // Exit
-			color.HiYellow("Exiting program. Goodbye!")
-			return
-		default:
-			color.HiRed("Invalid option. Please try again.")
-		}
-
-		if operation != 0 {
-			break
-		}
-
-		if operation == 0 {
-			fmt.Print("\n" + color.HiWhiteString("Press Enter to continue..."))
-			bufio.NewReader(os.Stdin).ReadBytes('\n')
-		}
-	}
-}
-
 func DisplayPlaylistsToConsole(playlists []model.PlaylistInfo) {
 	if len(playlists) > 0 {
 		successColor := color.New(color.FgHiGreen, color.Bold).PrintFunc()
@@ -188,7 +30,8 @@ func DisplayPlaylistsToConsole(playlists []model.PlaylistInfo) {
 	}
 }
 
-func DisplaySongsToConsole(songs []model.Song) {
+// DisplaySongsToConsole lists songs with their durations (by object ID) when known
+func DisplaySongsToConsole(songs []model.Song, durations map[uint32]time.Duration) {
 	if len(songs) > 0 {
 		successColor := color.New(color.FgHiGreen, color.Bold).PrintFunc()
 		songNameColor := color.New(color.FgHiCyan).SprintFunc()
@@ -202,11 +45,19 @@ func DisplaySongsToConsole(songs []model.Song) {
 		successColor("🎧 Found songs")
 		fmt.Println(strings.Repeat("─", 50))
 
+		var totalDuration time.Duration
 		for i, song := range songs {
-			fmt.Printf("  %s %s %s\n",
+			length := ""
+			if duration, ok := durations[song.ObjectID]; ok {
+				length = " " + pathColor(fmt.Sprintf("(%s)", util.FormatDuration(duration)))
+				totalDuration += duration
+			}
+
+			fmt.Printf("  %s %s %s%s\n",
 				indexColor(fmt.Sprintf("%d.", i+1)),
 				musicEmoji,
-				songNameColor(song.Name))
+				songNameColor(song.Name),
+				length)
 			fmt.Printf("     %s %s\n",
 				strings.Repeat(" ", len(fmt.Sprintf("%d", i+1))+1),
 				pathColor(fmt.Sprintf("Path: %s", song.Path)))
@@ -218,6 +69,9 @@ func DisplaySongsToConsole(songs []model.Song) {
 
 		fmt.Println(strings.Repeat("─", 50))
 		fmt.Printf("\n%s Total songs: %s\n", musicEmoji, totalColor(fmt.Sprintf("%d", len(songs))))
+		if totalDuration > 0 {
+			fmt.Printf("%s Total length: %s\n", musicEmoji, totalColor(util.FormatDuration(totalDuration)))
+		}
 	} else {
 		errorColor := color.New(color.FgHiRed, color.Bold).PrintFunc()
 		errorColor("\n❌ No songs found\n")
@@ -238,11 +92,18 @@ func PrintPlaylistsAndSongs(dev *mtp.Device, result *model.DevicePlaylistData) {
 	headerColor("\n==== Playlists and Songs on Device ====\n")
 
 	totalSongs := 0
+	indexes := newDeviceIndexes(dev, "/Music")
+	durations := make(map[uint32]time.Duration)
 
 	for _, storage := range result.Storages {
 		storageColor(fmt.Sprintf("\nStorage: %s (ID: %d)\n",
 			storage.StorageDescription, storage.StorageID))
 
+		index, err := indexes.Get(storage.StorageID)
+		if err != nil {
+			util.LogVerbose("Could not index songs for durations: %v", err)
+		}
+
 		for i, playlist := range storage.Playlists {
 			playlistName := filepath.Base(playlist.Path)
 			playlistColor(fmt.Sprintf("\n%d. %s\n", i+1, playlistName))
@@ -251,11 +112,41 @@ func PrintPlaylistsAndSongs(dev *mtp.Device, result *model.DevicePlaylistData) {
 			songCount := len(playlist.SongPaths)
 			totalSongs += songCount
 
+			// Resolve every entry for the total length, even those not listed below
+			var playlistLength time.Duration
+			unknown := 0
+			songLengths := make([]string, songCount)
+			for j, songPath := range playlist.SongPaths {
+				var duration time.Duration
+				if index != nil {
+					if object, ok := index.Lookup(songPath); ok {
+						var cached bool
+						if duration, cached = durations[object.ObjectID]; !cached {
+							duration = objectDuration(dev, object.ObjectID, object.Path, object.Size)
+							durations[object.ObjectID] = duration
+						}
+					}
+				}
+
+				if duration > 0 {
+					playlistLength += duration
+					songLengths[j] = fmt.Sprintf(" (%s)", util.FormatDuration(duration))
+				} else {
+					unknown++
+				}
+			}
+
 			if songCount == 0 {
 				fmt.Println("   (Empty playlist)")
 			} else {
+				if unknown > 0 {
+					fmt.Printf("   Length: %s (%d songs of unknown length)\n", util.FormatDuration(playlistLength), unknown)
+				} else {
+					fmt.Printf("   Length: %s\n", util.FormatDuration(playlistLength))
+				}
+
 				for j, songPath := range playlist.SongPaths {
-					fmt.Printf("   %d.%d. %s\n", i+1, j+1, filepath.Base(songPath))
+					fmt.Printf("   %d.%d. %s%s\n", i+1, j+1, filepath.Base(songPath), songLengths[j])
 					fmt.Printf("       Path: %s\n", songPath)
 
 					if j >= 9 && songCount > 10 {
@@ -347,8 +238,18 @@ func DeletePlaylistAndAllSongs(dev *mtp.Device, storages interface{}) {
 		playlistName = strings.ToUpper(playlistName)
 	}
 
-	promptColor.Printf("\n⚠️  Are you sure you want to delete playlist '%s' and all its songs? (y/n): ",
-		strings.TrimSuffix(playlistName, ".M3U8"))
+	fmt.Println("\n🔄 Checking which songs other playlists use...")
+
+	plan, err := PlanPlaylistDeletion(context.Background(), dev, storages, playlistName)
+	if err != nil {
+		util.LogError("Error deleting playlist and songs: %v", err)
+		errorColor.Printf("\n❌ Error: %v\n", err)
+		return
+	}
+	DisplayPlaylistDeletionPlan(plan)
+
+	promptColor.Printf("\n⚠️  Are you sure you want to delete playlist '%s' and %d songs? (y/n): ",
+		strings.TrimSuffix(playlistName, ".M3U8"), len(plan.Delete))
 	confirmResponse, _ := reader.ReadString('\n')
 	confirmResponse = strings.TrimSpace(confirmResponse)
 
@@ -359,149 +260,12 @@ func DeletePlaylistAndAllSongs(dev *mtp.Device, storages interface{}) {
 
 	fmt.Println("\n🔄 Processing deletion request...")
 
-	if err := EnhancedDeletePlaylistAndAllSongs(dev, storages, playlistName); err != nil {
+	if _, err := ExecutePlaylistDeletion(context.Background(), dev, storages, plan); err != nil {
 		util.LogError("Error deleting playlist and songs: %v", err)
 		errorColor.Printf("\n❌ Error: %v\n", err)
 		return
 	}
 
-	successColor.Printf("\n✅ Successfully deleted playlist '%s' and all its songs\n",
+	successColor.Printf("\n✅ Successfully deleted playlist '%s' and its unshared songs\n",
 		strings.TrimSuffix(playlistName, ".M3U8"))
 }
-
-func DownloadSpotifyPlaylist() {
-	headerColor := color.New(color.FgHiCyan, color.Bold)
-	promptColor := color.New(color.FgHiYellow)
-	successColor := color.New(color.FgHiGreen, color.Bold)
-	errorColor := color.New(color.FgHiRed, color.Bold)
-	infoColor := color.New(color.FgHiWhite)
-
-	headerColor.Println("\n╔═══════════════════════════════════╗")
-	headerColor.Println("║    DOWNLOAD SPOTIFY PLAYLIST      ║")
-	headerColor.Println("╚═══════════════════════════════════╝")
-
-	reader := bufio.NewReader(os.Stdin)
-
-	promptColor.Print("\n🔗 Enter Spotify playlist URL: ")
-	playlistURL, err := reader.ReadString('\n')
-	playlistURL = strings.TrimSpace(playlistURL)
-	if err != nil || playlistURL == "" {
-		errorColor.Println("\n❌ Invalid input. Operation cancelled.")
-		return
-	}
-
-	playlistName, err := util.GetSpotifyPlaylistName(playlistURL)
-	if err != nil {
-		errorColor.Printf("\n❌ Error getting playlist name: %v\n", err)
-		promptColor.Print("\n📝 Enter playlist name: ")
-		playlistName, err = reader.ReadString('\n')
-		playlistName = strings.TrimSpace(playlistName)
-		if err != nil || playlistName == "" {
-			errorColor.Println("\n❌ Invalid input. Operation cancelled.")
-			return
-		}
-		return
-	}
-
-	playlistName = strings.TrimSpace(playlistName)
-	if err != nil || playlistName == "" {
-		errorColor.Println("\n❌ Invalid input. Operation cancelled.")
-		return
-	}
-
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		homeDir = "."
-	}
-	defaultDir := filepath.Join(homeDir, "Documents", "music")
-
-	promptColor.Printf("\n📂 Enter download path [default: %s]: ", defaultDir)
-	downloadPath, err := reader.ReadString('\n')
-	downloadPath = strings.TrimSpace(downloadPath)
-	if err != nil {
-		errorColor.Println("\n❌ Error reading input.")
-		return
-	}
-
-	if downloadPath == "" {
-		downloadPath = defaultDir
-	}
-
-	destDir := filepath.Join(downloadPath, playlistName)
-	os.MkdirAll(destDir, 0755)
-
-	headerColor.Println("\n📋 Configuration Summary:")
-	infoColor.Printf("  🔗 Playlist URL: ")
-	successColor.Printf("%s\n", playlistURL)
-	infoColor.Printf("  📝 Playlist Name: ")
-	successColor.Printf("%s\n", playlistName)
-	infoColor.Printf("  📂 Destination: ")
-	successColor.Printf("%s\n", destDir)
-
-	promptColor.Print("\n⚠️  Proceed with download? (y/n): ")
-	confirm, _ := reader.ReadString('\n')
-	confirm = strings.TrimSpace(confirm)
-	if strings.ToLower(confirm) != "y" {
-		promptColor.Println("\nOperation cancelled.")
-		return
-	}
-
-	infoColor.Println("\n⏳ Downloading playlist...")
-
-	cmd := exec.Command("spotdl", "download", playlistURL, "--output", filepath.Join(destDir, "{title}"))
-
-	stdout, _ := cmd.StdoutPipe()
-	stderr, _ := cmd.StderrPipe()
-
-	if err := cmd.Start(); err != nil {
-		errorColor.Printf("\n❌ Failed to start spotdl: %v\n", err)
-		errorColor.Println("\n❓ Is spotdl installed? Install with: pip install spotdl")
-		return
-	}
-
-	scanner := bufio.NewScanner(io.MultiReader(stdout, stderr))
-	for scanner.Scan() {
-		fmt.Println(scanner.Text())
-	}
-
-	if err := cmd.Wait(); err != nil {
-		errorColor.Printf("\n❌ Error during download: %v\n", err)
-		return
-	}
-
-	successColor.Printf("\n✅ Download complete! Files saved to: %s\n", destDir)
-
-	promptColor.Print("\n📲 Do you want to upload this playlist to your Garmin device? (y/n): ")
-	uploadConfirm, _ := reader.ReadString('\n')
-	uploadConfirm = strings.TrimSpace(uploadConfirm)
-
-	if strings.ToLower(uploadConfirm) == "y" {
-		infoColor.Println("\n🔄 Preparing to upload to Garmin device...")
-		os.Setenv("SPOTIFY_DOWNLOAD_PATH", destDir)
-		return
-	}
-}
-
-// UploadDirectoryWithPlaylistFromPath is a variant of UploadDirectoryWithPlaylist
-// that uses a pre-set path from a Spotify download
-func UploadDirectoryWithPlaylistFromPath(dev *mtp.Device, storageID, musicFolderID uint32, directoryPath string) {
-	headerColor := color.New(color.FgHiCyan, color.Bold)
-	infoColor := color.New(color.FgHiWhite)
-
-	headerColor.Println("\n╔═══════════════════════════════════╗")
-	headerColor.Println("║  UPLOAD SPOTIFY PLAYLIST TO GARMIN ║")
-	headerColor.Println("╚═══════════════════════════════════╝")
-
-	infoColor.Printf("\n📁 Using directory: %s\n", directoryPath)
-
-	// Set this global variable to be used by UploadDirectoryWithPlaylist
-	os.Setenv("PRESET_DIRECTORY_PATH", directoryPath)
-	os.Setenv("PRESET_CONFIRM_UPLOAD", "yes")
-
-	// Call the original function which will now use our preset path
-	UploadDirectoryWithPlaylist(dev, storageID, musicFolderID)
-
-	// Clear environment variables
-	os.Setenv("PRESET_DIRECTORY_PATH", "")
-	os.Setenv("PRESET_CONFIRM_UPLOAD", "")
-}
@@ -0,0 +1,200 @@
+package operations
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/schachte/better-sync/pkg/files"
+	"github.com/schachte/better-sync/pkg/model"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+type uint32Value struct {
+	Value uint32
+}
+
+// artistAlbumFromPath reads the artist and album folders of a /MUSIC/ARTIST/ALBUM/TRACK path
+func artistAlbumFromPath(path string) (string, string) {
+	parts := strings.Split(strings.TrimPrefix(path, "0:"), "/")
+
+	for i := 0; i < len(parts)-1; i++ {
+		if !strings.EqualFold(parts[i], "MUSIC") {
+			continue
+		}
+
+		var artist, album string
+		if i+2 < len(parts) {
+			artist = strings.ReplaceAll(parts[i+1], "_", " ")
+		}
+		if i+3 < len(parts) {
+			album = strings.ReplaceAll(parts[i+2], "_", " ")
+		}
+		return artist, album
+	}
+
+	return "", ""
+}
+
+func readStringProp(dev *mtp.Device, objectID uint32, propCode uint16) string {
+	value := mtp.StringValue{}
+	if err := dev.GetObjectPropValue(objectID, propCode, &value); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(value.Value)
+}
+
+// parseMTPDate parses the YYYYMMDDThhmmss date strings used by MTP properties
+func parseMTPDate(value string) time.Time {
+	for _, layout := range []string{"20060102T150405", "20060102"} {
+		if len(value) >= len(layout) {
+			if t, err := time.Parse(layout, value[:len(layout)]); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// GetTrack reads the metadata of a song from its object info and MTP properties,
+// falling back to the /MUSIC/ARTIST/ALBUM folder layout used by the uploader
+func GetTrack(dev *mtp.Device, song model.Song) model.Track {
+	track := model.Track{Song: song}
+	track.Artist, track.Album = artistAlbumFromPath(song.Path)
+	track.Title = stripNumericPrefix(song.Name)
+
+	if song.ObjectID == 0 {
+		return track
+	}
+
+	info, err := util.GetObjectInfoWithRetry(dev, song.ObjectID)
+	if err != nil {
+		util.LogVerbose("Could not read object info for %s: %v", song.Path, err)
+		return track
+	}
+	track.Size = int64(info.CompressedSize)
+	track.Added = info.ModificationDate
+
+	if artist := readStringProp(dev, song.ObjectID, mtp.OPC_Artist); artist != "" {
+		track.Artist = artist
+	}
+	if album := readStringProp(dev, song.ObjectID, mtp.OPC_AlbumName); album != "" {
+		track.Album = album
+	}
+	if title := readStringProp(dev, song.ObjectID, mtp.OPC_Name); title != "" {
+		track.Title = title
+	}
+	track.Genre = readStringProp(dev, song.ObjectID, mtp.OPC_Genre)
+
+	if released := readStringProp(dev, song.ObjectID, mtp.OPC_OriginalReleaseDate); len(released) >= 4 {
+		if year, err := strconv.Atoi(released[:4]); err == nil {
+			track.Year = year
+		}
+	}
+
+	if added := parseMTPDate(readStringProp(dev, song.ObjectID, mtp.OPC_DateAdded)); !added.IsZero() {
+		track.Added = added
+	}
+
+	track.Duration = objectDuration(dev, song.ObjectID, song.Path, track.Size)
+
+	return track
+}
+
+// deviceObjectReader reads byte ranges of a device object using the standard MTP
+// GetPartialObject operation, so headers can be parsed without a full download
+type deviceObjectReader struct {
+	dev      *mtp.Device
+	objectID uint32
+	size     int64
+}
+
+func (r *deviceObjectReader) ReadAt(p []byte, off int64) (int, error) {
+	if off >= r.size {
+		return 0, io.EOF
+	}
+
+	length := int64(len(p))
+	if off+length > r.size {
+		length = r.size - off
+	}
+
+	var buf bytes.Buffer
+	req := mtp.Container{Code: mtp.OC_GetPartialObject, Param: []uint32{r.objectID, uint32(off), uint32(length)}}
+	var rep mtp.Container
+	if err := r.dev.RunTransaction(&req, &rep, &buf, nil, 0, mtp.EmptyProgressFunc); err != nil {
+		return 0, err
+	}
+
+	n := copy(p, buf.Bytes())
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// objectDuration returns the duration the device reports for an object, falling back
+// to the MP3 frame headers read from the device. Zero means unknown.
+func objectDuration(dev *mtp.Device, objectID uint32, path string, size int64) time.Duration {
+	duration := uint32Value{}
+	if err := dev.GetObjectPropValue(objectID, mtp.OPC_Duration, &duration); err == nil && duration.Value > 0 {
+		return time.Duration(duration.Value) * time.Millisecond
+	}
+
+	if size <= 0 || !strings.EqualFold(filepath.Ext(path), ".mp3") {
+		return 0
+	}
+
+	info, err := files.ParseMP3Header(&deviceObjectReader{dev: dev, objectID: objectID, size: size}, size)
+	if err != nil {
+		util.LogVerbose("Could not read MP3 headers of %s: %v", path, err)
+		return 0
+	}
+	return info.Duration
+}
+
+// SongDurations returns the duration of each song by object ID; songs whose duration
+// cannot be determined are left out. It stops with ctx's error once ctx is done.
+func SongDurations(ctx context.Context, dev *mtp.Device, songs []model.Song) (map[uint32]time.Duration, error) {
+	durations := make(map[uint32]time.Duration)
+	for _, song := range songs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		info, err := util.GetObjectInfoWithRetry(dev, song.ObjectID)
+		if err != nil {
+			continue
+		}
+		if duration := objectDuration(dev, song.ObjectID, song.Path, int64(info.CompressedSize)); duration > 0 {
+			durations[song.ObjectID] = duration
+		}
+	}
+	return durations, nil
+}
+
+// durationSeconds converts a duration to #EXTINF seconds, -1 when unknown
+func durationSeconds(duration time.Duration) int {
+	if duration <= 0 {
+		return -1
+	}
+	return int(duration.Round(time.Second) / time.Second)
+}
+
+// GetTracks reads the metadata of every song
+func GetTracks(dev *mtp.Device, songs []model.Song) []model.Track {
+	tracks := make([]model.Track, 0, len(songs))
+	for _, song := range songs {
+		tracks = append(tracks, GetTrack(dev, song))
+	}
+	util.LogVerbose("Read metadata for %d tracks", len(tracks))
+	return tracks
+}
+
+func trackFolder(track model.Track) string {
+	return filepath.Dir(track.Path)
+}
@@ -0,0 +1,202 @@
+package operations
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/schachte/better-sync/pkg/model"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+// OrphanTrack is a song that no playlist references
+type OrphanTrack struct {
+	Song model.Song
+	Size int64
+	// Deleted is set once DeleteOrphans deleted the song; Error is why it could not
+	Deleted bool
+	Error   string
+}
+
+// OrphanReport lists the songs on the device that no playlist references
+type OrphanReport struct {
+	Songs     int
+	Playlists int
+	Orphans   []OrphanTrack
+	// NameMatches are songs whose path no playlist lists, but whose file name matches a
+	// playlist entry. FindObjectByPath would resolve that entry to them, so they are kept.
+	NameMatches []model.Song
+	TotalSize   int64
+}
+
+// OrphanOptions controls RunOrphanCleanup
+type OrphanOptions struct {
+	Delete    bool
+	AssumeYes bool
+}
+
+// orphanKey normalises a path the same way FindObjectByPath does; the lookup itself is
+// case-insensitive, so the key is upper case
+func orphanKey(path string) string {
+	return strings.ToUpper(normalizeLookupPath(path))
+}
+
+// FindOrphans cross-references every song with the entries of every playlist
+func FindOrphans(dev *mtp.Device, storagesRaw interface{}) (*OrphanReport, error) {
+	songs, err := GetSongs(dev, storagesRaw)
+	if err != nil {
+		return nil, fmt.Errorf("error getting songs: %w", err)
+	}
+
+	playlistData, err := GetPlaylistsWithSongs(dev, storagesRaw)
+	if err != nil {
+		return nil, fmt.Errorf("error getting playlists: %w", err)
+	}
+
+	referenced := make(map[string]bool)
+	referencedNames := make(map[string]bool)
+	for _, storage := range playlistData.Storages {
+		for _, playlist := range storage.Playlists {
+			for _, songPath := range playlist.SongPaths {
+				referenced[orphanKey(songPath)] = true
+
+				name := strings.ToUpper(filepath.Base(strings.ReplaceAll(songPath, "\\", "/")))
+				referencedNames[name] = true
+				referencedNames[stripNumericPrefix(name)] = true
+			}
+		}
+	}
+
+	report := &OrphanReport{Songs: len(songs), Playlists: playlistData.TotalPlaylists}
+	for _, song := range songs {
+		if referenced[orphanKey(song.Path)] {
+			continue
+		}
+
+		name := strings.ToUpper(filepath.Base(song.Path))
+		if referencedNames[name] || referencedNames[stripNumericPrefix(name)] {
+			report.NameMatches = append(report.NameMatches, song)
+			continue
+		}
+
+		orphan := OrphanTrack{Song: song}
+		if info, err := util.GetObjectInfoWithRetry(dev, song.ObjectID); err == nil {
+			orphan.Size = int64(info.CompressedSize)
+		}
+		report.Orphans = append(report.Orphans, orphan)
+		report.TotalSize += orphan.Size
+	}
+
+	util.LogInfo("Found %d orphaned songs of %d (%d playlists)", len(report.Orphans), report.Songs, report.Playlists)
+	return report, nil
+}
+
+// DeleteOrphans deletes every orphan in the report, returning how many were deleted
+// and the space freed, and marks each orphan it deleted. Once ctx asks to stop, the remaining orphans are kept and the
+// error wraps ErrInterrupted.
+func DeleteOrphans(ctx context.Context, dev *mtp.Device, report *OrphanReport) (int, int64, error) {
+	deleted := 0
+	var freed int64
+	var failed []string
+
+	for i := range report.Orphans {
+		orphan := &report.Orphans[i]
+		if stopRequested(ctx) {
+			return deleted, freed, fmt.Errorf("deletion %w: %d songs were not deleted", ErrInterrupted,
+				len(report.Orphans)-i)
+		}
+
+		fmt.Fprintf(consoleOf(ctx), "[%d/%d] Deleting %s\n", i+1, len(report.Orphans), orphan.Song.Path)
+		untrash, err := trashObject(dev, orphan.Song.StorageID, orphan.Song.ObjectID, orphan.Song.Path)
+		if err != nil {
+			util.LogError("Skipping %s: %v", orphan.Song.Path, err)
+			orphan.Error = err.Error()
+			failed = append(failed, orphan.Song.Path)
+			continue
+		}
+		if err := deleteDeviceObject(dev, orphan.Song.StorageID, orphan.Song.ObjectID); err != nil {
+			untrash()
+			util.LogError("Error deleting %s: %v", orphan.Song.Path, err)
+			orphan.Error = err.Error()
+			failed = append(failed, orphan.Song.Path)
+			continue
+		}
+		orphan.Deleted = true
+		deleted++
+		freed += orphan.Size
+	}
+
+	if len(failed) > 0 {
+		return deleted, freed, fmt.Errorf("%d songs could not be deleted", len(failed))
+	}
+	return deleted, freed, nil
+}
+
+func DisplayOrphanReport(report *OrphanReport) {
+	warnColor := color.New(color.FgHiYellow).SprintFunc()
+	pathColor := color.New(color.Faint).SprintFunc()
+
+	fmt.Println("\n==== Orphaned Songs ====")
+	fmt.Printf("%d songs, %d playlists\n", report.Songs, report.Playlists)
+
+	if len(report.Orphans) == 0 {
+		color.HiGreen("\nEvery song is referenced by at least one playlist.")
+	} else {
+		fmt.Println()
+		for i, orphan := range report.Orphans {
+			fmt.Printf("%3d. %-50s %10s\n", i+1, orphan.Song.Name, util.FormatSize(orphan.Size))
+			fmt.Printf("     %s\n", pathColor(orphan.Song.Path))
+		}
+		fmt.Printf("\n%d orphaned songs, %s reclaimable\n", len(report.Orphans), util.FormatSize(report.TotalSize))
+	}
+
+	if len(report.NameMatches) > 0 {
+		fmt.Printf("\n%s %d songs are only matched by file name from a playlist entry in another folder and were kept:\n",
+			warnColor("!"), len(report.NameMatches))
+		for _, song := range report.NameMatches {
+			fmt.Printf("     %s\n", pathColor(song.Path))
+		}
+	}
+}
+
+// RunOrphanCleanup lists orphaned songs and, when asked to, deletes them all after a
+// single confirmation. The report marks the songs that were deleted.
+func RunOrphanCleanup(ctx context.Context, dev *mtp.Device, storagesRaw interface{}, options OrphanOptions) (*OrphanReport, error) {
+	report, err := FindOrphans(dev, storagesRaw)
+	if err != nil {
+		return nil, err
+	}
+
+	DisplayOrphanReport(report)
+	if len(report.Orphans) == 0 || !options.Delete {
+		return report, nil
+	}
+
+	if !options.AssumeYes {
+		fmt.Printf("\nDelete %d orphaned songs (%s)? (y/n): ", len(report.Orphans), util.FormatSize(report.TotalSize))
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Scan()
+		if strings.ToLower(strings.TrimSpace(scanner.Text())) != "y" {
+			fmt.Println("Operation cancelled.")
+			return report, nil
+		}
+	}
+
+	deleted, freed, err := DeleteOrphans(ctx, dev, report)
+	color.HiGreen("\n✓ Deleted %d songs, freed %s", deleted, util.FormatSize(freed))
+	pruneAfterDelete(ctx, dev, storagesRaw)
+	return report, err
+}
+
+// CleanupOrphans is the interactive menu entry for RunOrphanCleanup
+func CleanupOrphans(dev *mtp.Device, storagesRaw interface{}) {
+	fmt.Println("\n=== Find Orphaned Songs ===")
+	if _, err := RunOrphanCleanup(context.Background(), dev, storagesRaw, OrphanOptions{Delete: true}); err != nil {
+		util.LogError("Error cleaning up orphaned songs: %v", err)
+	}
+}
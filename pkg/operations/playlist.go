@@ -0,0 +1,542 @@
+package operations
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/schachte/better-sync/pkg/files"
+	"github.com/schachte/better-sync/pkg/model"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+var playlistExtensions = []string{".m3u8", ".m3u", ".pls"}
+
+// PlaylistFileName applies the sanitisation used for every playlist written to the device
+func PlaylistFileName(name string) string {
+	name = strings.ToUpper(util.SanitizeFileName(strings.TrimSpace(name)))
+	if !hasPlaylistExtension(name) {
+		name += ".m3u8"
+	}
+	return name
+}
+
+// PlaylistFileNameForFormat is PlaylistFileName with the extension chosen by format;
+// an empty format keeps the extension of the name, defaulting to M3U8
+func PlaylistFileNameForFormat(name, format string) string {
+	if format == "" {
+		return PlaylistFileName(name)
+	}
+
+	name = strings.TrimSpace(name)
+	if hasPlaylistExtension(name) {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+
+	if format == files.PlaylistFormatPLS {
+		return PlaylistFileName(name + ".pls")
+	}
+	return PlaylistFileName(name + ".m3u8")
+}
+
+func hasPlaylistExtension(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, playlistExt := range playlistExtensions {
+		if ext == playlistExt {
+			return true
+		}
+	}
+	return false
+}
+
+// playlistKey is the name used to compare playlists: no extension, no case
+func playlistKey(name string) string {
+	name = filepath.Base(strings.TrimSpace(name))
+	if hasPlaylistExtension(name) {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return strings.ToUpper(name)
+}
+
+func findPlaylist(playlists []model.PlaylistInfo, name string) *model.PlaylistInfo {
+	key := playlistKey(name)
+	sanitizedKey := playlistKey(PlaylistFileName(name))
+	for i, playlist := range playlists {
+		playlistName := playlistKey(playlist.Name)
+		if playlistName == key || playlistName == sanitizedKey {
+			return &playlists[i]
+		}
+	}
+	return nil
+}
+
+func findPlaylistInStorage(playlists []model.PlaylistInfo, storageID uint32, fileName string) *model.PlaylistInfo {
+	key := playlistKey(fileName)
+	for i, playlist := range playlists {
+		if playlist.StorageID == storageID && playlistKey(playlist.Name) == key {
+			return &playlists[i]
+		}
+	}
+	return nil
+}
+
+// targetPlaylistFileName sanitises newName, keeping the source's format when no extension is given
+func targetPlaylistFileName(source *model.PlaylistInfo, newName string) (string, error) {
+	sourceExt := strings.ToLower(filepath.Ext(source.Name))
+	if !hasPlaylistExtension(newName) {
+		newName += sourceExt
+	}
+
+	fileName := PlaylistFileName(newName)
+	if !strings.EqualFold(filepath.Ext(fileName), sourceExt) {
+		return "", fmt.Errorf("cannot change playlist format from %s to %s", sourceExt, strings.ToLower(filepath.Ext(fileName)))
+	}
+
+	return fileName, nil
+}
+
+func readObjectData(dev *mtp.Device, objectID uint32) ([]byte, error) {
+	var buf bytes.Buffer
+	err := dev.GetObject(objectID, &buf, model.EmptyProgressFunc)
+	if err != nil {
+		return nil, fmt.Errorf("error reading object %d: %w", objectID, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// replacePlaylistData rewrites a playlist with new content under the same name. MTP has
+// no way to overwrite an object, so the old playlist is deleted and a new one uploaded;
+// if that upload fails the original content is put back.
+func replacePlaylistData(dev *mtp.Device, playlist model.PlaylistInfo, data []byte) (uint32, error) {
+	parentID, err := GetParentIDForObject(dev, playlist.ObjectID)
+	if err != nil {
+		return 0, err
+	}
+
+	original, err := readObjectData(dev, playlist.ObjectID)
+	if err != nil {
+		return 0, fmt.Errorf("error reading playlist content: %w", err)
+	}
+
+	if err := dev.DeleteObject(playlist.ObjectID); err != nil {
+		util.LogError("Error deleting playlist %s: %v", playlist.Path, err)
+		if err := TryAlternativeDeleteMethod(dev, playlist.StorageID, playlist.ObjectID); err != nil {
+			return 0, fmt.Errorf("could not remove old playlist %s: %w", playlist.Path, err)
+		}
+	}
+
+	objectID, err := files.UploadPlaylistData(dev, playlist.StorageID, parentID, playlist.Name, data)
+	if err != nil {
+		if _, restoreErr := files.UploadPlaylistData(dev, playlist.StorageID, parentID, playlist.Name, original); restoreErr != nil {
+			util.LogError("Could not restore playlist %s: %v", playlist.Path, restoreErr)
+		}
+		return 0, fmt.Errorf("error uploading playlist %s: %w", playlist.Path, err)
+	}
+
+	util.LogVerbose("Rewrote playlist %s (ID: %d -> %d)", playlist.Path, playlist.ObjectID, objectID)
+	return objectID, nil
+}
+
+// canRenameObject reports whether the device lets us change the filename property in place
+func canRenameObject(dev *mtp.Device, objectFormat uint16) bool {
+	if !deviceSupports(dev, mtp.OC_MTP_SetObjectPropValue) {
+		return false
+	}
+
+	props := mtp.Uint16Array{}
+	if err := dev.GetObjectPropsSupported(objectFormat, &props); err != nil {
+		util.LogVerbose("Could not read supported properties for format 0x%04X: %v", objectFormat, err)
+		return false
+	}
+
+	for _, prop := range props.Values {
+		if prop != mtp.OPC_ObjectFileName {
+			continue
+		}
+		desc := mtp.ObjectPropDesc{}
+		if err := dev.GetObjectPropDesc(mtp.OPC_ObjectFileName, objectFormat, &desc); err != nil {
+			util.LogVerbose("Could not read filename property description: %v", err)
+			return false
+		}
+		return desc.GetSet == 1
+	}
+
+	return false
+}
+
+// RenameDevicePlaylist renames a playlist in place when the device allows it,
+// otherwise it uploads the content under the new name and removes the old object.
+// Nothing is renamed once ctx asks to stop.
+func RenameDevicePlaylist(ctx context.Context, dev *mtp.Device, storagesRaw interface{}, oldName, newName string) (*model.PlaylistInfo, error) {
+	playlists, err := GetPlaylists(dev, storagesRaw)
+	if err != nil {
+		return nil, fmt.Errorf("error getting playlists: %w", err)
+	}
+
+	source := findPlaylist(playlists, oldName)
+	if source == nil {
+		return nil, fmt.Errorf("playlist '%s' not found", oldName)
+	}
+
+	fileName, err := targetPlaylistFileName(source, newName)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing := findPlaylistInStorage(playlists, source.StorageID, fileName); existing != nil {
+		if existing.ObjectID == source.ObjectID {
+			return nil, fmt.Errorf("playlist '%s' is already named '%s'", source.Name, fileName)
+		}
+		return nil, fmt.Errorf("a playlist named '%s' already exists at %s", existing.Name, existing.Path)
+	}
+	if stopRequested(ctx) {
+		return nil, fmt.Errorf("rename of '%s' %w", source.Name, ErrInterrupted)
+	}
+
+	info, err := util.GetObjectInfoWithRetry(dev, source.ObjectID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting playlist info: %w", err)
+	}
+
+	renamed := *source
+	renamed.Name = fileName
+	renamed.Path = filepath.Join(filepath.Dir(source.Path), fileName)
+
+	if canRenameObject(dev, info.ObjectFormat) {
+		err = dev.SetObjectPropValue(source.ObjectID, mtp.OPC_ObjectFileName, &mtp.StringValue{Value: fileName})
+		if err == nil {
+			util.LogInfo("Renamed playlist %s to %s in place (ID: %d)", source.Path, fileName, source.ObjectID)
+			return &renamed, nil
+		}
+		util.LogError("In-place rename of %s failed, re-uploading instead: %v", source.Path, err)
+	} else {
+		util.LogVerbose("Device does not support renaming objects, re-uploading %s", source.Path)
+	}
+
+	data, err := readObjectData(dev, source.ObjectID)
+	if err != nil {
+		return nil, fmt.Errorf("error reading playlist content: %w", err)
+	}
+
+	objectID, err := files.UploadPlaylistData(dev, source.StorageID, info.ParentObject, fileName, data)
+	if err != nil {
+		return nil, fmt.Errorf("error uploading renamed playlist: %w", err)
+	}
+	renamed.ObjectID = objectID
+
+	err = dev.DeleteObject(source.ObjectID)
+	if err != nil {
+		util.LogError("Error deleting old playlist %s: %v", source.Path, err)
+		err = TryAlternativeDeleteMethod(dev, source.StorageID, source.ObjectID)
+		if err != nil {
+			return &renamed, fmt.Errorf("created %s but could not remove %s: %w", renamed.Path, source.Path, err)
+		}
+	}
+
+	util.LogInfo("Renamed playlist %s to %s by re-upload (ID: %d)", source.Path, fileName, objectID)
+	return &renamed, nil
+}
+
+// CopyDevicePlaylist duplicates a playlist under a new name, keeping its content byte
+// for byte. Nothing is copied once ctx asks to stop.
+func CopyDevicePlaylist(ctx context.Context, dev *mtp.Device, storagesRaw interface{}, sourceName, targetName string) (*model.PlaylistInfo, error) {
+	playlists, err := GetPlaylists(dev, storagesRaw)
+	if err != nil {
+		return nil, fmt.Errorf("error getting playlists: %w", err)
+	}
+
+	source := findPlaylist(playlists, sourceName)
+	if source == nil {
+		return nil, fmt.Errorf("playlist '%s' not found", sourceName)
+	}
+
+	fileName, err := targetPlaylistFileName(source, targetName)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing := findPlaylistInStorage(playlists, source.StorageID, fileName); existing != nil {
+		return nil, fmt.Errorf("a playlist named '%s' already exists at %s", existing.Name, existing.Path)
+	}
+	if stopRequested(ctx) {
+		return nil, fmt.Errorf("copy of '%s' %w", source.Name, ErrInterrupted)
+	}
+
+	parentID, err := GetParentIDForObject(dev, source.ObjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := readObjectData(dev, source.ObjectID)
+	if err != nil {
+		return nil, fmt.Errorf("error reading playlist content: %w", err)
+	}
+
+	objectID, err := files.UploadPlaylistData(dev, source.StorageID, parentID, fileName, data)
+	if err != nil {
+		return nil, fmt.Errorf("error uploading playlist copy: %w", err)
+	}
+
+	util.LogInfo("Copied playlist %s to %s (ID: %d)", source.Path, fileName, objectID)
+
+	return &model.PlaylistInfo{
+		Name:      fileName,
+		Path:      filepath.Join(filepath.Dir(source.Path), fileName),
+		ObjectID:  objectID,
+		StorageID: source.StorageID,
+		Storage:   source.Storage,
+	}, nil
+}
+
+// CreateDevicePlaylist writes a playlist of songs already on the device. Tracks are
+// device paths such as /Music/ARTIST/ALBUM/SONG.MP3 and must match a song exactly.
+// An existing playlist with the same name is only overwritten when replace is set.
+func CreateDevicePlaylist(ctx context.Context, dev *mtp.Device, storagesRaw interface{}, name string, tracks []string, replace bool) (*model.Playlist, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("no playlist name given")
+	}
+	if len(tracks) == 0 {
+		return nil, fmt.Errorf("no tracks given for playlist '%s'", name)
+	}
+
+	storageID, musicFolderID, err := SelectStorageAndMusicFolder(ctx, dev, storagesRaw)
+	if err != nil {
+		return nil, fmt.Errorf("error selecting storage: %w", err)
+	}
+
+	fileName := PlaylistFileName(name)
+	playlists, err := GetPlaylists(dev, storagesRaw)
+	if err != nil {
+		return nil, fmt.Errorf("error getting playlists: %w", err)
+	}
+
+	existing := findPlaylistInStorage(playlists, storageID, fileName)
+	if existing != nil && !replace {
+		return nil, fmt.Errorf("a playlist named '%s' already exists at %s", existing.Name, existing.Path)
+	}
+
+	index, err := buildDeviceIndex(dev, storageID, "/Music")
+	if err != nil {
+		return nil, err
+	}
+
+	songPaths, durations, err := resolvePlaylistTracks(dev, index, tracks, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		content := buildPlaylistContent(songPaths, PlaylistPathStyle(dev), files.PlaylistFormatForName(fileName), durations)
+		objectID, err := replacePlaylistData(dev, *existing, []byte(content))
+		if err != nil {
+			return nil, fmt.Errorf("could not replace playlist %s: %w", existing.Path, err)
+		}
+		util.LogInfo("Replaced playlist %s with %d songs (ID: %d)", existing.Path, len(songPaths), objectID)
+		return &model.Playlist{Path: existing.Name, ObjectID: objectID, StorageID: existing.StorageID, SongPaths: songPaths}, nil
+	}
+
+	playlist, err := createPlaylist(ctx, dev, storageID, musicFolderID, fileName, songPaths, durations)
+	if err != nil {
+		return nil, fmt.Errorf("playlist creation failed: %w", err)
+	}
+
+	util.LogInfo("Created playlist %s with %d songs (ID: %d)", fileName, len(songPaths), playlist.ObjectID)
+	return &playlist, nil
+}
+
+// resolvePlaylistTracks turns device paths into the song paths written to a playlist,
+// with their durations. Tracks in skip, by pathKey, are left out; every other track must
+// match a song exactly.
+func resolvePlaylistTracks(dev *mtp.Device, index *deviceIndex, tracks []string, skip map[string]bool) ([]string, map[string]time.Duration, error) {
+	var songPaths []string
+	var missing []string
+	durations := make(map[string]time.Duration)
+	for _, track := range tracks {
+		object, ok := index.Lookup(strings.TrimSpace(track))
+		if !ok || object.IsDir {
+			missing = append(missing, track)
+			continue
+		}
+		if skip[pathKey(object.Path)] {
+			continue
+		}
+		if skip != nil {
+			skip[pathKey(object.Path)] = true
+		}
+
+		songPath := "0:" + strings.ToUpper(normalizePath(object.Path))
+		songPaths = append(songPaths, songPath)
+		durations[songPath] = objectDuration(dev, object.ObjectID, object.Path, object.Size)
+	}
+	if len(missing) > 0 {
+		return nil, nil, fmt.Errorf("no song at %s", strings.Join(missing, ", "))
+	}
+	return songPaths, durations, nil
+}
+
+// AddToDevicePlaylist appends songs already on the device to a playlist, creating it
+// when there is none with that name. Songs the playlist lists already are skipped; the
+// existing entries are left as they are.
+func AddToDevicePlaylist(ctx context.Context, dev *mtp.Device, storagesRaw interface{}, name string, tracks []string) (*model.Playlist, error) {
+	playlists, err := GetPlaylists(dev, storagesRaw)
+	if err != nil {
+		return nil, fmt.Errorf("error getting playlists: %w", err)
+	}
+
+	existing := findPlaylist(playlists, name)
+	if existing == nil {
+		return CreateDevicePlaylist(ctx, dev, storagesRaw, name, tracks, false)
+	}
+
+	data, err := readObjectData(dev, existing.ObjectID)
+	if err != nil {
+		return nil, fmt.Errorf("error reading playlist content: %w", err)
+	}
+	index, err := buildDeviceIndex(dev, existing.StorageID, "/Music")
+	if err != nil {
+		return nil, err
+	}
+
+	listed := make(map[string]bool)
+	for _, entry := range files.ParsePlaylist(existing.Name, string(data)) {
+		if object, ok := index.Lookup(strings.TrimSpace(entry.Location)); ok {
+			listed[pathKey(object.Path)] = true
+		}
+	}
+	songPaths, durations, err := resolvePlaylistTracks(dev, index, tracks, listed)
+	if err != nil {
+		return nil, err
+	}
+
+	playlist := &model.Playlist{Path: existing.Name, ObjectID: existing.ObjectID, StorageID: existing.StorageID, SongPaths: songPaths}
+	if len(songPaths) == 0 {
+		util.LogInfo("Playlist %s already lists every song", existing.Name)
+		return playlist, nil
+	}
+
+	entries := buildPlaylistEntries(songPaths, PlaylistPathStyle(dev), durations)
+	content := files.AppendPlaylist(existing.Name, string(data), entries)
+	if playlist.ObjectID, err = replacePlaylistData(dev, *existing, []byte(content)); err != nil {
+		return nil, err
+	}
+
+	util.LogInfo("Added %d songs to playlist %s", len(songPaths), existing.Name)
+	return playlist, nil
+}
+
+// DeleteDevicePlaylist deletes a playlist by name, leaving its songs on the device.
+// Nothing is deleted once ctx asks to stop.
+func DeleteDevicePlaylist(ctx context.Context, dev *mtp.Device, storagesRaw interface{}, name string) (*model.PlaylistInfo, error) {
+	playlists, err := GetPlaylists(dev, storagesRaw)
+	if err != nil {
+		return nil, fmt.Errorf("error getting playlists: %w", err)
+	}
+
+	playlist := findPlaylist(playlists, name)
+	if playlist == nil {
+		return nil, fmt.Errorf("playlist '%s' not found", name)
+	}
+	if stopRequested(ctx) {
+		return nil, fmt.Errorf("deletion of '%s' %w", playlist.Name, ErrInterrupted)
+	}
+
+	untrash, err := trashObject(dev, playlist.StorageID, playlist.ObjectID, playlist.Path)
+	if err != nil {
+		return nil, fmt.Errorf("playlist '%s' was not deleted: %w", playlist.Path, err)
+	}
+	if err := deleteDeviceObject(dev, playlist.StorageID, playlist.ObjectID); err != nil {
+		untrash()
+		return nil, fmt.Errorf("failed to delete playlist '%s' (ID: %d): %w", playlist.Path, playlist.ObjectID, err)
+	}
+
+	util.LogInfo("Deleted playlist: %s (ID: %d)", playlist.Path, playlist.ObjectID)
+	return playlist, nil
+}
+
+// selectPlaylist lists the playlists on the device and asks the user to pick one
+func selectPlaylist(dev *mtp.Device, storagesRaw interface{}, scanner *bufio.Scanner, action string) *model.PlaylistInfo {
+	playlists, err := GetPlaylists(dev, storagesRaw)
+	if err != nil {
+		util.LogError("Error getting playlists: %v", err)
+		return nil
+	}
+
+	if len(playlists) == 0 {
+		fmt.Println("No playlists found on the device")
+		return nil
+	}
+
+	fmt.Println("\n==== Available Playlists ====")
+	for i, playlist := range playlists {
+		fmt.Printf("%d. [%s] %s\n", i+1, playlist.Storage, playlist.Name)
+	}
+
+	fmt.Printf("\nSelect playlist to %s (1-%d): ", action, len(playlists))
+	scanner.Scan()
+	var selection int
+	if _, err := fmt.Sscanf(strings.TrimSpace(scanner.Text()), "%d", &selection); err != nil ||
+		selection < 1 || selection > len(playlists) {
+		fmt.Println("Invalid selection")
+		return nil
+	}
+
+	return &playlists[selection-1]
+}
+
+func RenamePlaylist(dev *mtp.Device, storagesRaw interface{}) {
+	fmt.Println("\n=== Rename Playlist ===")
+
+	scanner := bufio.NewScanner(os.Stdin)
+	selected := selectPlaylist(dev, storagesRaw, scanner, "rename")
+	if selected == nil {
+		return
+	}
+
+	fmt.Print("Enter new playlist name: ")
+	scanner.Scan()
+	newName := strings.TrimSpace(scanner.Text())
+	if newName == "" {
+		fmt.Println("No name provided. Operation cancelled.")
+		return
+	}
+
+	renamed, err := RenameDevicePlaylist(context.Background(), dev, storagesRaw, selected.Name, newName)
+	if err != nil {
+		util.LogError("Error renaming playlist: %v", err)
+		return
+	}
+
+	fmt.Printf("Renamed playlist '%s' to '%s'\n", selected.Name, renamed.Name)
+}
+
+func CopyPlaylist(dev *mtp.Device, storagesRaw interface{}) {
+	fmt.Println("\n=== Copy Playlist ===")
+
+	scanner := bufio.NewScanner(os.Stdin)
+	selected := selectPlaylist(dev, storagesRaw, scanner, "copy")
+	if selected == nil {
+		return
+	}
+
+	fmt.Print("Enter name for the copy: ")
+	scanner.Scan()
+	newName := strings.TrimSpace(scanner.Text())
+	if newName == "" {
+		fmt.Println("No name provided. Operation cancelled.")
+		return
+	}
+
+	copied, err := CopyDevicePlaylist(context.Background(), dev, storagesRaw, selected.Name, newName)
+	if err != nil {
+		util.LogError("Error copying playlist: %v", err)
+		return
+	}
+
+	fmt.Printf("Copied playlist '%s' to '%s'\n", selected.Name, copied.Name)
+}
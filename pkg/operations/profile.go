@@ -0,0 +1,255 @@
+package operations
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/schachte/better-sync/pkg/config"
+	"github.com/schachte/better-sync/pkg/device"
+	"github.com/schachte/better-sync/pkg/files"
+	"github.com/schachte/better-sync/pkg/model"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+const (
+	PathStyleFromDevice  = "saved for this device"
+	PathStyleFromConfig  = "config"
+	PathStyleFromProfile = "device profile"
+	PathStyleFromDefault = "default"
+)
+
+// probeSongCount is how many songs each probe playlist lists
+const probeSongCount = 3
+
+const probePlaylistPrefix = "BETTER SYNC PROBE "
+
+// PathStyleChoice is the playlist path style used for a device and where it came from
+type PathStyleChoice struct {
+	Model  string
+	Serial string
+	Style  int
+	Source string
+}
+
+var pathStyleChoices = make(map[*mtp.Device]PathStyleChoice)
+
+// ResolvePathStyle picks the playlist path style for a device: the style set by a flag,
+// the environment or config.toml (including its [devices."SERIAL"] table), then the
+// style saved for its serial number in devices.json by a probe, then the style listed
+// for its model, then the default. A path_style other than auto in config.toml therefore
+// wins over the probed style.
+func ResolvePathStyle(dev *mtp.Device) PathStyleChoice {
+	if choice, ok := pathStyleChoices[dev]; ok {
+		return choice
+	}
+
+	choice := PathStyleChoice{Style: util.DefaultPathStyle, Source: PathStyleFromDefault}
+
+	modelName, serial, err := device.Identify(dev)
+	if err != nil {
+		util.LogVerbose("Using default path style: %v", err)
+		return choice
+	}
+	choice.Model = modelName
+	choice.Serial = serial
+
+	if style, source := config.PathStyle(); style != 0 {
+		choice.Style = style
+		choice.Source = PathStyleFromConfig + " (" + source + ")"
+	} else if settings, err := files.LoadDeviceSettings(); err != nil {
+		util.LogError("Error loading device settings: %v", err)
+	} else if saved, ok := settings[serial]; ok && serial != "" && saved.PathStyle != 0 {
+		choice.Style = saved.PathStyle
+		choice.Source = PathStyleFromDevice
+	}
+
+	if choice.Source == PathStyleFromDefault {
+		if style, ok := device.ProfilePathStyle(modelName); ok {
+			choice.Style = style
+			choice.Source = PathStyleFromProfile
+		}
+	}
+
+	util.LogInfo("Using path style %d for %s (%s)", choice.Style, modelName, choice.Source)
+	pathStyleChoices[dev] = choice
+	return choice
+}
+
+// PlaylistPathStyle returns the path style used for playlist entries written to dev
+func PlaylistPathStyle(dev *mtp.Device) int {
+	return ResolvePathStyle(dev).Style
+}
+
+// SavePathStyle remembers the path style that works for the connected device
+func SavePathStyle(dev *mtp.Device, style int) error {
+	if style < util.PathStyleDrive || style > util.PathStyleMixedCase {
+		return fmt.Errorf("unknown path style %d, expected 1-%d", style, len(util.PathStyles))
+	}
+
+	choice := ResolvePathStyle(dev)
+	if choice.Serial == "" {
+		return fmt.Errorf("the device did not report a serial number, so the path style cannot be remembered")
+	}
+
+	if err := files.SaveDeviceSettings(choice.Serial, model.DeviceSettings{Model: choice.Model, PathStyle: style}); err != nil {
+		return err
+	}
+
+	if configured, source := config.PathStyle(); configured != 0 {
+		util.LogInfo("Saved path style %d, but path style %d set by %s is used", style, configured, source)
+		return nil
+	}
+
+	choice.Style = style
+	choice.Source = PathStyleFromDevice
+	pathStyleChoices[dev] = choice
+	return nil
+}
+
+// ProbePlaylist is a test playlist whose entries use one path style
+type ProbePlaylist struct {
+	Style int
+	Path  string
+}
+
+func probePlaylistName(style int) string {
+	return PlaylistFileName(fmt.Sprintf("%s%d", probePlaylistPrefix, style))
+}
+
+// RemoveProbePlaylists deletes the test playlists written by WriteProbePlaylists
+func RemoveProbePlaylists(dev *mtp.Device, storagesRaw interface{}) (int, error) {
+	playlists, err := GetPlaylists(dev, storagesRaw)
+	if err != nil {
+		return 0, fmt.Errorf("error getting playlists: %w", err)
+	}
+
+	removed := 0
+	for _, playlist := range playlists {
+		if !strings.HasPrefix(strings.ToUpper(playlist.Name), probePlaylistPrefix) {
+			continue
+		}
+
+		if err := deleteDeviceObject(dev, playlist.StorageID, playlist.ObjectID); err != nil {
+			return removed, fmt.Errorf("error deleting %s: %w", playlist.Path, err)
+		}
+		util.LogVerbose("Removed probe playlist %s", playlist.Path)
+		removed++
+	}
+	return removed, nil
+}
+
+// WriteProbePlaylists writes one playlist per path style, each listing the same few
+// songs, so the style that shows songs on the watch can be picked
+func WriteProbePlaylists(dev *mtp.Device, storagesRaw interface{}) ([]ProbePlaylist, error) {
+	if _, err := RemoveProbePlaylists(dev, storagesRaw); err != nil {
+		return nil, err
+	}
+
+	storageID, musicFolderID, err := SelectStorageAndMusicFolder(context.Background(), dev, storagesRaw)
+	if err != nil {
+		return nil, fmt.Errorf("error selecting storage: %w", err)
+	}
+
+	songs, err := GetSongs(dev, storagesRaw)
+	if err != nil {
+		return nil, fmt.Errorf("error getting songs: %w", err)
+	}
+
+	var songPaths []string
+	for _, song := range songs {
+		if song.StorageID == storageID && len(songPaths) < probeSongCount {
+			songPaths = append(songPaths, song.Path)
+		}
+	}
+	if len(songPaths) == 0 {
+		return nil, fmt.Errorf("upload at least one song before probing")
+	}
+
+	var probes []ProbePlaylist
+	for _, style := range util.PathStyles {
+		var entries []files.PlaylistEntry
+		for _, songPath := range songPaths {
+			entries = append(entries, files.PlaylistEntry{
+				Location: util.FormatPlaylistPath(songPath, style),
+				Title:    strings.ToUpper(util.ExtractTrackInfo(songPath)),
+				Seconds:  -1,
+			})
+		}
+
+		fileName := probePlaylistName(style)
+		if _, err := createPlaylistWithEntries(context.Background(), dev, storageID, musicFolderID, fileName, songPaths, entries); err != nil {
+			return probes, fmt.Errorf("error writing %s: %w", fileName, err)
+		}
+		probes = append(probes, ProbePlaylist{Style: style, Path: "/Music/" + fileName})
+	}
+
+	return probes, nil
+}
+
+// DisplayPathStyle shows the path style in use for the device
+func DisplayPathStyle(choice PathStyleChoice) {
+	fmt.Printf("Device: %s (serial %s)\n", choice.Model, choice.Serial)
+	fmt.Printf("Playlist path style: %d, %s (%s)\n", choice.Style, util.PathStyleExample(choice.Style), choice.Source)
+}
+
+// DisplayProbePlaylists explains how to pick a style from the probe playlists
+func DisplayProbePlaylists(probes []ProbePlaylist) {
+	fmt.Println("\n==== Probe Playlists ====")
+	for _, probe := range probes {
+		fmt.Printf("  %d. %s  %s\n", probe.Style, probe.Path, color.New(color.Faint).Sprint(util.PathStyleExample(probe.Style)))
+	}
+	fmt.Println("\nDisconnect the watch and open each probe playlist. Note the number of the one")
+	fmt.Println("that lists its songs, then reconnect and run: better-sync probe --select <number>")
+}
+
+// ProbePathStyle writes the probe playlists and asks which one showed songs
+func ProbePathStyle(dev *mtp.Device, storagesRaw interface{}) {
+	fmt.Println("\n=== Detect Playlist Path Style ===")
+	DisplayPathStyle(ResolvePathStyle(dev))
+
+	probes, err := WriteProbePlaylists(dev, storagesRaw)
+	if err != nil {
+		util.LogError("Error writing probe playlists: %v", err)
+		return
+	}
+	DisplayProbePlaylists(probes)
+
+	fmt.Print("\nIf you already know which playlist works, enter its number (Enter to decide later): ")
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Scan()
+	input := strings.TrimSpace(scanner.Text())
+	if input == "" {
+		return
+	}
+
+	var style int
+	if _, err := fmt.Sscanf(input, "%d", &style); err != nil {
+		util.LogError("Invalid path style: %s", input)
+		return
+	}
+	if err := SelectProbedPathStyle(dev, storagesRaw, style); err != nil {
+		util.LogError("%v", err)
+	}
+}
+
+// SelectProbedPathStyle remembers the chosen style and removes the probe playlists
+func SelectProbedPathStyle(dev *mtp.Device, storagesRaw interface{}, style int) error {
+	if err := SavePathStyle(dev, style); err != nil {
+		return err
+	}
+	color.HiGreen("✓ Playlists for this device will use path style %d (%s)", style, util.PathStyleExample(style))
+
+	removed, err := RemoveProbePlaylists(dev, storagesRaw)
+	if err != nil {
+		return err
+	}
+	if removed > 0 {
+		fmt.Printf("Removed %d probe playlists\n", removed)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+// protectedFolders are never pruned, even when empty
+var protectedFolders = map[string]bool{
+	"/":      true,
+	"/MUSIC": true,
+}
+
+// autoPrune controls whether empty folders are pruned after songs are deleted
+var autoPrune = true
+
+// SetAutoPrune enables or disables pruning empty folders after delete operations
+func SetAutoPrune(enabled bool) {
+	autoPrune = enabled
+}
+
+// PruneResult lists the folders removed by PruneEmptyFolders
+type PruneResult struct {
+	Checked int
+	Deleted []string
+	Failed  []string
+}
+
+func isProtectedFolder(path string) bool {
+	path = strings.TrimSuffix(strings.ToUpper(strings.ReplaceAll(path, "\\", "/")), "/")
+	if path == "" {
+		path = "/"
+	}
+	return protectedFolders[path]
+}
+
+// folderDepth counts the path elements of a folder, so children sort before parents
+func folderDepth(path string) int {
+	return strings.Count(strings.Trim(path, "/"), "/")
+}
+
+// pruneStorage deletes the empty folders below /Music on one storage, deepest first,
+// so an artist folder whose only album folder was empty is removed as well. The walk
+// skips objects it cannot read, so each folder is asked for its children again before
+// it is deleted; many devices delete a folder's contents along with it
+func pruneStorage(dev *mtp.Device, storageID uint32, result *PruneResult) error {
+	index, err := buildDeviceIndex(dev, storageID, "/Music")
+	if err != nil {
+		return err
+	}
+
+	children := make(map[uint32]int)
+	var folders []deviceObject
+	for _, object := range index.Objects {
+		children[object.ParentID]++
+		if object.IsDir {
+			folders = append(folders, object)
+		}
+	}
+
+	sort.SliceStable(folders, func(i, j int) bool {
+		return folderDepth(folders[i].Path) > folderDepth(folders[j].Path)
+	})
+
+	for _, folder := range folders {
+		result.Checked++
+		if children[folder.ObjectID] > 0 || isProtectedFolder(folder.Path) {
+			continue
+		}
+
+		handles := mtp.Uint32Array{}
+		if err := dev.GetObjectHandles(storageID, 0, folder.ObjectID, &handles); err != nil {
+			return fmt.Errorf("error listing %s before removing it: %w", folder.Path, err)
+		}
+		if len(handles.Values) > 0 {
+			util.LogVerbose("Keeping %s: the device still lists %d objects in it", folder.Path, len(handles.Values))
+			continue
+		}
+
+		util.LogVerbose("Removing empty folder %s (ID: %d)", folder.Path, folder.ObjectID)
+		if err := deleteDeviceObject(dev, storageID, folder.ObjectID); err != nil {
+			util.LogError("Error removing empty folder %s: %v", folder.Path, err)
+			result.Failed = append(result.Failed, folder.Path)
+			continue
+		}
+
+		children[folder.ParentID]--
+		result.Deleted = append(result.Deleted, folder.Path)
+	}
+
+	return nil
+}
+
+// PruneEmptyFolders walks the Music tree of every storage bottom-up and deletes the
+// folders that have no children, e.g. artist and album folders left by deleted songs
+func PruneEmptyFolders(dev *mtp.Device, storagesRaw interface{}) (*PruneResult, error) {
+	storagesValue := reflect.ValueOf(storagesRaw)
+	if storagesValue.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("invalid storages data format: not a slice")
+	}
+
+	result := &PruneResult{}
+	for i := 0; i < storagesValue.Len(); i++ {
+		storageID := extractUint32Field(storagesValue.Index(i).Interface(), "Sid")
+		if err := pruneStorage(dev, storageID, result); err != nil {
+			util.LogVerbose("Skipping storage %d: %v", storageID, err)
+		}
+	}
+
+	util.LogInfo("Removed %d empty folders of %d", len(result.Deleted), result.Checked)
+	if len(result.Failed) > 0 {
+		return result, fmt.Errorf("%d empty folders could not be removed", len(result.Failed))
+	}
+	return result, nil
+}
+
+func DisplayPruneResult(result *PruneResult) {
+	if len(result.Deleted) == 0 {
+		fmt.Println("No empty folders found.")
+		return
+	}
+
+	for _, folder := range result.Deleted {
+		fmt.Printf("  Removed %s\n", folder)
+	}
+	color.HiGreen("✓ Removed %d empty folders", len(result.Deleted))
+}
+
+// pruneAfterDelete removes the folders emptied by a delete operation, unless
+// automatic pruning was disabled
+func pruneAfterDelete(ctx context.Context, dev *mtp.Device, storagesRaw interface{}) {
+	if !autoPrune {
+		return
+	}
+
+	result, err := PruneEmptyFolders(dev, storagesRaw)
+	if err != nil {
+		util.LogError("Error pruning empty folders: %v", err)
+	}
+	if result != nil && len(result.Deleted) > 0 {
+		fmt.Fprintf(consoleOf(ctx), "Removed %d empty folders\n", len(result.Deleted))
+	}
+}
+
+// PruneFolders is the interactive menu entry for PruneEmptyFolders
+func PruneFolders(dev *mtp.Device, storagesRaw interface{}) {
+	fmt.Println("\n=== Prune Empty Folders ===")
+
+	result, err := PruneEmptyFolders(dev, storagesRaw)
+	if result != nil {
+		DisplayPruneResult(result)
+	}
+	if err != nil {
+		util.LogError("%v", err)
+	}
+}
@@ -0,0 +1,330 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/schachte/better-sync/pkg/files"
+	"github.com/schachte/better-sync/pkg/model"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+// PlaylistReference is a playlist with entries that resolve to songs being deleted
+type PlaylistReference struct {
+	Playlist model.PlaylistInfo
+	// Entries are the matching entries as written in the playlist
+	Entries []string
+	format  string
+	entries []files.PlaylistEntry
+	// matches maps the index of each matching entry to the object it resolves to
+	matches map[int]uint32
+}
+
+// FindPlaylistReferences reads every playlist and returns the ones with entries that
+// resolve to one of the given objects. Entries are resolved by exact path, the same
+// way FindObjectByPath does, so a song only matched by name in another folder is not
+// counted.
+func FindPlaylistReferences(dev *mtp.Device, storagesRaw interface{}, objectIDs []uint32) ([]PlaylistReference, error) {
+	targets := make(map[uint32]bool)
+	for _, objectID := range objectIDs {
+		targets[objectID] = true
+	}
+
+	playlists, err := GetPlaylists(dev, storagesRaw)
+	if err != nil {
+		return nil, fmt.Errorf("error getting playlists: %w", err)
+	}
+
+	indexes := newDeviceIndexes(dev, "/Music")
+	var references []PlaylistReference
+
+	for _, playlist := range playlists {
+		index, err := indexes.Get(playlist.StorageID)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := readObjectData(dev, playlist.ObjectID)
+		if err != nil {
+			util.LogError("Error reading playlist %s: %v", playlist.Path, err)
+			continue
+		}
+
+		reference := PlaylistReference{
+			Playlist: playlist,
+			format:   files.PlaylistFormatOf(playlist.Name, string(data)),
+			entries:  files.ParsePlaylist(playlist.Name, string(data)),
+			matches:  make(map[int]uint32),
+		}
+		for i, entry := range reference.entries {
+			location := strings.TrimSpace(entry.Location)
+			if object, ok := index.Lookup(location); ok && targets[object.ObjectID] {
+				reference.matches[i] = object.ObjectID
+				reference.Entries = append(reference.Entries, location)
+			}
+		}
+
+		if len(reference.matches) > 0 {
+			util.LogVerbose("Playlist %s references %d deleted entries", playlist.Path, len(reference.matches))
+			references = append(references, reference)
+		}
+	}
+
+	return references, nil
+}
+
+// RemovePlaylistReferences rewrites each referencing playlist without the matching
+// entries, keeping its format and the order of the remaining entries
+func RemovePlaylistReferences(dev *mtp.Device, references []PlaylistReference) error {
+	var failed []string
+	for i := range references {
+		reference := &references[i]
+
+		var kept []files.PlaylistEntry
+		for j, entry := range reference.entries {
+			if _, ok := reference.matches[j]; !ok {
+				kept = append(kept, entry)
+			}
+		}
+
+		content := files.FormatPlaylist(reference.format, kept)
+		objectID, err := replacePlaylistData(dev, reference.Playlist, []byte(content))
+		if err != nil {
+			util.LogError("Error updating playlist %s: %v", reference.Playlist.Path, err)
+			failed = append(failed, reference.Playlist.Path)
+			continue
+		}
+
+		reference.Playlist.ObjectID = objectID
+		util.LogInfo("Removed %d entries from playlist %s", len(reference.matches), reference.Playlist.Path)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d playlists could not be updated: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// referencesToDeleted narrows references to the entries of objects that were actually
+// deleted, so a song that could not be deleted stays in its playlists
+func referencesToDeleted(references []PlaylistReference, deleted map[uint32]bool) []PlaylistReference {
+	var kept []PlaylistReference
+	for _, reference := range references {
+		matches := make(map[int]uint32)
+		for i, objectID := range reference.matches {
+			if deleted[objectID] {
+				matches[i] = objectID
+			}
+		}
+		if len(matches) > 0 {
+			reference.matches = matches
+			kept = append(kept, reference)
+		}
+	}
+	return kept
+}
+
+// DisplayPlaylistReferences writes the playlists a deletion updates to w
+func DisplayPlaylistReferences(w io.Writer, references []PlaylistReference) {
+	if len(references) == 0 {
+		fmt.Fprintln(w, "No playlist references the songs being deleted.")
+		return
+	}
+
+	entryColor := color.New(color.Faint).SprintFunc()
+	fmt.Fprintf(w, "\nThese %d playlists will be updated:\n", len(references))
+	for _, reference := range references {
+		fmt.Fprintf(w, "  [%s] %s (%d of %d entries removed)\n", reference.Playlist.Storage, reference.Playlist.Path,
+			len(reference.matches), len(reference.entries))
+		for _, entry := range reference.Entries {
+			fmt.Fprintf(w, "      - %s\n", entryColor(entry))
+		}
+	}
+}
+
+// PlannedTrack is a song of a playlist being deleted, with why it is kept
+type PlannedTrack struct {
+	Entry    string
+	Path     string
+	ObjectID uint32
+	Reason   string
+	// SharedWith lists the other playlists that use the song
+	SharedWith []string
+}
+
+// PlaylistDeletionPlan lists what deleting a playlist and its songs will remove
+type PlaylistDeletionPlan struct {
+	Playlist model.PlaylistInfo
+	Delete   []PlannedTrack
+	Keep     []PlannedTrack
+}
+
+// PlanPlaylistDeletion counts how many playlists use each song of the named playlist,
+// matching their entries by exact path or, for stale entries, by the most similar song.
+// Only songs that no other playlist uses, and whose entry resolves to a song by its
+// exact path, are deleted; every other entry is kept with the reason. If another
+// playlist cannot be read the plan is refused, since its songs are unknown, and so is
+// a plan whose playlists were not all read before ctx asked to stop.
+func PlanPlaylistDeletion(ctx context.Context, dev *mtp.Device, storagesRaw interface{}, playlistName string) (*PlaylistDeletionPlan, error) {
+	playlists, err := GetPlaylists(dev, storagesRaw)
+	if err != nil {
+		return nil, fmt.Errorf("error getting playlists: %w", err)
+	}
+
+	target := findPlaylist(playlists, playlistName)
+	if target == nil {
+		util.LogError("Playlist '%s' not found", playlistName)
+		return nil, fmt.Errorf("playlist '%s' not found", playlistName)
+	}
+
+	indexes := newDeviceIndexes(dev, "/Music")
+	usedBy := make(map[uint32][]string)
+	var targetEntries []files.PlaylistEntry
+
+	for _, playlist := range playlists {
+		if stopRequested(ctx) {
+			return nil, fmt.Errorf("deletion plan %w before reading %s", ErrInterrupted, playlist.Path)
+		}
+		index, err := indexes.Get(playlist.StorageID)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := readObjectData(dev, playlist.ObjectID)
+		if err != nil {
+			return nil, fmt.Errorf("could not read playlist %s, so its songs cannot be protected: %w", playlist.Path, err)
+		}
+
+		entries := files.ParsePlaylist(playlist.Name, string(data))
+		if playlist.ObjectID == target.ObjectID {
+			targetEntries = entries
+			continue
+		}
+
+		// A stale entry still protects the song it most likely means, so another
+		// playlist never loses a song it points at with a different case or prefix
+		seen := make(map[uint32]bool)
+		for _, entry := range entries {
+			location := strings.TrimSpace(entry.Location)
+			object, ok := index.Lookup(location)
+			if !ok {
+				object, _, ok = index.FindSimilar(location)
+			}
+			if ok && !seen[object.ObjectID] {
+				seen[object.ObjectID] = true
+				usedBy[object.ObjectID] = append(usedBy[object.ObjectID], playlist.Name)
+			}
+		}
+	}
+
+	plan := &PlaylistDeletionPlan{Playlist: *target}
+	index, err := indexes.Get(target.StorageID)
+	if err != nil {
+		return nil, err
+	}
+
+	planned := make(map[uint32]bool)
+	for _, entry := range targetEntries {
+		location := strings.TrimSpace(entry.Location)
+		track := PlannedTrack{Entry: location}
+
+		object, ok := index.Lookup(location)
+		if !ok {
+			track.Reason = "no song at this exact path"
+			plan.Keep = append(plan.Keep, track)
+			continue
+		}
+		if planned[object.ObjectID] {
+			continue
+		}
+		planned[object.ObjectID] = true
+
+		track.Path = object.Path
+		track.ObjectID = object.ObjectID
+		if others := usedBy[object.ObjectID]; len(others) > 0 {
+			track.SharedWith = others
+			track.Reason = "also in " + strings.Join(others, ", ")
+			plan.Keep = append(plan.Keep, track)
+			continue
+		}
+		plan.Delete = append(plan.Delete, track)
+	}
+
+	util.LogInfo("Deleting playlist %s: %d songs to delete, %d kept", target.Path, len(plan.Delete), len(plan.Keep))
+	return plan, nil
+}
+
+func DisplayPlaylistDeletionPlan(plan *PlaylistDeletionPlan) {
+	deleteColor := color.New(color.FgHiRed).SprintFunc()
+	keepColor := color.New(color.FgHiGreen).SprintFunc()
+	reasonColor := color.New(color.Faint).SprintFunc()
+
+	fmt.Printf("\nPlaylist %s\n", plan.Playlist.Path)
+
+	if len(plan.Delete) > 0 {
+		fmt.Printf("\n%d songs will be deleted:\n", len(plan.Delete))
+		for _, track := range plan.Delete {
+			fmt.Printf("  %s %s\n", deleteColor("✗"), track.Path)
+		}
+	} else {
+		fmt.Println("\nNo songs will be deleted.")
+	}
+
+	if len(plan.Keep) > 0 {
+		fmt.Printf("\n%d entries will be kept:\n", len(plan.Keep))
+		for _, track := range plan.Keep {
+			fmt.Printf("  %s %s %s\n", keepColor("✓"), track.Entry, reasonColor("("+track.Reason+")"))
+		}
+	}
+}
+
+// ExecutePlaylistDeletion deletes the planned songs and then the playlist itself, and
+// returns the paths of the songs that were deleted. Once ctx asks to stop, the
+// remaining songs and the playlist are kept and the error wraps ErrInterrupted.
+func ExecutePlaylistDeletion(ctx context.Context, dev *mtp.Device, storagesRaw interface{}, plan *PlaylistDeletionPlan) ([]string, error) {
+	out := consoleOf(ctx)
+	var deletedSongs []string
+	for i, track := range plan.Delete {
+		if stopRequested(ctx) {
+			return deletedSongs, fmt.Errorf("deletion %w: %d songs and playlist '%s' were not deleted",
+				ErrInterrupted, len(plan.Delete)-i, plan.Playlist.Path)
+		}
+
+		untrash, err := trashObject(dev, plan.Playlist.StorageID, track.ObjectID, track.Path)
+		if err != nil {
+			util.LogError("Skipping song '%s': %v", track.Path, err)
+			fmt.Fprintf(out, "Failed to delete song: %s\n", track.Path)
+			continue
+		}
+		if err := deleteDeviceObject(dev, plan.Playlist.StorageID, track.ObjectID); err != nil {
+			untrash()
+			util.LogError("Failed to delete song '%s' (ID: %d): %v", track.Path, track.ObjectID, err)
+			fmt.Fprintf(out, "Failed to delete song: %s\n", track.Path)
+			continue
+		}
+		util.LogInfo("Deleted song: %s (ID: %d)", track.Path, track.ObjectID)
+		fmt.Fprintf(out, "Deleted song: %s\n", track.Path)
+		deletedSongs = append(deletedSongs, track.Path)
+	}
+
+	untrash, err := trashObject(dev, plan.Playlist.StorageID, plan.Playlist.ObjectID, plan.Playlist.Path)
+	if err != nil {
+		return deletedSongs, fmt.Errorf("playlist '%s' was not deleted: %w", plan.Playlist.Path, err)
+	}
+	if err := deleteDeviceObject(dev, plan.Playlist.StorageID, plan.Playlist.ObjectID); err != nil {
+		untrash()
+		return deletedSongs, fmt.Errorf("failed to delete playlist '%s' (ID: %d): %w",
+			plan.Playlist.Path, plan.Playlist.ObjectID, err)
+	}
+
+	util.LogInfo("Deleted playlist: %s (ID: %d)", plan.Playlist.Path, plan.Playlist.ObjectID)
+	fmt.Fprintf(out, "Successfully deleted playlist '%s' and %d/%d songs (%d kept)\n",
+		plan.Playlist.Name, len(deletedSongs), len(plan.Delete), len(plan.Keep))
+
+	pruneAfterDelete(ctx, dev, storagesRaw)
+	return deletedSongs, nil
+}
@@ -0,0 +1,467 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/ganeshrvel/go-mtpx"
+	"github.com/schachte/better-sync/pkg/model"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+// DeviceEntry is a file or folder anywhere on a storage, not only below /Music
+type DeviceEntry struct {
+	Name     string
+	Path     string
+	ObjectID uint32
+	Size     int64
+	ModTime  time.Time
+	IsDir    bool
+}
+
+// TransferResult counts what a get or put copied, and lists the files that failed
+type TransferResult struct {
+	Files   int
+	Folders int
+	Bytes   int64
+	Failed  []string
+	// NotTransferred are the files left alone because the transfer was interrupted
+	NotTransferred []string
+}
+
+// CleanDevicePath resolves a path typed in the shell against the current folder
+func CleanDevicePath(cwd, devicePath string) string {
+	devicePath = strings.ReplaceAll(strings.TrimSpace(devicePath), "\\", "/")
+	if !strings.HasPrefix(devicePath, "/") {
+		devicePath = path.Join(cwd, devicePath)
+	}
+	return path.Clean("/" + devicePath)
+}
+
+func deviceEntryOf(devicePath string, fi *mtpx.FileInfo) DeviceEntry {
+	entry := DeviceEntry{
+		Name:     fi.Name,
+		Path:     devicePath,
+		ObjectID: fi.ObjectId,
+		Size:     fi.Size,
+		ModTime:  fi.ModTime,
+		IsDir:    fi.IsDir,
+	}
+	if devicePath == "/" {
+		entry.ObjectID = PARENT_ROOT
+	} else {
+		entry.Path = path.Join(path.Dir(devicePath), fi.Name)
+	}
+	return entry
+}
+
+// StatDevicePath looks up the file or folder at a path; names match regardless of case
+func StatDevicePath(dev *mtp.Device, storageID uint32, devicePath string) (*DeviceEntry, error) {
+	devicePath = CleanDevicePath("/", devicePath)
+	fi, err := mtpx.GetObjectFromPath(dev, storageID, devicePath)
+	if err != nil {
+		var notFound mtpx.InvalidPathError
+		if errors.As(err, &notFound) {
+			return nil, fmt.Errorf("%s: no such file or folder", devicePath)
+		}
+		return nil, fmt.Errorf("error looking up %s: %w", devicePath, err)
+	}
+
+	entry := deviceEntryOf(devicePath, fi)
+	return &entry, nil
+}
+
+// statFolder looks up a path that must be a folder
+func statFolder(dev *mtp.Device, storageID uint32, devicePath string) (*DeviceEntry, error) {
+	entry, err := StatDevicePath(dev, storageID, devicePath)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.IsDir {
+		return nil, fmt.Errorf("%s is not a folder", entry.Path)
+	}
+	return entry, nil
+}
+
+// ListDevicePath lists a folder, folders first, or returns the file itself
+func ListDevicePath(dev *mtp.Device, storageID uint32, devicePath string) ([]DeviceEntry, error) {
+	entry, err := StatDevicePath(dev, storageID, devicePath)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.IsDir {
+		return []DeviceEntry{*entry}, nil
+	}
+
+	var entries []DeviceEntry
+	_, _, _, err = mtpx.Walk(dev, storageID, entry.Path, false, false, false,
+		func(objectID uint32, fi *mtpx.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			entries = append(entries, deviceEntryOf(path.Join(entry.Path, fi.Name), fi))
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("error listing %s: %w", entry.Path, err)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].IsDir != entries[j].IsDir {
+			return entries[i].IsDir
+		}
+		return strings.ToLower(entries[i].Name) < strings.ToLower(entries[j].Name)
+	})
+	return entries, nil
+}
+
+// DisplayDeviceEntries prints a listing with sizes and modification dates
+func DisplayDeviceEntries(entries []DeviceEntry) {
+	folderColor := color.New(color.FgHiBlue, color.Bold).SprintFunc()
+	var total int64
+	for _, entry := range entries {
+		date := "                "
+		if !entry.ModTime.IsZero() {
+			date = entry.ModTime.Format("2006-01-02 15:04")
+		}
+		if entry.IsDir {
+			fmt.Printf("%10s  %s  %s/\n", "-", date, folderColor(entry.Name))
+			continue
+		}
+		fmt.Printf("%10s  %s  %s\n", util.FormatSize(entry.Size), date, entry.Name)
+		total += entry.Size
+	}
+	fmt.Printf("%d entries, %s in files\n", len(entries), util.FormatSize(total))
+}
+
+// DownloadDevicePath copies a file or a whole folder from the device. When localPath is
+// an existing folder the copy is made inside it, otherwise it is created at localPath.
+// Once ctx asks to stop, the remaining files are left on the device.
+func DownloadDevicePath(ctx context.Context, dev *mtp.Device, storageID uint32, devicePath, localPath string) (*TransferResult, error) {
+	entry, err := StatDevicePath(dev, storageID, devicePath)
+	if err != nil {
+		return nil, err
+	}
+
+	target := localPath
+	if info, err := os.Stat(localPath); err == nil && info.IsDir() && entry.Path != "/" {
+		target = filepath.Join(localPath, entry.Name)
+	}
+
+	result := &TransferResult{}
+	if !entry.IsDir {
+		if err := downloadObject(ctx, dev, entry.ObjectID, entry.Size, target); err != nil {
+			return result, err
+		}
+		result.Files++
+		result.Bytes += entry.Size
+		return result, nil
+	}
+
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return result, fmt.Errorf("error creating directory: %w", err)
+	}
+	result.Folders++
+
+	prefix := strings.TrimSuffix(entry.Path, "/") + "/"
+	_, _, _, err = mtpx.Walk(dev, storageID, entry.Path, true, false, false,
+		func(objectID uint32, fi *mtpx.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			localFile := filepath.Join(target, filepath.FromSlash(strings.TrimPrefix(fi.FullPath, prefix)))
+
+			if fi.IsDir {
+				if err := os.MkdirAll(localFile, 0755); err != nil {
+					return fmt.Errorf("error creating directory: %w", err)
+				}
+				result.Folders++
+				return nil
+			}
+			if stopRequested(ctx) || ctx.Err() != nil {
+				result.NotTransferred = append(result.NotTransferred, fi.FullPath)
+				return nil
+			}
+
+			if err := downloadObject(ctx, dev, objectID, fi.Size, localFile); err != nil {
+				if ctx.Err() != nil {
+					result.NotTransferred = append(result.NotTransferred, fi.FullPath)
+					return nil
+				}
+				util.LogError("Failed to download %s: %v", fi.FullPath, err)
+				result.Failed = append(result.Failed, fi.FullPath)
+				return nil
+			}
+			result.Files++
+			result.Bytes += fi.Size
+			return nil
+		})
+	if err != nil {
+		return result, fmt.Errorf("error walking %s: %w", entry.Path, err)
+	}
+	return result, transferError("download", result)
+}
+
+// transferError turns the failures recorded in a transfer result into one error
+func transferError(action string, result *TransferResult) error {
+	if len(result.NotTransferred) > 0 {
+		return fmt.Errorf("%s %w: %d files were not transferred", action, ErrInterrupted, len(result.NotTransferred))
+	}
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("%s finished with %d errors", action, len(result.Failed))
+	}
+	return nil
+}
+
+// objectFormatForFile picks the MTP object format for an uploaded file
+func objectFormatForFile(name string) uint16 {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".mp3":
+		return 0xB901
+	case ".m3u", ".m3u8", ".pls":
+		return 0xBA05
+	case ".jpg", ".jpeg", ".png", ".gif", ".bmp":
+		return getMTPFormatByExtension(filepath.Ext(name))
+	}
+	return mtp.OFC_Undefined
+}
+
+// putFile uploads one local file into a device folder, refusing to replace a file
+func putFile(ctx context.Context, dev *mtp.Device, storageID, parentID uint32, deviceDir, localPath string, result *TransferResult) error {
+	name := filepath.Base(localPath)
+	devicePath := path.Join(deviceDir, name)
+	if existing, err := StatDevicePath(dev, storageID, devicePath); err == nil {
+		return fmt.Errorf("%s already exists on the device", existing.Path)
+	}
+
+	if _, err := sendFile(ctx, dev, storageID, parentID, devicePath, localPath, objectFormatForFile(name), false); err != nil {
+		return err
+	}
+	if info, err := os.Stat(localPath); err == nil {
+		result.Bytes += info.Size()
+	}
+	result.Files++
+	return nil
+}
+
+// UploadLocalPath copies a local file, or a folder with everything in it, into a folder
+// on the device. Existing files are not replaced. Cancelling ctx aborts the file in
+// progress; with WithGracefulStop it finishes first.
+func UploadLocalPath(ctx context.Context, dev *mtp.Device, storageID uint32, localPath, deviceDir string) (*TransferResult, error) {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return nil, err
+	}
+	folder, err := statFolder(dev, storageID, deviceDir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TransferResult{}
+	if !info.IsDir() {
+		return result, putFile(ctx, dev, storageID, folder.ObjectID, folder.Path, localPath, result)
+	}
+
+	root := filepath.Clean(localPath)
+	folderIDs := map[string]uint32{}
+	folderPaths := map[string]string{}
+	err = filepath.WalkDir(root, func(localFile string, d fs.DirEntry, err error) error {
+		if err != nil {
+			util.LogError("Skipping %s: %v", localFile, err)
+			return nil
+		}
+
+		parent := filepath.Dir(localFile)
+		parentID, parentPath := folderIDs[parent], folderPaths[parent]
+		if localFile == root {
+			parentID, parentPath = folder.ObjectID, folder.Path
+		}
+
+		if d.IsDir() {
+			folderID, err := findOrCreateFolder(dev, storageID, parentID, d.Name())
+			if err != nil {
+				return err
+			}
+			folderIDs[localFile] = folderID
+			folderPaths[localFile] = path.Join(parentPath, d.Name())
+			result.Folders++
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if stopRequested(ctx) || ctx.Err() != nil {
+			result.NotTransferred = append(result.NotTransferred, localFile)
+			return nil
+		}
+
+		if err := putFile(ctx, dev, storageID, parentID, parentPath, localFile, result); err != nil {
+			util.LogError("Failed to upload %s: %v", localFile, err)
+			result.Failed = append(result.Failed, localFile)
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	return result, transferError("upload", result)
+}
+
+// DisplayTransferResult prints the summary of a get or put
+func DisplayTransferResult(result *TransferResult) {
+	fmt.Printf("Copied %d files (%s)", result.Files, util.FormatSize(result.Bytes))
+	if result.Folders > 0 {
+		fmt.Printf(" in %d folders", result.Folders)
+	}
+	fmt.Println()
+	if len(result.NotTransferred) > 0 {
+		color.HiYellow("Stopped: %d files not copied (interrupted)", len(result.NotTransferred))
+	}
+	if len(result.Failed) > 0 {
+		color.HiRed("Failed:  %d files", len(result.Failed))
+		for _, failed := range result.Failed {
+			fmt.Printf("  %s\n", failed)
+		}
+	}
+}
+
+// DeleteDevicePath deletes a file, or a folder with everything in it. The root folder
+// and /Music itself are refused; use wipe to empty the Music folder.
+func DeleteDevicePath(ctx context.Context, dev *mtp.Device, storageID uint32, devicePath string) (*DeviceEntry, error) {
+	entry, err := StatDevicePath(dev, storageID, devicePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.IsDir {
+		return entry, DeleteFolderRecursively(ctx, dev, storageID, entry.ObjectID, entry.Path, true)
+	}
+	untrash, err := trashObject(dev, storageID, entry.ObjectID, entry.Path)
+	if err != nil {
+		return entry, err
+	}
+	if err := deleteWithRetries(dev, storageID, entry.ObjectID, entry.Path); err != nil {
+		untrash()
+		return entry, err
+	}
+	return entry, nil
+}
+
+// MakeDeviceFolder creates a folder and any missing folders above it
+func MakeDeviceFolder(dev *mtp.Device, storageID uint32, devicePath string) (*DeviceEntry, error) {
+	devicePath = CleanDevicePath("/", devicePath)
+	if existing, err := StatDevicePath(dev, storageID, devicePath); err == nil {
+		return nil, fmt.Errorf("%s already exists", existing.Path)
+	}
+
+	parentID := PARENT_ROOT
+	current := "/"
+	for _, name := range strings.Split(strings.Trim(devicePath, "/"), "/") {
+		current = path.Join(current, name)
+		if existing, err := StatDevicePath(dev, storageID, current); err == nil {
+			if !existing.IsDir {
+				return nil, fmt.Errorf("%s is not a folder", existing.Path)
+			}
+			parentID = existing.ObjectID
+			continue
+		}
+
+		folderID, err := util.CreateFolder(dev, storageID, parentID, name)
+		if err != nil {
+			return nil, fmt.Errorf("error creating %s: %w", current, err)
+		}
+		util.LogInfo("Created folder %s (ID: %d)", current, folderID)
+		parentID = folderID
+	}
+
+	return &DeviceEntry{Name: path.Base(devicePath), Path: devicePath, ObjectID: parentID, IsDir: true}, nil
+}
+
+// deviceSupports reports whether the device implements an MTP operation
+func deviceSupports(dev *mtp.Device, operation uint16) bool {
+	deviceInfo := mtp.DeviceInfo{}
+	if err := dev.GetDeviceInfo(&deviceInfo); err != nil {
+		util.LogVerbose("Could not read device info: %v", err)
+		return false
+	}
+	for _, op := range deviceInfo.OperationsSupported {
+		if op == operation {
+			return true
+		}
+	}
+	return false
+}
+
+// MoveDevicePath moves or renames a file or folder. A target that is an existing folder
+// receives the source under its own name. Renaming needs a device that can change the
+// filename property, and moving one that implements MoveObject.
+func MoveDevicePath(dev *mtp.Device, storageID uint32, source, target string) (*DeviceEntry, error) {
+	entry, err := StatDevicePath(dev, storageID, source)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Path == "/" {
+		return nil, fmt.Errorf("cannot move the root folder")
+	}
+
+	target = CleanDevicePath("/", target)
+	parentPath, name := path.Dir(target), path.Base(target)
+	if existing, err := StatDevicePath(dev, storageID, target); err == nil {
+		if !existing.IsDir {
+			return nil, fmt.Errorf("%s already exists", existing.Path)
+		}
+		parentPath, name = existing.Path, entry.Name
+	}
+	parent, err := statFolder(dev, storageID, parentPath)
+	if err != nil {
+		return nil, err
+	}
+	if entry.IsDir && (parent.Path == entry.Path || strings.HasPrefix(strings.ToLower(parent.Path), strings.ToLower(entry.Path)+"/")) {
+		return nil, fmt.Errorf("cannot move %s into itself", entry.Path)
+	}
+
+	moved := *entry
+	moved.Name = name
+	moved.Path = path.Join(parent.Path, name)
+
+	if !strings.EqualFold(parent.Path, path.Dir(entry.Path)) {
+		if !deviceSupports(dev, mtp.OC_MoveObject) {
+			return nil, fmt.Errorf("the device cannot move objects between folders; get and put %s instead", entry.Path)
+		}
+
+		var req, rep mtp.Container
+		req.Code = mtp.OC_MoveObject
+		req.Param = []uint32{entry.ObjectID, storageID, parent.ObjectID}
+		if err := dev.RunTransaction(&req, &rep, nil, nil, 0, model.EmptyProgressFunc); err != nil {
+			return nil, fmt.Errorf("error moving %s: %w", entry.Path, err)
+		}
+		util.LogInfo("Moved %s to %s", entry.Path, parent.Path)
+	}
+
+	if name != entry.Name {
+		info, err := util.GetObjectInfoWithRetry(dev, entry.ObjectID)
+		if err != nil {
+			return nil, fmt.Errorf("error getting info for %s: %w", entry.Path, err)
+		}
+		if !canRenameObject(dev, info.ObjectFormat) {
+			return nil, fmt.Errorf("the device cannot rename %s", entry.Path)
+		}
+		err = dev.SetObjectPropValue(entry.ObjectID, mtp.OPC_ObjectFileName, &mtp.StringValue{Value: name})
+		if err != nil {
+			return nil, fmt.Errorf("error renaming %s: %w", entry.Path, err)
+		}
+		util.LogInfo("Renamed %s to %s", entry.Path, name)
+	}
+
+	return &moved, nil
+}
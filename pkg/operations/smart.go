@@ -0,0 +1,576 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/schachte/better-sync/pkg/files"
+	"github.com/schachte/better-sync/pkg/model"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+const (
+	SmartStatusCreated   = "created"
+	SmartStatusUpdated   = "updated"
+	SmartStatusUnchanged = "unchanged"
+	SmartStatusEmpty     = "no matching tracks"
+	SmartStatusFailed    = "failed"
+
+	// SmartStatusCleared marks an existing playlist emptied because no track matches any more
+	SmartStatusCleared = "cleared"
+)
+
+var smartTextFields = map[string]bool{
+	"artist": true,
+	"album":  true,
+	"title":  true,
+	"genre":  true,
+	"folder": true,
+}
+
+var smartNumericFields = map[string]bool{
+	"year":     true,
+	"duration": true,
+	"added":    true,
+}
+
+var smartSortFields = map[string]bool{
+	"artist":   true,
+	"album":    true,
+	"title":    true,
+	"genre":    true,
+	"path":     true,
+	"year":     true,
+	"duration": true,
+	"added":    true,
+	"size":     true,
+}
+
+// Longer operators first so that ">=" is not read as ">"
+var smartOperators = []string{"!=", "!~", ">=", "<=", "=", "~", ">", "<"}
+
+// SmartRefreshResult describes what happened to one smart playlist during a refresh
+type SmartRefreshResult struct {
+	Name   string
+	Path   string
+	Tracks int
+	Status string
+	Error  string
+}
+
+// ParseSmartRule parses expressions such as artist=Foo, folder~RUNNING, year>=2020,
+// duration<5:00 or added>=30d
+func ParseSmartRule(expr string) (model.SmartRule, error) {
+	for i := 0; i < len(expr); i++ {
+		for _, op := range smartOperators {
+			if !strings.HasPrefix(expr[i:], op) {
+				continue
+			}
+
+			rule := model.SmartRule{
+				Field:    strings.ToLower(strings.TrimSpace(expr[:i])),
+				Operator: op,
+				Value:    strings.TrimSpace(expr[i+len(op):]),
+			}
+			if err := validateSmartRule(rule); err != nil {
+				return model.SmartRule{}, fmt.Errorf("invalid rule %q: %w", expr, err)
+			}
+			return rule, nil
+		}
+	}
+
+	return model.SmartRule{}, fmt.Errorf("invalid rule %q: expected <field><operator><value>", expr)
+}
+
+func validateSmartRule(rule model.SmartRule) error {
+	if rule.Value == "" {
+		return fmt.Errorf("missing value")
+	}
+
+	switch {
+	case smartTextFields[rule.Field]:
+		if rule.Operator != "=" && rule.Operator != "!=" && rule.Operator != "~" && rule.Operator != "!~" {
+			return fmt.Errorf("%s only supports =, !=, ~ and !~", rule.Field)
+		}
+	case smartNumericFields[rule.Field]:
+		if rule.Operator == "~" || rule.Operator == "!~" {
+			return fmt.Errorf("%s does not support %s", rule.Field, rule.Operator)
+		}
+		if _, err := smartRuleNumber(rule, time.Now()); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown field %q", rule.Field)
+	}
+
+	return nil
+}
+
+// FormatSmartRule renders a rule in the same form ParseSmartRule accepts
+func FormatSmartRule(rule model.SmartRule) string {
+	return rule.Field + rule.Operator + rule.Value
+}
+
+// parseTrackDuration accepts plain seconds, m:ss or Go duration strings such as 4m30s
+func parseTrackDuration(value string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	if parts := strings.Split(value, ":"); len(parts) == 2 {
+		minutes, errMin := strconv.Atoi(parts[0])
+		seconds, errSec := strconv.Atoi(parts[1])
+		if errMin == nil && errSec == nil {
+			return time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second, nil
+		}
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return duration, nil
+}
+
+// parseAddedDate accepts a YYYY-MM-DD date or an age such as 30d, 2w or 12h
+func parseAddedDate(value string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+
+	age, err := ParseAge(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: use YYYY-MM-DD or an age like 30d", value)
+	}
+	return now.Add(-age), nil
+}
+
+// ParseAge parses ages such as 30d, 2w or 12h
+func ParseAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < 2 {
+		return 0, fmt.Errorf("invalid age %q", value)
+	}
+
+	unit := value[len(value)-1]
+	count, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || count < 0 {
+		return 0, fmt.Errorf("invalid age %q", value)
+	}
+
+	switch unit {
+	case 'h':
+		return time.Duration(count) * time.Hour, nil
+	case 'd':
+		return time.Duration(count) * 24 * time.Hour, nil
+	case 'w':
+		return time.Duration(count) * 7 * 24 * time.Hour, nil
+	default:
+		return 0, fmt.Errorf("invalid age %q: use h, d or w", value)
+	}
+}
+
+// smartRuleNumber converts the rule value to a comparable number for numeric fields
+func smartRuleNumber(rule model.SmartRule, now time.Time) (int64, error) {
+	switch rule.Field {
+	case "year":
+		year, err := strconv.Atoi(rule.Value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid year %q", rule.Value)
+		}
+		return int64(year), nil
+	case "duration":
+		duration, err := parseTrackDuration(rule.Value)
+		return int64(duration), err
+	case "added":
+		added, err := parseAddedDate(rule.Value, now)
+		return added.Unix(), err
+	}
+	return 0, fmt.Errorf("%s is not numeric", rule.Field)
+}
+
+func trackNumber(track model.Track, field string) (int64, bool) {
+	switch field {
+	case "year":
+		return int64(track.Year), track.Year != 0
+	case "duration":
+		return int64(track.Duration), track.Duration != 0
+	case "added":
+		return track.Added.Unix(), !track.Added.IsZero()
+	}
+	return 0, false
+}
+
+func trackText(track model.Track, field string) string {
+	switch field {
+	case "artist":
+		return track.Artist
+	case "album":
+		return track.Album
+	case "title":
+		return track.Title
+	case "genre":
+		return track.Genre
+	case "folder":
+		return trackFolder(track)
+	case "path":
+		return track.Path
+	}
+	return ""
+}
+
+func matchSmartRule(track model.Track, rule model.SmartRule, now time.Time) bool {
+	if smartTextFields[rule.Field] {
+		value := strings.ToUpper(trackText(track, rule.Field))
+		expected := strings.ToUpper(rule.Value)
+
+		equal := value == expected
+		if rule.Field == "folder" {
+			// A folder rule also matches everything below that folder
+			expected = strings.TrimSuffix(normalizePath(expected), "/")
+			value = normalizePath(value)
+			equal = value == expected || strings.HasPrefix(value, expected+"/")
+		}
+
+		switch rule.Operator {
+		case "=":
+			return equal
+		case "!=":
+			return !equal
+		case "~":
+			return strings.Contains(value, expected)
+		case "!~":
+			return !strings.Contains(value, expected)
+		}
+		return false
+	}
+
+	value, known := trackNumber(track, rule.Field)
+	expected, err := smartRuleNumber(rule, now)
+	if err != nil {
+		return false
+	}
+	if !known {
+		// Tracks without the value only match rules that exclude something
+		return rule.Operator == "!="
+	}
+
+	switch rule.Operator {
+	case "=":
+		return value == expected
+	case "!=":
+		return value != expected
+	case ">":
+		return value > expected
+	case ">=":
+		return value >= expected
+	case "<":
+		return value < expected
+	case "<=":
+		return value <= expected
+	}
+	return false
+}
+
+func compareTracks(a, b model.Track, field string) int {
+	if smartNumericFields[field] {
+		av, _ := trackNumber(a, field)
+		bv, _ := trackNumber(b, field)
+		switch {
+		case av < bv:
+			return -1
+		case av > bv:
+			return 1
+		}
+		return 0
+	}
+
+	if field == "size" {
+		switch {
+		case a.Size < b.Size:
+			return -1
+		case a.Size > b.Size:
+			return 1
+		}
+		return 0
+	}
+
+	return strings.Compare(strings.ToUpper(trackText(a, field)), strings.ToUpper(trackText(b, field)))
+}
+
+// ParseSmartSort validates a sort key such as artist or -added
+func ParseSmartSort(sortBy string) error {
+	field := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(sortBy)), "-")
+	if field != "" && !smartSortFields[field] {
+		return fmt.Errorf("unknown sort field %q", field)
+	}
+	return nil
+}
+
+// EvaluateSmartPlaylist filters, sorts and limits tracks according to a definition
+func EvaluateSmartPlaylist(definition model.SmartPlaylist, tracks []model.Track) []model.Track {
+	now := time.Now()
+	var matched []model.Track
+
+	for _, track := range tracks {
+		include := !definition.MatchAny || len(definition.Rules) == 0
+		for _, rule := range definition.Rules {
+			ok := matchSmartRule(track, rule, now)
+			if definition.MatchAny && ok {
+				include = true
+				break
+			}
+			if !definition.MatchAny && !ok {
+				include = false
+				break
+			}
+		}
+
+		if include {
+			matched = append(matched, track)
+		}
+	}
+
+	sortBy := strings.ToLower(strings.TrimSpace(definition.Sort))
+	descending := strings.HasPrefix(sortBy, "-")
+	sortBy = strings.TrimPrefix(sortBy, "-")
+	if sortBy == "" {
+		sortBy = "path"
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		result := compareTracks(matched[i], matched[j], sortBy)
+		if result == 0 {
+			return matched[i].Path < matched[j].Path
+		}
+		if descending {
+			return result > 0
+		}
+		return result < 0
+	})
+
+	if definition.Limit > 0 && len(matched) > definition.Limit {
+		matched = matched[:definition.Limit]
+	}
+
+	return matched
+}
+
+// AddSmartPlaylist stores a new definition, replacing one with the same name
+func AddSmartPlaylist(definition model.SmartPlaylist) error {
+	if strings.TrimSpace(definition.Name) == "" {
+		return fmt.Errorf("smart playlist name is required")
+	}
+	if err := ParseSmartSort(definition.Sort); err != nil {
+		return err
+	}
+	if definition.Format != "" {
+		format, err := files.ParsePlaylistFormat(definition.Format)
+		if err != nil {
+			return err
+		}
+		definition.Format = format
+	}
+	for _, rule := range definition.Rules {
+		if err := validateSmartRule(rule); err != nil {
+			return fmt.Errorf("invalid rule %q: %w", FormatSmartRule(rule), err)
+		}
+	}
+
+	definitions, err := files.LoadSmartPlaylists()
+	if err != nil {
+		return err
+	}
+
+	key := playlistKey(PlaylistFileName(definition.Name))
+	replaced := false
+	for i, existing := range definitions {
+		if playlistKey(PlaylistFileName(existing.Name)) == key {
+			definitions[i] = definition
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		definitions = append(definitions, definition)
+	}
+
+	return files.SaveSmartPlaylists(definitions)
+}
+
+// RemoveSmartPlaylist deletes a stored definition; the playlist on the device is left alone
+func RemoveSmartPlaylist(name string) error {
+	definitions, err := files.LoadSmartPlaylists()
+	if err != nil {
+		return err
+	}
+
+	key := playlistKey(PlaylistFileName(name))
+	for i, existing := range definitions {
+		if playlistKey(PlaylistFileName(existing.Name)) == key {
+			definitions = append(definitions[:i], definitions[i+1:]...)
+			return files.SaveSmartPlaylists(definitions)
+		}
+	}
+
+	return fmt.Errorf("smart playlist '%s' not found", name)
+}
+
+// RefreshSmartPlaylists regenerates smart playlists on the device. Only playlists whose
+// content changed are rewritten; one whose rules no longer match any track is emptied.
+// An empty names list refreshes every definition. Once ctx asks to stop the remaining
+// playlists are left as they are, and the error wraps ErrInterrupted next to the
+// results so far.
+func RefreshSmartPlaylists(ctx context.Context, dev *mtp.Device, storagesRaw interface{}, names []string) ([]SmartRefreshResult, error) {
+	definitions, err := files.LoadSmartPlaylists()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(names) > 0 {
+		var selected []model.SmartPlaylist
+		for _, name := range names {
+			key := playlistKey(PlaylistFileName(name))
+			found := false
+			for _, definition := range definitions {
+				if playlistKey(PlaylistFileName(definition.Name)) == key {
+					selected = append(selected, definition)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("smart playlist '%s' not found", name)
+			}
+		}
+		definitions = selected
+	}
+
+	if len(definitions) == 0 {
+		return nil, fmt.Errorf("no smart playlists defined")
+	}
+
+	storageID, musicFolderID, err := SelectStorageAndMusicFolder(ctx, dev, storagesRaw)
+	if err != nil {
+		return nil, fmt.Errorf("error selecting storage: %w", err)
+	}
+
+	songs, err := GetSongs(dev, storagesRaw)
+	if err != nil {
+		return nil, fmt.Errorf("error getting songs: %w", err)
+	}
+
+	var storageSongs []model.Song
+	for _, song := range songs {
+		if song.StorageID == storageID {
+			storageSongs = append(storageSongs, song)
+		}
+	}
+
+	fmt.Fprintf(consoleOf(ctx), "Reading metadata for %d songs...\n", len(storageSongs))
+	tracks := GetTracks(dev, storageSongs)
+
+	playlists, err := GetPlaylists(dev, storagesRaw)
+	if err != nil {
+		return nil, fmt.Errorf("error getting playlists: %w", err)
+	}
+
+	pathStyle := PlaylistPathStyle(dev)
+	var results []SmartRefreshResult
+
+	for i, definition := range definitions {
+		if stopRequested(ctx) {
+			return results, fmt.Errorf("refresh %w: %d smart playlists were not refreshed", ErrInterrupted, len(definitions)-i)
+		}
+		fileName := PlaylistFileNameForFormat(definition.Name, definition.Format)
+		result := SmartRefreshResult{Name: definition.Name}
+
+		matched := EvaluateSmartPlaylist(definition, tracks)
+		result.Tracks = len(matched)
+
+		var songPaths []string
+		durations := make(map[string]time.Duration)
+		for _, track := range matched {
+			songPaths = append(songPaths, track.Path)
+			durations[track.Path] = track.Duration
+		}
+
+		if existing := findPlaylistInStorage(playlists, storageID, fileName); existing != nil {
+			result.Path = existing.Path
+			content := buildPlaylistContent(songPaths, pathStyle, files.PlaylistFormatForName(fileName), durations)
+
+			// A playlist whose rules stopped matching is emptied rather than left as it was.
+			// replacePlaylistData puts the old content back if the new one cannot be written.
+			if current, err := readObjectData(dev, existing.ObjectID); err == nil && string(current) == content {
+				result.Status = SmartStatusUnchanged
+			} else if _, err := replacePlaylistData(dev, *existing, []byte(content)); err != nil {
+				result.Status = SmartStatusFailed
+				result.Error = fmt.Sprintf("could not replace existing playlist: %v", err)
+			} else if len(matched) == 0 {
+				result.Status = SmartStatusCleared
+			} else {
+				result.Status = SmartStatusUpdated
+			}
+		} else if len(matched) == 0 {
+			result.Status = SmartStatusEmpty
+		} else if playlist, err := createPlaylist(ctx, dev, storageID, musicFolderID, fileName, songPaths, durations); err != nil {
+			result.Status = SmartStatusFailed
+			result.Error = err.Error()
+		} else {
+			result.Status = SmartStatusCreated
+			if result.Path, err = GetObjectPath(dev, playlist.ObjectID); err != nil {
+				util.LogVerbose("Could not read the path of playlist %s: %v", fileName, err)
+			}
+		}
+
+		util.LogInfo("Smart playlist %s: %s (%d tracks)", definition.Name, result.Status, result.Tracks)
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func DisplaySmartPlaylistsToConsole(definitions []model.SmartPlaylist) {
+	if len(definitions) == 0 {
+		color.New(color.FgHiRed).Println("\n✗ No smart playlists defined")
+		return
+	}
+
+	nameColor := color.New(color.FgHiCyan, color.Bold).SprintFunc()
+	for i, definition := range definitions {
+		var rules []string
+		for _, rule := range definition.Rules {
+			rules = append(rules, FormatSmartRule(rule))
+		}
+
+		match := "all"
+		if definition.MatchAny {
+			match = "any"
+		}
+
+		fmt.Printf("%d. %s (match %s: %s)\n", i+1, nameColor(definition.Name), match, strings.Join(rules, ", "))
+		if definition.Sort != "" || definition.Limit > 0 {
+			fmt.Printf("   sort: %s, limit: %d\n", definition.Sort, definition.Limit)
+		}
+	}
+}
+
+func DisplaySmartRefreshResults(results []SmartRefreshResult) {
+	successColor := color.New(color.FgHiGreen).SprintFunc()
+	errorColor := color.New(color.FgHiRed).SprintFunc()
+
+	fmt.Println("\n==== Smart Playlist Refresh ====")
+	for _, result := range results {
+		status := successColor(result.Status)
+		if result.Status == SmartStatusFailed {
+			status = errorColor(result.Status + ": " + result.Error)
+		}
+		fmt.Printf("%s: %d tracks, %s\n", result.Name, result.Tracks, status)
+	}
+}
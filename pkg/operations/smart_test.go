@@ -0,0 +1,101 @@
+package operations
+
+import (
+	"testing"
+	"time"
+
+	"github.com/schachte/better-sync/pkg/model"
+)
+
+func TestParseSmartRule(t *testing.T) {
+	tests := []struct {
+		expr string
+		want model.SmartRule
+	}{
+		{"artist=Foo", model.SmartRule{Field: "artist", Operator: "=", Value: "Foo"}},
+		{"artist!=Foo", model.SmartRule{Field: "artist", Operator: "!=", Value: "Foo"}},
+		{"folder~RUNNING", model.SmartRule{Field: "folder", Operator: "~", Value: "RUNNING"}},
+		{"genre!~jazz", model.SmartRule{Field: "genre", Operator: "!~", Value: "jazz"}},
+		{" Title = Setting Sun ", model.SmartRule{Field: "title", Operator: "=", Value: "Setting Sun"}},
+		{"title=a<b", model.SmartRule{Field: "title", Operator: "=", Value: "a<b"}},
+		{"year>=2020", model.SmartRule{Field: "year", Operator: ">=", Value: "2020"}},
+		{"year<=1999", model.SmartRule{Field: "year", Operator: "<=", Value: "1999"}},
+		{"year>2020", model.SmartRule{Field: "year", Operator: ">", Value: "2020"}},
+		{"year=2020", model.SmartRule{Field: "year", Operator: "=", Value: "2020"}},
+		{"duration<300", model.SmartRule{Field: "duration", Operator: "<", Value: "300"}},
+		{"duration<5:00", model.SmartRule{Field: "duration", Operator: "<", Value: "5:00"}},
+		{"duration>=4m30s", model.SmartRule{Field: "duration", Operator: ">=", Value: "4m30s"}},
+		{"added>=30d", model.SmartRule{Field: "added", Operator: ">=", Value: "30d"}},
+		{"added<2024-01-31", model.SmartRule{Field: "added", Operator: "<", Value: "2024-01-31"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			rule, err := ParseSmartRule(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rule != tt.want {
+				t.Errorf("ParseSmartRule(%q) = %+v, want %+v", tt.expr, rule, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSmartRuleErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{"no operator", "artist"},
+		{"missing value", "artist="},
+		{"unknown field", "bitrate>128"},
+		{"comparison on text", "artist>Foo"},
+		{"pattern on a number", "year~20"},
+		{"year not a number", "year>=recent"},
+		{"bad duration", "duration<five"},
+		{"bad date", "added>=2024-13-01"},
+		{"bad age unit", "added>=30m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rule, err := ParseSmartRule(tt.expr); err == nil {
+				t.Errorf("ParseSmartRule(%q) = %+v, want an error", tt.expr, rule)
+			}
+		})
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		valid bool
+	}{
+		{"12h", 12 * time.Hour, true},
+		{"30d", 30 * 24 * time.Hour, true},
+		{"2w", 14 * 24 * time.Hour, true},
+		{"0d", 0, true},
+		{" 1d ", 24 * time.Hour, true},
+		{"", 0, false},
+		{"d", 0, false},
+		{"30", 0, false},
+		{"30m", 0, false},
+		{"-1d", 0, false},
+		{"1.5d", 0, false},
+		{"30D", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			age, err := ParseAge(tt.value)
+			if (err == nil) != tt.valid {
+				t.Fatalf("ParseAge(%q) error = %v, want valid %v", tt.value, err, tt.valid)
+			}
+			if age != tt.want {
+				t.Errorf("ParseAge(%q) = %v, want %v", tt.value, age, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package operations
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/schachte/better-sync/pkg/config"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+// SpotifyDownloadOptions controls DownloadSpotify
+type SpotifyDownloadOptions struct {
+	// Name is the folder the tracks are saved in, by default the playlist name on Spotify
+	Name string
+	// Directory holds the playlist folder, by default the configured download directory
+	Directory string
+}
+
+// DownloadSpotify downloads a Spotify playlist with spotdl without asking and returns
+// the folder the tracks were saved in
+func DownloadSpotify(ctx context.Context, playlistURL string, options SpotifyDownloadOptions) (string, error) {
+	name := strings.TrimSpace(options.Name)
+	if name == "" {
+		var err error
+		if name, err = util.GetSpotifyPlaylistName(playlistURL, config.SpotifyCredentials()); err != nil {
+			return "", fmt.Errorf("error getting playlist name, give one with --name: %w", err)
+		}
+		name = strings.TrimSpace(name)
+	}
+
+	directory := options.Directory
+	if directory == "" {
+		directory = defaultSearchDir()
+	}
+
+	destDir := filepath.Join(util.ExpandPath(directory), name)
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return "", fmt.Errorf("error creating %s: %w", destDir, err)
+	}
+
+	util.LogInfo("Downloading %s to %s", playlistURL, destDir)
+	if err := runSpotdl(ctx, playlistURL, destDir); err != nil {
+		return destDir, err
+	}
+	return destDir, nil
+}
+
+// runSpotdl downloads a Spotify playlist into destDir, echoing the output of spotdl
+func runSpotdl(ctx context.Context, playlistURL, destDir string) error {
+	cmd := exec.CommandContext(ctx, "spotdl", "download", playlistURL, "--output", filepath.Join(destDir, "{title}"))
+
+	stdout, _ := cmd.StdoutPipe()
+	stderr, _ := cmd.StderrPipe()
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start spotdl, is it installed? (pip install spotdl): %w", err)
+	}
+
+	scanner := bufio.NewScanner(io.MultiReader(stdout, stderr))
+	for scanner.Scan() {
+		fmt.Fprintln(consoleOf(ctx), scanner.Text())
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("error during download: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package operations
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/schachte/better-sync/pkg/files"
+	"github.com/schachte/better-sync/pkg/model"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+// trashEnabled controls whether deleted songs and playlists are copied to the local
+// trash first
+var trashEnabled = false
+
+// SetTrash enables or disables copying objects to the local trash before deleting them
+func SetTrash(enabled bool) {
+	trashEnabled = enabled
+}
+
+// TrashEnabled reports whether deletions can be restored from the local trash
+func TrashEnabled() bool {
+	return trashEnabled
+}
+
+// trashObject copies a device object to the local trash before it is deleted. Folders
+// are not copied; restoring a file recreates its folders. Nothing is copied when the
+// trash is disabled. The returned function removes the copy again; call it when the
+// object could not be deleted, so a restore does not create a duplicate.
+func trashObject(dev *mtp.Device, storageID, objectID uint32, devicePath string) (func(), error) {
+	keep := func() {}
+	if !trashEnabled {
+		return keep, nil
+	}
+
+	info, err := util.GetObjectInfoWithRetry(dev, objectID)
+	if err != nil {
+		return keep, fmt.Errorf("error getting object info for %s: %w", devicePath, err)
+	}
+	if info.ObjectFormat == FILETYPE_FOLDER {
+		return keep, nil
+	}
+
+	data, err := readObjectData(dev, objectID)
+	if err != nil {
+		return keep, fmt.Errorf("error copying %s to trash: %w", devicePath, err)
+	}
+
+	entry := model.TrashEntry{
+		DevicePath:   normalizePath(strings.ReplaceAll(devicePath, "\\", "/")),
+		StorageID:    storageID,
+		ObjectFormat: info.ObjectFormat,
+		ModifiedAt:   info.ModificationDate,
+		DeletedAt:    time.Now(),
+	}
+	entry, err = files.AddToTrash(entry, bytes.NewReader(data))
+	if err != nil {
+		return keep, err
+	}
+
+	util.LogInfo("Copied %s to trash (#%d)", entry.DevicePath, entry.ID)
+	return func() {
+		if err := files.RemoveFromTrash(entry.ID); err != nil {
+			util.LogError("Could not remove %s from the trash: %v", entry.DevicePath, err)
+			return
+		}
+		util.LogVerbose("Removed %s from the trash (#%d): it was not deleted", entry.DevicePath, entry.ID)
+	}, nil
+}
+
+// findTrashEntry returns the trash entry with the given ID
+func findTrashEntry(id int) (*model.TrashEntry, error) {
+	entries, err := files.LoadTrash()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		if entries[i].ID == id {
+			return &entries[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no trash entry #%d", id)
+}
+
+// restoreStorage returns the storage an entry was deleted from, or the first storage
+// when that one is no longer present
+func restoreStorage(storagesRaw interface{}, storageID uint32) (uint32, error) {
+	storagesValue := reflect.ValueOf(storagesRaw)
+	if storagesValue.Kind() != reflect.Slice || storagesValue.Len() == 0 {
+		return 0, fmt.Errorf("no storage found on device")
+	}
+
+	for i := 0; i < storagesValue.Len(); i++ {
+		if extractUint32Field(storagesValue.Index(i).Interface(), "Sid") == storageID {
+			return storageID, nil
+		}
+	}
+	return extractUint32Field(storagesValue.Index(0).Interface(), "Sid"), nil
+}
+
+// RestoreFromTrash recreates the folders of a trashed object and uploads it again to
+// its original path, then removes it from the trash. Once ctx asks to stop nothing is
+// restored; cancelling it during the upload deletes what was written.
+func RestoreFromTrash(ctx context.Context, dev *mtp.Device, storagesRaw interface{}, id int) (*model.TrashEntry, error) {
+	if stopRequested(ctx) {
+		return nil, fmt.Errorf("restore of #%d %w", id, ErrInterrupted)
+	}
+	entry, err := findTrashEntry(id)
+	if err != nil {
+		return nil, err
+	}
+
+	storageID, err := restoreStorage(storagesRaw, entry.StorageID)
+	if err != nil {
+		return nil, err
+	}
+
+	parentID := PARENT_ROOT
+	for _, folder := range strings.Split(strings.Trim(path.Dir(entry.DevicePath), "/"), "/") {
+		if folder == "" {
+			continue
+		}
+		parentID, err = findOrCreateFolder(dev, storageID, parentID, folder)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fileName := path.Base(entry.DevicePath)
+	if _, err := findObjectByName(dev, storageID, parentID, fileName); err == nil {
+		return nil, fmt.Errorf("%s already exists on the device", entry.DevicePath)
+	}
+
+	localPath, err := files.TrashFilePath(*entry)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading trash copy of %s: %w", entry.DevicePath, err)
+	}
+
+	info := mtp.ObjectInfo{
+		StorageID:        storageID,
+		ObjectFormat:     entry.ObjectFormat,
+		ParentObject:     parentID,
+		Filename:         fileName,
+		CompressedSize:   uint32(len(data)),
+		ModificationDate: entry.ModifiedAt,
+	}
+
+	_, _, objectID, err := dev.SendObjectInfo(storageID, parentID, &info)
+	if err != nil {
+		return nil, fmt.Errorf("error creating %s on device: %w", entry.DevicePath, err)
+	}
+
+	err = dev.SendObject(contextReader{ctx: ctx, reader: bytes.NewReader(data)}, int64(len(data)), model.EmptyProgressFunc)
+	if err != nil && ctx.Err() != nil {
+		err = fmt.Errorf("%w: %v", ErrInterrupted, ctx.Err())
+	} else if err != nil {
+		util.LogVerbose("Standard file transfer failed: %v", err)
+		err = tryAlternativeDataTransfer(dev, objectID, data, int64(len(data)))
+	}
+	if err != nil {
+		if deleteErr := dev.DeleteObject(objectID); deleteErr != nil {
+			util.LogError("Could not remove the incomplete copy of %s: %v", entry.DevicePath, deleteErr)
+		}
+		return nil, fmt.Errorf("error uploading %s: %w", entry.DevicePath, err)
+	}
+
+	if err := files.RemoveFromTrash(entry.ID); err != nil {
+		util.LogError("Restored %s but could not remove it from the trash: %v", entry.DevicePath, err)
+	}
+
+	util.LogInfo("Restored %s from trash (#%d, object ID %d)", entry.DevicePath, entry.ID, objectID)
+	return entry, nil
+}
+
+// EmptyTrash permanently removes trash entries deleted more than olderThan ago; zero
+// removes everything. It returns how many entries were removed and their size.
+func EmptyTrash(olderThan time.Duration) (int, int64, error) {
+	entries, err := files.LoadTrash()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	var ids []int
+	var size int64
+	for _, entry := range entries {
+		if olderThan == 0 || entry.DeletedAt.Before(cutoff) {
+			ids = append(ids, entry.ID)
+			size += entry.Size
+		}
+	}
+
+	if len(ids) == 0 {
+		return 0, 0, nil
+	}
+	if err := files.RemoveFromTrash(ids...); err != nil {
+		return 0, 0, err
+	}
+	return len(ids), size, nil
+}
+
+func DisplayTrash(entries []model.TrashEntry) {
+	if len(entries) == 0 {
+		fmt.Println("The trash is empty.")
+		return
+	}
+
+	dateColor := color.New(color.Faint).SprintFunc()
+	var total int64
+	fmt.Println("\n==== Trash ====")
+	for _, entry := range entries {
+		fmt.Printf("%4d. %-60s %10s  %s\n", entry.ID, entry.DevicePath, util.FormatSize(entry.Size),
+			dateColor(entry.DeletedAt.Format("2006-01-02 15:04")))
+		total += entry.Size
+	}
+	fmt.Printf("\n%d items, %s\n", len(entries), util.FormatSize(total))
+}
+
+// ManageTrash is the interactive menu entry for listing and restoring the trash
+func ManageTrash(dev *mtp.Device, storagesRaw interface{}) {
+	fmt.Println("\n=== Restore From Trash ===")
+
+	entries, err := files.LoadTrash()
+	if err != nil {
+		util.LogError("Error reading trash: %v", err)
+		return
+	}
+	if !trashEnabled {
+		fmt.Println("Deleted files are only kept when better-sync is started with --trash.")
+	}
+
+	DisplayTrash(entries)
+	if len(entries) == 0 {
+		return
+	}
+
+	fmt.Print("\nEnter the numbers of the items to restore, separated by spaces (Enter to cancel): ")
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Scan()
+
+	for _, field := range strings.Fields(scanner.Text()) {
+		var id int
+		if _, err := fmt.Sscanf(field, "%d", &id); err != nil {
+			util.LogError("Invalid trash entry: %s", field)
+			continue
+		}
+
+		entry, err := RestoreFromTrash(context.Background(), dev, storagesRaw, id)
+		if err != nil {
+			util.LogError("Error restoring #%d: %v", id, err)
+			continue
+		}
+		color.HiGreen("✓ Restored %s", entry.DevicePath)
+	}
+}
@@ -3,18 +3,21 @@ package operations
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
 	"strings"
 	"time"
 
 	"github.com/bogem/id3v2"
-	"github.com/fatih/color"
 	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/schachte/better-sync/pkg/config"
 	"github.com/schachte/better-sync/pkg/device"
+	"github.com/schachte/better-sync/pkg/files"
 	"github.com/schachte/better-sync/pkg/model"
 	"github.com/schachte/better-sync/pkg/util"
 	"github.com/schollz/progressbar/v3"
@@ -27,7 +30,7 @@ const (
 
 func UploadSong(dev *mtp.Device, storagesRaw interface{}) {
 
-	storageID, musicFolderID, err := SelectStorageAndMusicFolder(dev, storagesRaw)
+	storageID, musicFolderID, err := SelectStorageAndMusicFolder(context.Background(), dev, storagesRaw)
 	if err != nil {
 		util.LogError("Error selecting storage: %v", err)
 		fmt.Printf("Error: %v\n", err)
@@ -143,7 +146,7 @@ func uploadDirectory(dev *mtp.Device, storageID, musicFolderID uint32) {
 func CreateAndUploadPlaylist(dev *mtp.Device, storagesRaw interface{}) {
 	fmt.Println("\n=== Create and Upload Playlist ===")
 
-	storageID, musicFolderID, err := SelectStorageAndMusicFolder(dev, storagesRaw)
+	storageID, musicFolderID, err := SelectStorageAndMusicFolder(context.Background(), dev, storagesRaw)
 	if err != nil {
 		util.LogError("Error selecting storage: %v", err)
 		return
@@ -152,16 +155,10 @@ func CreateAndUploadPlaylist(dev *mtp.Device, storagesRaw interface{}) {
 	fmt.Print("Enter playlist name: ")
 	scanner := bufio.NewScanner(os.Stdin)
 	scanner.Scan()
-	playlistName := scanner.Text()
-	playlistName = util.SanitizeFileName(playlistName)
-	playlistName = strings.ToUpper(playlistName)
+	playlistName := PlaylistFileName(scanner.Text())
 
-	if !strings.HasSuffix(strings.ToLower(playlistName), ".m3u8") {
-		playlistName += ".m3u8"
-	}
-
-	pathStyle := 1
-	fmt.Println("Using standard path format: 0:/MUSIC/ARTIST/ALBUM/##_TRACK.MP3")
+	pathStyle := PlaylistPathStyle(dev)
+	fmt.Printf("Using path format: %s\n", util.PathStyleExample(pathStyle))
 	util.LogInfo("Using path style %d for playlist entries", pathStyle)
 
 	mp3Files, err := FindMP3Files(dev, storageID)
@@ -205,9 +202,21 @@ func CreateAndUploadPlaylist(dev *mtp.Device, storagesRaw interface{}) {
 		selectedIndices = append(selectedIndices, index-1)
 	}
 
+	index, err := buildDeviceIndex(dev, storageID, "/Music")
+	if err != nil {
+		util.LogVerbose("Could not index songs for durations: %v", err)
+	}
+
 	for trackNum, idx := range selectedIndices {
 		songPath := mp3Files[idx]
 
+		seconds := -1
+		if index != nil {
+			if object, ok := index.Lookup(songPath); ok {
+				seconds = durationSeconds(objectDuration(dev, object.ObjectID, object.Path, object.Size))
+			}
+		}
+
 		displayTrackNum := trackNum + 1
 
 		pathParts := strings.Split(songPath, "/")
@@ -217,16 +226,11 @@ func CreateAndUploadPlaylist(dev *mtp.Device, storagesRaw interface{}) {
 			numberedFilename := fmt.Sprintf("%02d %s", displayTrackNum, filename)
 			pathParts[len(pathParts)-1] = numberedFilename
 
-			formattedPath := strings.Join(pathParts, "/")
-			if strings.HasPrefix(songPath, "/") {
-				formattedPath = "/" + formattedPath
-			}
-
-			formattedPath = "0:" + formattedPath
+			formattedPath := util.FormatPlaylistPath(strings.Join(pathParts, "/"), pathStyle)
 
 			displayName := strings.ToUpper(util.ExtractTrackInfo(songPath))
 
-			playlistContent.WriteString(fmt.Sprintf("#EXTINF:-1,%s\n", displayName))
+			playlistContent.WriteString(fmt.Sprintf("#EXTINF:%d,%s\n", seconds, displayName))
 			playlistContent.WriteString(formattedPath)
 			playlistContent.WriteString("\n")
 
@@ -237,7 +241,7 @@ func CreateAndUploadPlaylist(dev *mtp.Device, storagesRaw interface{}) {
 			formattedPath := util.FormatPlaylistPath(songPath, pathStyle)
 			displayName := strings.ToUpper(util.ExtractTrackInfo(songPath))
 
-			playlistContent.WriteString(fmt.Sprintf("#EXTINF:-1,%s\n", displayName))
+			playlistContent.WriteString(fmt.Sprintf("#EXTINF:%d,%s\n", seconds, displayName))
 			playlistContent.WriteString(formattedPath)
 			playlistContent.WriteString("\n")
 
@@ -246,6 +250,12 @@ func CreateAndUploadPlaylist(dev *mtp.Device, storagesRaw interface{}) {
 		}
 	}
 
+	if files.PlaylistFormatForName(playlistName) == files.PlaylistFormatPLS {
+		content := files.FormatPLS(files.ParseM3U(playlistContent.String()))
+		playlistContent.Reset()
+		playlistContent.WriteString(content)
+	}
+
 	util.LogVerbose("Full playlist content:\n%s", playlistContent.String())
 
 	parentFolderID := musicFolderID
@@ -254,177 +264,30 @@ func CreateAndUploadPlaylist(dev *mtp.Device, storagesRaw interface{}) {
 	fmt.Printf("Uploading playlist to: %s\n", uploadPath)
 	util.LogInfo("Uploading playlist to path: %s (parent ID: %d)", uploadPath, parentFolderID)
 
-	tempFile, err := os.CreateTemp("", "playlist-*.m3u8")
-	if err != nil {
-		util.LogError("Error creating temporary playlist file: %v", err)
-		return
-	}
-	defer os.Remove(tempFile.Name())
-	defer tempFile.Close()
-
-	_, err = tempFile.WriteString(playlistContent.String())
-	if err != nil {
-		util.LogError("Error writing playlist content: %v", err)
-		return
-	}
-
-	tempFile.Sync()
-	tempFile.Seek(0, 0)
-
-	fileInfo, err := tempFile.Stat()
-	if err != nil {
-		util.LogError("Error getting file info: %v", err)
-		return
-	}
-
-	info := mtp.ObjectInfo{
-		StorageID:        storageID,
-		ObjectFormat:     0xBA05,
-		ParentObject:     parentFolderID,
-		Filename:         playlistName,
-		CompressedSize:   uint32(fileInfo.Size()),
-		ModificationDate: time.Now(),
-	}
-
-	fmt.Println("Creating playlist on device...")
-	var objectID uint32
-	_, _, objectID, err = dev.SendObjectInfo(storageID, parentFolderID, &info)
-	if err != nil {
-		util.LogError("Error creating playlist on device: %v", err)
-		return
-	}
-
-	fmt.Printf("Sending playlist data (size: %d bytes)...\n", fileInfo.Size())
-
-	data := make([]byte, fileInfo.Size())
-	_, err = tempFile.Seek(0, 0)
+	data := []byte(playlistContent.String())
+	fmt.Printf("Sending playlist data (size: %d bytes)...\n", len(data))
+	objectID, err := files.UploadPlaylistData(dev, storageID, parentFolderID, playlistName, data)
 	if err != nil {
-		util.LogError("Error seeking file: %v", err)
+		util.LogError("Error uploading playlist: %v", err)
 		return
 	}
 
-	_, err = tempFile.Read(data)
-	if err != nil {
-		util.LogError("Error reading file: %v", err)
-		return
-	}
-
-	fmt.Println("Playlist data transferred successfully using alternative method.")
-
-	fmt.Printf("Successfully created and uploaded playlist %s\n", playlistName)
-	util.LogInfo("Successfully created and uploaded playlist %s (object ID: %d) to %s",
-		playlistName, objectID, uploadPath)
-
+	util.LogInfo("Uploaded playlist %s (object ID: %d) to %s", playlistName, objectID, uploadPath)
 	util.LogInfo("Playlist contains %d songs", len(selectedSongs))
 	for i, song := range selectedSongs {
 		util.LogVerbose("Playlist song %d: %s", i+1, song)
 	}
 
-	fmt.Println("Waiting for device to process playlist...")
-	time.Sleep(2 * time.Second)
-
-	verified := VerifyPlaylistUploaded(dev, storageID, parentFolderID, playlistName)
-	if verified {
-		fmt.Println("Playlist verification successful")
-	} else {
-		fmt.Println("Playlist verification failed")
-	}
-}
-
-func VerifyPlaylistUploaded(dev *mtp.Device, storageID uint32, parentID uint32, playlistName string) bool {
-	util.LogInfo("Verifying playlist upload for %s", playlistName)
-
-	handles := mtp.Uint32Array{}
-	err := dev.GetObjectHandles(storageID, 0, parentID, &handles)
-	if err != nil {
-		util.LogError("Error getting object handles during verification: %v", err)
-		util.LogError("Unable to verify playlist creation due to device error")
-		util.LogInfo("However, the playlist was likely created successfully.")
-		return false
-	}
-
-	found := false
-	var foundHandle uint32
-	for _, handle := range handles.Values {
-		info := mtp.ObjectInfo{}
-		err = dev.GetObjectInfo(handle, &info)
-		if err != nil {
-			continue
-		}
-
-		if info.Filename == playlistName {
-			util.LogInfo("Playlist verification: Found playlist %s directly in parent folder (ID: %d)",
-				playlistName, handle)
-			util.LogInfo("✓ Playlist %s verified at parent folder", playlistName)
-			found = true
-			foundHandle = handle
-			break
-		}
-	}
-
-	if found {
-		tryReadPlaylistContent(dev, foundHandle, playlistName)
-		return true
-	} else {
-		util.LogInfo("Playlist %s not found in direct parent check", playlistName)
-		util.LogInfo("Direct verification couldn't find playlist. Running a full search...")
-
-		util.LogVerbose("Waiting 3 seconds for device to update its database...")
-		time.Sleep(3 * time.Second)
-
-		playlists, err := FindPlaylists(dev, storageID)
-		if err != nil {
-			util.LogError("Error searching for playlists: %v", err)
-			util.LogError("Error during full search, but playlist may still have been created successfully.")
-			return false
-		}
-
-		for _, path := range playlists {
-			filename := filepath.Base(path)
-			if filename == playlistName {
-				util.LogInfo("Playlist verification: Found playlist %s at path %s during full search",
-					playlistName, path)
-				util.LogInfo("✓ Playlist %s verified at %s", playlistName, path)
-				return true
-			}
-		}
-
-		util.LogInfo("Could not verify playlist %s was indexed by device", playlistName)
-		util.LogInfo("Note: Playlist %s was uploaded, but couldn't be verified in device index.", playlistName)
-		util.LogInfo("This is common with MTP devices which may need to be disconnected/reconnected")
-		util.LogInfo("or may need time to update their pkg database.")
-		util.LogInfo("The playlist should be available after reconnecting or restarting the device.")
-
-		util.LogInfo("\nTroubleshooting tips:")
-		util.LogInfo("1. Check if your device has a 'Refresh Media Library' option in its settings")
-		util.LogInfo("2. Try disconnecting and reconnecting the device")
-		util.LogInfo("3. Try creating playlists directly on the device instead")
-		util.LogInfo("4. Some devices only recognize playlists created by specific apps")
-		return false
-	}
-}
-
-func tryReadPlaylistContent(dev *mtp.Device, objectID uint32, playlistName string) {
-	util.LogInfo("Attempting to read content of playlist %s (ID: %d)", playlistName, objectID)
-	fmt.Println("Attempting to read playlist content to verify transfer...")
-
-	info := mtp.ObjectInfo{}
-	err := dev.GetObjectInfo(objectID, &info)
-	if err != nil {
-		util.LogVerbose("Could not get object info: %v", err)
-		fmt.Println("Could not read playlist content - this is normal for many devices")
-		return
-	}
-
-	util.LogInfo("Playlist appears to exist on device")
+	fmt.Println("Reading playlist back to verify it...")
+	DisplayPlaylistValidation(os.Stdout, ValidatePlaylistUpload(dev, storageID, objectID, playlistName, data))
 }
 
 func ProcessAndUploadFile(dev *mtp.Device, storageID, musicFolderID uint32, filePath string) bool {
 
 	fileInfo, _ := os.Stat(filePath)
-	if fileInfo.Size() > 10*1024*1024 {
-		fmt.Printf("File %s is too large. This implementation only supports files up to 10MB.\n",
-			filepath.Base(filePath))
+	if limit := config.MaxFileSize(); fileInfo.Size() > limit {
+		fmt.Printf("File %s is too large. Files up to %s are uploaded (max_file_size).\n",
+			filepath.Base(filePath), util.FormatSize(limit))
 		return false
 	}
 
@@ -533,7 +396,7 @@ func ProcessAndUploadFile(dev *mtp.Device, storageID, musicFolderID uint32, file
 	fmt.Printf("Successfully uploaded %s to %s\n", fileName, devicePath)
 	util.LogVerbose("Successfully uploaded %s (object ID: %d) to %s", fileName, objectID, devicePath)
 
-	verified := verifyFileUploaded(dev, objectID, storageID, albumFolderID, fileName, fileInfo.Size())
+	verified := verifyFileUploaded(os.Stdout, dev, objectID, storageID, albumFolderID, fileName, fileInfo.Size())
 	if verified {
 		fmt.Printf("✓ Verified: %s exists on device\n", fileName)
 	} else {
@@ -544,9 +407,9 @@ func ProcessAndUploadFile(dev *mtp.Device, storageID, musicFolderID uint32, file
 	return true
 }
 
-func verifyFileUploaded(dev *mtp.Device, objectID, storageID, parentID uint32, fileName string, expectedSize int64) bool {
+func verifyFileUploaded(out io.Writer, dev *mtp.Device, objectID, storageID, parentID uint32, fileName string, expectedSize int64) bool {
 	util.LogInfo("Verifying file upload for %s (ID: %d)", fileName, objectID)
-	fmt.Printf("Verifying file was successfully uploaded...\n")
+	fmt.Fprintf(out, "Verifying file was successfully uploaded...\n")
 
 	fileInfo := mtp.ObjectInfo{}
 	err := dev.GetObjectInfo(objectID, &fileInfo)
@@ -559,14 +422,14 @@ func verifyFileUploaded(dev *mtp.Device, objectID, storageID, parentID uint32, f
 		} else {
 			util.LogError("File size mismatch: expected %d bytes, got %d bytes",
 				expectedSize, fileInfo.CompressedSize)
-			fmt.Printf("File size mismatch: expected %d bytes, got %d bytes\n",
+			fmt.Fprintf(out, "File size mismatch: expected %d bytes, got %d bytes\n",
 				expectedSize, fileInfo.CompressedSize)
 		}
 	} else {
 		util.LogError("Error getting object info: %v", err)
 	}
 
-	fmt.Println("Trying to find file in parent folder...")
+	fmt.Fprintln(out, "Trying to find file in parent folder...")
 	handles := mtp.Uint32Array{}
 	err = dev.GetObjectHandles(storageID, 0, parentID, &handles)
 	if err != nil {
@@ -586,12 +449,12 @@ func verifyFileUploaded(dev *mtp.Device, objectID, storageID, parentID uint32, f
 				fileName, handle, info.CompressedSize)
 
 			if info.CompressedSize == uint32(expectedSize) {
-				fmt.Printf("✓ File verified in folder with correct size: %d bytes\n", info.CompressedSize)
+				fmt.Fprintf(out, "✓ File verified in folder with correct size: %d bytes\n", info.CompressedSize)
 				return true
 			} else {
 				util.LogError("File size mismatch: expected %d bytes, got %d bytes",
 					expectedSize, info.CompressedSize)
-				fmt.Printf("File exists but size mismatch: expected %d bytes, got %d bytes\n",
+				fmt.Fprintf(out, "File exists but size mismatch: expected %d bytes, got %d bytes\n",
 					expectedSize, info.CompressedSize)
 
 				return true
@@ -599,7 +462,7 @@ func verifyFileUploaded(dev *mtp.Device, objectID, storageID, parentID uint32, f
 		}
 	}
 
-	fmt.Println("File not found on first attempt. Waiting 2 seconds and trying again...")
+	fmt.Fprintln(out, "File not found on first attempt. Waiting 2 seconds and trying again...")
 	time.Sleep(2 * time.Second)
 
 	err = dev.GetObjectInfo(objectID, &fileInfo)
@@ -665,7 +528,9 @@ func extractStringField(obj interface{}, fieldName string) string {
 	return field.String()
 }
 
-func SelectStorageAndMusicFolder(dev *mtp.Device, storagesRaw interface{}) (uint32, uint32, error) {
+// SelectStorageAndMusicFolder picks the first storage and finds or creates its Music
+// folder
+func SelectStorageAndMusicFolder(ctx context.Context, dev *mtp.Device, storagesRaw interface{}) (uint32, uint32, error) {
 
 	storagesValue := reflect.ValueOf(storagesRaw)
 	if storagesValue.Kind() != reflect.Slice || storagesValue.Len() == 0 {
@@ -677,7 +542,7 @@ func SelectStorageAndMusicFolder(dev *mtp.Device, storagesRaw interface{}) (uint
 	storageDesc := extractStringField(firstStorage, "Description")
 
 	util.LogInfo("Automatically selected storage: %s (ID: %d)", storageDesc, storageID)
-	fmt.Printf("Automatically selected storage: %s (ID: %d)\n", storageDesc, storageID)
+	fmt.Fprintf(consoleOf(ctx), "Automatically selected storage: %s (ID: %d)\n", storageDesc, storageID)
 
 	musicFolderID, err := util.FindOrCreateMusicFolder(dev, storageID)
 	if err != nil {
@@ -727,41 +592,16 @@ func tryAlternativeDataTransfer(dev *mtp.Device, objectID uint32, data []byte, f
 	return err
 }
 
-func UploadDirectoryWithPlaylist(dev *mtp.Device, storageID, musicFolderID uint32) *UploadResult {
-	result := &UploadResult{
-		Success:       false,
-		UploadedFiles: make([]model.MP3File, 0),
-		Playlist:      nil,
-		Errors:        make([]string, 0),
-	}
-
-	// Check if we have a preset directory path (from Spotify download)
-	dirPath := os.Getenv("PRESET_DIRECTORY_PATH")
-	presetConfirm := os.Getenv("PRESET_CONFIRM_UPLOAD")
-
-	if dirPath == "" {
-		fmt.Print("Enter path to directory containing MP3 files: ")
-		scanner := bufio.NewScanner(os.Stdin)
-		scanner.Scan()
-		dirPath = scanner.Text()
-	}
-
+// FindLocalMP3Files returns the MP3 files below a local directory, including its
+// subfolders
+func FindLocalMP3Files(dirPath string) ([]string, error) {
 	dirInfo, err := os.Stat(dirPath)
 	if err != nil {
-		result.AddError(fmt.Sprintf("Error accessing directory: %v", err))
-		return result
+		return nil, fmt.Errorf("error accessing directory: %w", err)
 	}
 
 	if !dirInfo.IsDir() {
-		result.AddError("Specified path is not a directory. Please use single file upload option.")
-		return result
-	}
-
-	recursive := true
-	dirName := filepath.Base(dirPath)
-	playlistName := strings.ToUpper(util.SanitizeFileName(dirName))
-	if !strings.HasSuffix(strings.ToLower(playlistName), ".m3u8") {
-		playlistName += ".m3u8"
+		return nil, fmt.Errorf("%s is not a directory", dirPath)
 	}
 
 	var mp3Files []string
@@ -770,13 +610,7 @@ func UploadDirectoryWithPlaylist(dev *mtp.Device, storageID, musicFolderID uint3
 			util.LogVerbose("Error accessing path %s: %v", path, err)
 			return nil
 		}
-		if info.IsDir() {
-			if !recursive && path != dirPath {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-		if strings.HasSuffix(strings.ToLower(path), ".mp3") {
+		if !info.IsDir() && strings.HasSuffix(strings.ToLower(path), ".mp3") {
 			mp3Files = append(mp3Files, path)
 		}
 		return nil
@@ -784,39 +618,127 @@ func UploadDirectoryWithPlaylist(dev *mtp.Device, storageID, musicFolderID uint3
 
 	if err != nil {
 		util.LogVerbose("Error walking directory: %v", err)
-		result.AddError(fmt.Sprintf("Error scanning directory: %v", err))
-		return result
+		return nil, fmt.Errorf("error scanning directory: %w", err)
 	}
 
 	if len(mp3Files) == 0 {
-		result.AddError("No MP3 files found in the specified directory.")
+		return nil, fmt.Errorf("no MP3 files found in %s", dirPath)
+	}
+
+	return mp3Files, nil
+}
+
+// UploadDirectory uploads every MP3 file below a local directory without asking and
+// creates a playlist of the uploaded tracks, named after the directory unless
+// playlistName is given
+func UploadDirectory(ctx context.Context, dev *mtp.Device, storageID, musicFolderID uint32, dirPath, playlistName string) *UploadResult {
+	result := &UploadResult{
+		UploadedFiles: make([]model.MP3File, 0),
+		Errors:        make([]string, 0),
+	}
+
+	mp3Files, err := FindLocalMP3Files(dirPath)
+	if err != nil {
+		result.AddError(err.Error())
 		return result
 	}
 
-	fmt.Printf("Found %d MP3 files in %s\n", len(mp3Files), dirPath)
+	if playlistName == "" {
+		playlistName = filepath.Base(filepath.Clean(dirPath))
+	}
+
+	fmt.Fprintf(consoleOf(ctx), "Found %d MP3 files in %s\n", len(mp3Files), dirPath)
+	uploadFilesWithPlaylist(ctx, dev, storageID, musicFolderID, mp3Files, PlaylistFileName(playlistName), result)
+	return result
+}
 
-	// Check if we should skip confirmation (for automated Spotify uploads)
-	confirm := "n"
-	if presetConfirm != "" {
-		confirm = presetConfirm
-	} else {
-		fmt.Printf("Do you want to upload %d MP3 files and create a playlist? (y/n): ", len(mp3Files))
-		scanner := bufio.NewScanner(os.Stdin)
-		scanner.Scan()
-		confirm = strings.ToLower(scanner.Text())
+// UploadFiles uploads local MP3 files without asking. When playlistName is given, a
+// playlist of the uploaded tracks is created as well.
+func UploadFiles(ctx context.Context, dev *mtp.Device, storageID, musicFolderID uint32, filePaths []string, playlistName string) *UploadResult {
+	result := &UploadResult{
+		UploadedFiles: make([]model.MP3File, 0),
+		Errors:        make([]string, 0),
 	}
 
-	if confirm != "y" && confirm != "yes" {
-		result.AddError("Upload cancelled by user.")
+	if playlistName != "" {
+		uploadFilesWithPlaylist(ctx, dev, storageID, musicFolderID, filePaths, PlaylistFileName(playlistName), result)
 		return result
 	}
 
+	uploadLocalFiles(ctx, dev, storageID, musicFolderID, filePaths, nil, result)
+	result.Success = len(result.UploadedFiles) == len(filePaths)
+	return result
+}
+
+// uploadFilesWithPlaylist uploads files and creates a playlist of the ones that were
+// uploaded, recording the outcome in result. When the upload is interrupted the
+// playlist is left pending for WriteUploadPlaylist.
+func uploadFilesWithPlaylist(ctx context.Context, dev *mtp.Device, storageID, musicFolderID uint32, filePaths []string, playlistName string, result *UploadResult) {
+	uploadedFilePaths := uploadLocalFiles(ctx, dev, storageID, musicFolderID, filePaths, nil, result)
+	if len(uploadedFilePaths) == 0 {
+		if !result.Interrupted {
+			result.AddError("No files were successfully uploaded, so no playlist was created.")
+		}
+		return
+	}
+
+	result.PendingPlaylist = playlistName
+	if result.Interrupted {
+		return
+	}
+
+	if err := WriteUploadPlaylist(ctx, dev, result); err != nil {
+		result.AddError(fmt.Sprintf("Playlist creation failed: %v", err))
+		return
+	}
+	result.Success = true
+}
+
+// WriteUploadPlaylist creates the pending playlist of an upload, listing the files
+// that were uploaded. It is used to keep the finished tracks of an interrupted upload.
+func WriteUploadPlaylist(ctx context.Context, dev *mtp.Device, result *UploadResult) error {
+	if result.PendingPlaylist == "" {
+		return fmt.Errorf("the upload has no playlist to write")
+	}
+	if len(result.UploadedFiles) == 0 {
+		return fmt.Errorf("no files were uploaded")
+	}
+
+	var uploadedFilePaths []string
+	durations := make(map[string]time.Duration)
+	for _, file := range result.UploadedFiles {
+		uploadedFilePaths = append(uploadedFilePaths, file.Path)
+		durations[file.Path] = file.Duration
+	}
+
+	first := result.UploadedFiles[0]
+	playlistResult, err := createPlaylist(ctx, dev, first.StorageID, first.ParentID, result.PendingPlaylist, uploadedFilePaths, durations)
+	if err != nil {
+		return err
+	}
+	result.Playlist = &playlistResult
+	result.PendingPlaylist = ""
+	return nil
+}
+
+// uploadLocalFiles uploads files in order, recording each outcome in result, and
+// returns the device paths of the files that were uploaded. Files with an entry in
+// tags are placed using those tags instead of their ID3 tags. Once ctx asks to stop,
+// the remaining files are recorded as not uploaded.
+func uploadLocalFiles(ctx context.Context, dev *mtp.Device, storageID, musicFolderID uint32, filePaths []string, tags map[string]*trackTags, result *UploadResult) []string {
+	out := consoleOf(ctx)
 	var uploadedFilePaths []string
 	successCount := 0
 	failureCount := 0
 
-	for i, filePath := range mp3Files {
-		fmt.Printf("\n[%d/%d] Processing %s\n", i+1, len(mp3Files), filepath.Base(filePath))
+	for i, filePath := range filePaths {
+		if stopRequested(ctx) {
+			result.Interrupted = true
+			result.NotUploaded = append(result.NotUploaded, filePaths[i:]...)
+			break
+		}
+
+		fmt.Fprintf(out, "\n[%d/%d] Processing %s\n", i+1, len(filePaths), filepath.Base(filePath))
 		fileInfo, err := os.Stat(filePath)
 		if err != nil {
 			util.LogVerbose("Error accessing file: %v. Skipping.", err)
@@ -825,23 +747,39 @@ func UploadDirectoryWithPlaylist(dev *mtp.Device, storageID, musicFolderID uint3
 			continue
 		}
 
-		if fileInfo.Size() > 10*1024*1024 {
-			util.LogVerbose("File is too large (%d MB). Skipping.", fileInfo.Size()/1024/1024)
+		if fileInfo.Size() > config.MaxFileSize() {
+			util.LogVerbose("File is too large (%s). Skipping.", util.FormatSize(fileInfo.Size()))
 			failureCount++
-			result.AddError(fmt.Sprintf("File %s is too large (%d MB)", filePath, fileInfo.Size()/1024/1024))
+			result.AddError(fmt.Sprintf("File %s is too large (%s, max_file_size is %s)", filePath,
+				util.FormatSize(fileInfo.Size()), util.FormatSize(config.MaxFileSize())))
 			continue
 		}
 
-		fileResult := ProcessAndUploadFileWithPath(dev, storageID, musicFolderID, filePath, i+1)
+		fileResult := uploadFileWithTags(ctx, dev, storageID, musicFolderID, filePath, i+1, tags[filePath])
+		if !fileResult.Success && ctx.Err() != nil {
+			result.Interrupted = true
+			result.NotUploaded = append(result.NotUploaded, filePaths[i:]...)
+			break
+		}
 		if fileResult.Success {
 			successCount++
 			uploadedFilePaths = append(uploadedFilePaths, fileResult.UploadedPath)
+
+			var duration time.Duration
+			if info, err := files.ReadMP3Info(filePath); err == nil {
+				duration = info.Duration
+			} else {
+				util.LogVerbose("Could not read duration: %v", err)
+			}
+
 			result.UploadedFiles = append(result.UploadedFiles, model.MP3File{
 				Path:        fileResult.UploadedPath,
 				ObjectID:    fileResult.ObjectID,
 				ParentID:    musicFolderID,
 				StorageID:   storageID,
 				DisplayName: fileResult.DisplayName,
+				LocalPath:   filePath,
+				Duration:    duration,
 			})
 		} else {
 			failureCount++
@@ -849,28 +787,13 @@ func UploadDirectoryWithPlaylist(dev *mtp.Device, storageID, musicFolderID uint3
 		}
 	}
 
-	fmt.Printf("\nUpload complete: %d successful, %d failed\n", successCount, failureCount)
-
-	if len(uploadedFilePaths) > 0 {
-		playlistResult, err := createPlaylist(dev, storageID, musicFolderID, playlistName, uploadedFilePaths)
-		if err != nil {
-			result.AddError(fmt.Sprintf("Playlist creation failed: %v", err))
-		} else {
-			result.Playlist = &playlistResult
-			result.Success = true
-
-			// If this was a Spotify playlist, show a special success message
-			if presetConfirm != "" {
-				successColor := color.New(color.FgHiGreen, color.Bold)
-				successColor.Printf("\n✅ Successfully uploaded Spotify playlist '%s' to your Garmin device\n",
-					strings.TrimSuffix(playlistName, ".M3U8"))
-			}
-		}
+	if result.Interrupted {
+		fmt.Fprintf(out, "\nUpload interrupted: %d successful, %d failed, %d not uploaded\n", successCount, failureCount,
+			len(result.NotUploaded))
 	} else {
-		result.AddError("No files were successfully uploaded, so no playlist was created.")
+		fmt.Fprintf(out, "\nUpload complete: %d successful, %d failed\n", successCount, failureCount)
 	}
-
-	return result
+	return uploadedFilePaths
 }
 
 type FileUploadResult struct {
@@ -886,6 +809,13 @@ type UploadResult struct {
 	UploadedFiles []model.MP3File
 	Playlist      *model.Playlist
 	Errors        []string
+	// Interrupted is set when the upload stopped before every file was tried
+	Interrupted bool
+	// NotUploaded are the local files that were not tried, or whose transfer was aborted
+	NotUploaded []string
+	// PendingPlaylist names the playlist that was not written because the upload was
+	// interrupted
+	PendingPlaylist string
 }
 
 func (r *UploadResult) AddError(msg string) {
@@ -893,105 +823,115 @@ func (r *UploadResult) AddError(msg string) {
 	util.LogVerbose("Error: %s", msg)
 }
 
-func createPlaylist(dev *mtp.Device, storageID, parentID uint32, playlistName string, uploadedFilePaths []string) (model.Playlist, error) {
-	util.LogVerbose("Creating playlist '%s' with %d songs...", playlistName, len(uploadedFilePaths))
-
-	pathStyle := 1
-	var playlistContent strings.Builder
-	playlistContent.WriteString("#EXTM3U\n")
-
-	var songs []model.PlaylistSong
+// buildPlaylistEntries describes each device song path as a playlist entry. durations
+// holds the known track lengths by song path; others are written as unknown (-1).
+func buildPlaylistEntries(songPaths []string, pathStyle int, durations map[string]time.Duration) []files.PlaylistEntry {
+	var entries []files.PlaylistEntry
 
-	for _, songPath := range uploadedFilePaths {
+	for _, songPath := range songPaths {
 		formattedPath := util.FormatPlaylistPath(songPath, pathStyle)
 		displayName := strings.ToUpper(util.ExtractTrackInfo(songPath))
 
-		playlistContent.WriteString(fmt.Sprintf("#EXTINF:-1,%s\n", displayName))
-		playlistContent.WriteString(formattedPath)
-		playlistContent.WriteString("\n")
+		entries = append(entries, files.PlaylistEntry{
+			Location: formattedPath,
+			Title:    displayName,
+			Seconds:  durationSeconds(durations[songPath]),
+		})
 
 		util.LogVerbose("Added to playlist: %s -> %s", songPath, displayName)
-
-		songs = append(songs, model.PlaylistSong{
-			Name: displayName,
-			Path: songPath,
-		})
 	}
 
-	util.LogVerbose("Full playlist content:\n%s", playlistContent.String())
+	return entries
+}
 
-	tempFile, err := os.CreateTemp("", "playlist-*.m3u8")
-	if err != nil {
-		return model.Playlist{}, fmt.Errorf("error creating temporary playlist file: %v", err)
-	}
-	defer os.Remove(tempFile.Name())
-	defer tempFile.Close()
+// buildPlaylistContent renders the playlist written for a list of device song paths
+func buildPlaylistContent(songPaths []string, pathStyle int, format string, durations map[string]time.Duration) string {
+	return files.FormatPlaylist(format, buildPlaylistEntries(songPaths, pathStyle, durations))
+}
 
-	_, err = tempFile.WriteString(playlistContent.String())
-	if err != nil {
-		return model.Playlist{}, fmt.Errorf("error writing playlist content: %v", err)
-	}
+func createPlaylist(ctx context.Context, dev *mtp.Device, storageID, parentID uint32, playlistName string, uploadedFilePaths []string, durations map[string]time.Duration) (model.Playlist, error) {
+	pathStyle := PlaylistPathStyle(dev)
+	return createPlaylistWithEntries(ctx, dev, storageID, parentID, playlistName, uploadedFilePaths,
+		buildPlaylistEntries(uploadedFilePaths, pathStyle, durations))
+}
+
+// createPlaylistWithEntries uploads a playlist whose entries (titles, durations) were
+// prepared by the caller; songPaths are the device paths the entries point at
+func createPlaylistWithEntries(ctx context.Context, dev *mtp.Device, storageID, parentID uint32, playlistName string, uploadedFilePaths []string, entries []files.PlaylistEntry) (model.Playlist, error) {
+	util.LogVerbose("Creating playlist '%s' with %d songs...", playlistName, len(uploadedFilePaths))
 
-	tempFile.Sync()
-	tempFile.Seek(0, 0)
+	data := []byte(files.FormatPlaylist(files.PlaylistFormatForName(playlistName), entries))
+	util.LogVerbose("Full playlist content:\n%s", data)
 
-	fileInfo, err := tempFile.Stat()
+	util.LogVerbose("Creating playlist on device (size: %d bytes)...", len(data))
+	objectID, err := files.UploadPlaylistData(dev, storageID, parentID, playlistName, data)
 	if err != nil {
-		return model.Playlist{}, fmt.Errorf("error getting file info: %v", err)
+		return model.Playlist{}, fmt.Errorf("error uploading playlist: %w", err)
 	}
 
-	info := mtp.ObjectInfo{
-		StorageID:        storageID,
-		ObjectFormat:     0xBA05,
-		ParentObject:     parentID,
-		Filename:         playlistName,
-		CompressedSize:   uint32(fileInfo.Size()),
-		ModificationDate: time.Now(),
-	}
+	util.LogVerbose("Successfully created and uploaded playlist %s (object ID: %d) with %d songs",
+		playlistName, objectID, len(uploadedFilePaths))
 
-	util.LogVerbose("Creating playlist on device...")
-	var objectID uint32
-	_, _, objectID, err = dev.SendObjectInfo(storageID, parentID, &info)
-	if err != nil {
-		return model.Playlist{}, fmt.Errorf("error creating playlist on device: %v", err)
+	validation := ValidatePlaylistUpload(dev, storageID, objectID, playlistName, data)
+	if !validation.OK() {
+		DisplayPlaylistValidation(consoleOf(ctx), validation)
 	}
 
-	util.LogVerbose("Sending playlist data (size: %d bytes)...", fileInfo.Size())
+	return model.Playlist{
+		Path:       playlistName,
+		ObjectID:   objectID,
+		ParentID:   parentID,
+		StorageID:  storageID,
+		SongPaths:  uploadedFilePaths,
+		Validation: validation,
+	}, nil
+}
 
-	data := make([]byte, fileInfo.Size())
-	_, err = tempFile.Seek(0, 0)
-	if err != nil {
-		return model.Playlist{}, fmt.Errorf("error seeking file: %v", err)
-	}
+// trackTags are tags from a trusted source (such as a beets library) that are used
+// instead of the file's ID3 tags to place an upload
+type trackTags struct {
+	Artist string
+	Album  string
+}
 
-	_, err = tempFile.Read(data)
-	if err != nil {
-		return model.Playlist{}, fmt.Errorf("error reading file: %v", err)
-	}
+// deviceLocationForFile returns the artist folder, album folder and file name (without
+// the track number prefix) that a local file is uploaded to
+func deviceLocationForFile(filePath string, tags *trackTags) (string, string, string) {
+	artist := "UNKNOWN_ARTIST"
+	album := "UNKNOWN_ALBUM"
 
-	err = dev.SendObject(bytes.NewReader(data), fileInfo.Size(), model.EmptyProgressFunc)
-	if err != nil {
-		return model.Playlist{}, fmt.Errorf("error uploading playlist: %v", err)
+	if tags != nil {
+		if tags.Artist != "" {
+			artist = strings.ToUpper(util.SanitizeFolderName(tags.Artist))
+		}
+		if tags.Album != "" {
+			album = strings.ToUpper(util.SanitizeFolderName(tags.Album))
+		}
+		return artist, album, strings.ToUpper(util.SanitizeFileName(filepath.Base(filePath)))
 	}
 
-	util.LogVerbose("Successfully created and uploaded playlist %s (object ID: %d) with %d songs",
-		playlistName, objectID, len(uploadedFilePaths))
+	tag, err := id3v2.Open(filePath, id3v2.Options{Parse: true})
+	if err == nil {
+		if tag.Artist() != "" {
+			artist = strings.ToUpper(util.SanitizeFolderName(tag.Artist()))
+		}
+		if tag.Album() != "" {
+			album = strings.ToUpper(util.SanitizeFolderName(tag.Album()))
+		}
 
-	verified := VerifyPlaylistUploaded(dev, storageID, parentID, playlistName)
-	if !verified {
-		util.LogVerbose("Warning: Playlist verification failed")
+		tag.Close()
+	} else {
+		util.LogVerbose("Error reading ID3 tags: %v", err)
 	}
 
-	return model.Playlist{
-		Path:      playlistName,
-		ObjectID:  objectID,
-		ParentID:  parentID,
-		StorageID: storageID,
-		SongPaths: uploadedFilePaths,
-	}, nil
+	return artist, album, strings.ToUpper(util.SanitizeFileName(filepath.Base(filePath)))
 }
 
 func ProcessAndUploadFileWithPath(dev *mtp.Device, storageID, musicFolderID uint32, filePath string, trackNumber int) FileUploadResult {
+	return uploadFileWithTags(context.Background(), dev, storageID, musicFolderID, filePath, trackNumber, nil)
+}
+
+func uploadFileWithTags(ctx context.Context, dev *mtp.Device, storageID, musicFolderID uint32, filePath string, trackNumber int, tags *trackTags) FileUploadResult {
 	result := FileUploadResult{
 		Success:      false,
 		UploadedPath: "",
@@ -1007,34 +947,15 @@ func ProcessAndUploadFileWithPath(dev *mtp.Device, storageID, musicFolderID uint
 		return result
 	}
 
-	if fileInfo.Size() > 10*1024*1024 {
-		result.Error = fmt.Sprintf("File %s is too large. This implementation only supports files up to 10MB",
-			filepath.Base(filePath))
-		util.LogVerbose("File %s is too large. This implementation only supports files up to 10MB",
-			filepath.Base(filePath))
+	if limit := config.MaxFileSize(); fileInfo.Size() > limit {
+		result.Error = fmt.Sprintf("File %s is too large. Files up to %s are uploaded (max_file_size)",
+			filepath.Base(filePath), util.FormatSize(limit))
+		util.LogVerbose("%s", result.Error)
 		return result
 	}
 
-	tag, err := id3v2.Open(filePath, id3v2.Options{Parse: true})
-
-	artist := "UNKNOWN_ARTIST"
-	album := "UNKNOWN_ALBUM"
-
-	if err == nil {
-		if tag.Artist() != "" {
-			artist = strings.ToUpper(util.SanitizeFolderName(tag.Artist()))
-		}
-		if tag.Album() != "" {
-			album = strings.ToUpper(util.SanitizeFolderName(tag.Album()))
-		}
-
-		defer tag.Close()
-	} else {
-		util.LogVerbose("Error reading ID3 tags: %v", err)
-	}
-
-	originalFileName := util.SanitizeFileName(filepath.Base(filePath))
-	fileName := fmt.Sprintf("%02d %s", trackNumber, strings.ToUpper(originalFileName))
+	artist, album, originalFileName := deviceLocationForFile(filePath, tags)
+	fileName := fmt.Sprintf("%02d %s", trackNumber, originalFileName)
 	devicePath := fmt.Sprintf("/MUSIC/%s/%s/%s", artist, album, fileName)
 
 	util.LogVerbose("Processing file: %s", devicePath)
@@ -1053,76 +974,127 @@ func ProcessAndUploadFileWithPath(dev *mtp.Device, storageID, musicFolderID uint
 		return result
 	}
 
+	objectID, err := sendLocalFile(ctx, dev, storageID, albumFolderID, devicePath, filePath)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Success = true
+	result.UploadedPath = "0:" + devicePath
+	result.ObjectID = objectID
+	result.DisplayName = fileName
+
+	return result
+}
+
+// sendLocalFile creates an MP3 object named after the last element of devicePath and
+// transfers the local file into it. The source is recorded in the upload history before
+// the transfer, so a track left empty by a failed transfer can be found and re-uploaded.
+// Cancelling ctx aborts the transfer and deletes the partly written object.
+func sendLocalFile(ctx context.Context, dev *mtp.Device, storageID, parentID uint32, devicePath, filePath string) (uint32, error) {
+	return sendFile(ctx, dev, storageID, parentID, devicePath, filePath, 0xB901, true)
+}
+
+// sendFile transfers a local file into a new object of the given format, recording it
+// in the upload history when history is set
+func sendFile(ctx context.Context, dev *mtp.Device, storageID, parentID uint32, devicePath, filePath string, format uint16, history bool) (uint32, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return 0, fmt.Errorf("Error accessing file: %v", err)
+	}
+	fileName := path.Base(devicePath)
+
 	info := mtp.ObjectInfo{
 		StorageID:        storageID,
-		ObjectFormat:     0xB901,
-		ParentObject:     albumFolderID,
+		ObjectFormat:     format,
+		ParentObject:     parentID,
 		Filename:         fileName,
 		CompressedSize:   uint32(fileInfo.Size()),
 		ModificationDate: time.Now(),
 	}
 
-	var objectID uint32
-	_, _, objectID, err = dev.SendObjectInfo(storageID, albumFolderID, &info)
+	_, _, objectID, err := dev.SendObjectInfo(storageID, parentID, &info)
 	if err != nil {
-		result.Error = fmt.Sprintf("Error creating file on device: %v", err)
 		util.LogVerbose("Error creating file on device: %v", err)
-		return result
+		return 0, fmt.Errorf("Error creating file on device: %v", err)
+	}
+
+	if history {
+		record := model.UploadRecord{
+			DevicePath: devicePath,
+			LocalPath:  filePath,
+			Size:       fileInfo.Size(),
+			UploadedAt: time.Now(),
+		}
+		if absPath, err := filepath.Abs(filePath); err == nil {
+			record.LocalPath = absPath
+		}
+		if err := files.RecordUpload(record); err != nil {
+			util.LogVerbose("Could not record upload of %s: %v", filePath, err)
+		}
 	}
 
 	file, err := os.Open(filePath)
 	if err != nil {
-		result.Error = fmt.Sprintf("Error opening file: %v", err)
 		util.LogVerbose("Error opening file: %v", err)
-		return result
+		return objectID, fmt.Errorf("Error opening file: %v", err)
 	}
 	defer file.Close()
 
+	out := consoleOf(ctx)
 	bar := progressbar.NewOptions64(
 		fileInfo.Size(),
+		progressbar.OptionSetWriter(out),
 		progressbar.OptionSetDescription(fmt.Sprintf("Uploading %s", fileName)),
 		progressbar.OptionSetWidth(30),
 		progressbar.OptionShowBytes(true),
 		progressbar.OptionShowCount(),
 		progressbar.OptionOnCompletion(func() {
-			fmt.Print("\n")
+			fmt.Fprint(out, "\n")
 		}),
 	)
 
 	progressReader := progressbar.NewReader(file, bar)
 
-	err = dev.SendObject(&progressReader, fileInfo.Size(), model.EmptyProgressFunc)
+	err = dev.SendObject(contextReader{ctx: ctx, reader: &progressReader}, fileInfo.Size(), model.EmptyProgressFunc)
+	if err != nil && ctx.Err() != nil {
+		fmt.Fprintln(out)
+		if deleteErr := deleteDeviceObject(dev, storageID, objectID); deleteErr != nil {
+			util.LogError("Could not delete the partly uploaded %s: %v", devicePath, deleteErr)
+		} else {
+			util.LogInfo("Deleted the partly uploaded %s", devicePath)
+		}
+		return 0, fmt.Errorf("upload of %s aborted: %w", fileName, ctx.Err())
+	}
 	if err != nil {
 		util.LogVerbose("Standard file transfer failed: %v", err)
 
 		file.Seek(0, 0)
 		data, readErr := io.ReadAll(file)
 		if readErr != nil {
-			result.Error = fmt.Sprintf("Error reading file: %v", readErr)
 			util.LogVerbose("Error reading file: %v", readErr)
-			return result
+			return objectID, fmt.Errorf("Error reading file: %v", readErr)
 		}
 
 		err = tryAlternativeDataTransfer(dev, objectID, data, fileInfo.Size())
 	}
 
 	if err != nil {
-		result.Error = fmt.Sprintf("All file transfer methods failed: %v", err)
 		util.LogVerbose("All file transfer methods failed: %v", err)
-		return result
+		return objectID, fmt.Errorf("All file transfer methods failed: %v", err)
 	}
 
 	util.LogVerbose("Successfully uploaded to %s", devicePath)
 
-	verified := verifyFileUploaded(dev, objectID, storageID, albumFolderID, fileName, fileInfo.Size())
+	verified := verifyFileUploaded(out, dev, objectID, storageID, parentID, fileName, fileInfo.Size())
 	if !verified {
 		util.LogVerbose("Could not verify file on device: %s", fileName)
 	}
 
-	result.Success = true
-	result.UploadedPath = "0:" + devicePath
-	result.ObjectID = objectID
-	result.DisplayName = fileName
-
-	return result
+	return objectID, nil
 }
@@ -0,0 +1,92 @@
+package operations
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/fatih/color"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/schachte/better-sync/pkg/files"
+	"github.com/schachte/better-sync/pkg/model"
+	"github.com/schachte/better-sync/pkg/util"
+)
+
+// firstDifference returns the offset of the first byte where a and b differ, or -1
+func firstDifference(a, b []byte) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	if len(a) != len(b) {
+		return min(len(a), len(b))
+	}
+	return -1
+}
+
+// ValidatePlaylistUpload downloads an uploaded playlist, compares it byte for byte with
+// the content that was generated and resolves every entry to an object under /Music
+func ValidatePlaylistUpload(dev *mtp.Device, storageID, objectID uint32, playlistName string, expected []byte) *model.PlaylistValidation {
+	validation := &model.PlaylistValidation{
+		ObjectID:        objectID,
+		Name:            playlistName,
+		ExpectedBytes:   len(expected),
+		FirstDifference: -1,
+	}
+
+	actual, err := readObjectData(dev, objectID)
+	if err != nil {
+		validation.Error = err.Error()
+		return validation
+	}
+
+	validation.ActualBytes = len(actual)
+	validation.FirstDifference = firstDifference(expected, actual)
+	validation.ContentMatch = validation.FirstDifference == -1
+
+	index, err := buildDeviceIndex(dev, storageID, "/Music")
+	if err != nil {
+		validation.Error = fmt.Sprintf("could not index songs: %v", err)
+		return validation
+	}
+
+	for _, entry := range files.ParsePlaylist(playlistName, string(actual)) {
+		validation.Entries++
+		if _, ok := index.Lookup(entry.Location); !ok {
+			validation.Missing = append(validation.Missing, entry.Location)
+		}
+	}
+
+	util.LogVerbose("Validated playlist %s: %d of %d bytes read back, content match %t, %d of %d entries missing",
+		playlistName, validation.ActualBytes, validation.ExpectedBytes, validation.ContentMatch,
+		len(validation.Missing), validation.Entries)
+	return validation
+}
+
+// DisplayPlaylistValidation writes the outcome of reading a playlist back to w
+func DisplayPlaylistValidation(w io.Writer, validation *model.PlaylistValidation) {
+	successColor := color.New(color.FgHiGreen).SprintFunc()
+	errorColor := color.New(color.FgHiRed).SprintFunc()
+
+	switch {
+	case validation.Error != "":
+		fmt.Fprintf(w, "%s Could not validate %s: %s\n", errorColor("✗"), validation.Name, validation.Error)
+		return
+	case validation.OK():
+		fmt.Fprintf(w, "%s Playlist %s verified: %d bytes, all %d entries found on the device\n",
+			successColor("✓"), validation.Name, validation.ActualBytes, validation.Entries)
+		return
+	}
+
+	if !validation.ContentMatch {
+		fmt.Fprintf(w, "%s Playlist %s differs from what was sent: %d bytes written, %d read back, first difference at byte %d\n",
+			errorColor("✗"), validation.Name, validation.ExpectedBytes, validation.ActualBytes, validation.FirstDifference)
+	}
+	if len(validation.Missing) > 0 {
+		fmt.Fprintf(w, "%s %d of %d entries in %s do not point at a song on the device:\n",
+			errorColor("✗"), len(validation.Missing), validation.Entries, validation.Name)
+		for _, entry := range validation.Missing {
+			fmt.Fprintf(w, "    %s\n", entry)
+		}
+	}
+}
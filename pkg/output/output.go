@@ -0,0 +1,211 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format selects how command results are written
+type Format string
+
+const (
+	// Text is the coloured, human-readable console output
+	Text Format = "text"
+	// JSON writes one document holding the schema version, the kind and the result
+	JSON Format = "json"
+	// NDJSON writes one JSON object per row, each with the schema version and kind
+	NDJSON Format = "ndjson"
+	// CSV writes a header row of field names followed by one record per row
+	CSV Format = "csv"
+)
+
+// ParseFormat accepts the values of the --output flag
+func ParseFormat(value string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(value))); format {
+	case "", Text:
+		return Text, nil
+	case JSON, NDJSON, CSV:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown output format %q (use text, json, ndjson or csv)", value)
+	}
+}
+
+// Structured reports whether the format is meant for other programs rather than people
+func (f Format) Structured() bool {
+	return f != Text && f != ""
+}
+
+// Document is the JSON output of a command
+type Document struct {
+	SchemaVersion int         `json:"schemaVersion"`
+	Kind          string      `json:"kind"`
+	Data          interface{} `json:"data"`
+}
+
+// Write writes a command result. JSON output holds value as it is, which may nest;
+// NDJSON and CSV output hold rows, a slice of flat records (or a single one), since
+// each line must stand on its own.
+func Write(w io.Writer, format Format, kind string, value, rows interface{}) error {
+	switch format {
+	case JSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(Document{SchemaVersion: SchemaVersion, Kind: kind, Data: value})
+	case NDJSON:
+		return writeNDJSON(w, kind, rowValues(rows))
+	case CSV:
+		return writeCSV(w, rows, rowValues(rows))
+	default:
+		return fmt.Errorf("output format %q is not structured", format)
+	}
+}
+
+// rowValues returns the records of a slice, or the value itself as the only record
+func rowValues(rows interface{}) []reflect.Value {
+	value := reflect.ValueOf(rows)
+	if !value.IsValid() {
+		return nil
+	}
+	if value.Kind() != reflect.Slice {
+		return []reflect.Value{value}
+	}
+
+	values := make([]reflect.Value, value.Len())
+	for i := range values {
+		values[i] = value.Index(i)
+	}
+	return values
+}
+
+// writeNDJSON writes each row as a JSON object that starts with the schema version and kind
+func writeNDJSON(w io.Writer, kind string, rows []reflect.Value) error {
+	prefix := fmt.Sprintf(`{"schemaVersion":%d,"kind":%q`, SchemaVersion, kind)
+	for _, row := range rows {
+		data, err := json.Marshal(row.Interface())
+		if err != nil {
+			return err
+		}
+		if len(data) < 2 || data[0] != '{' {
+			return fmt.Errorf("cannot write %s as an NDJSON row", row.Type())
+		}
+
+		line := prefix
+		if len(data) > 2 {
+			line += ","
+		}
+		if _, err := fmt.Fprintf(w, "%s%s\n", line, data[1:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// csvColumn is a field written to CSV, named after its JSON field
+type csvColumn struct {
+	name  string
+	index []int
+}
+
+// csvColumns lists the fields of a record type by their JSON names
+func csvColumns(recordType reflect.Type) []csvColumn {
+	for recordType.Kind() == reflect.Pointer {
+		recordType = recordType.Elem()
+	}
+
+	var columns []csvColumn
+	for _, field := range reflect.VisibleFields(recordType) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		columns = append(columns, csvColumn{name: name, index: field.Index})
+	}
+	return columns
+}
+
+func writeCSV(w io.Writer, rows interface{}, values []reflect.Value) error {
+	recordType := reflect.TypeOf(rows)
+	if recordType == nil {
+		return nil
+	}
+	if recordType.Kind() == reflect.Slice {
+		recordType = recordType.Elem()
+	}
+
+	columns := csvColumns(recordType)
+	writer := csv.NewWriter(w)
+
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.name
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, value := range values {
+		for value.Kind() == reflect.Pointer {
+			value = value.Elem()
+		}
+
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			record[i] = csvValue(value.FieldByIndex(column.index))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvValue formats a field; lists are joined with "; "
+func csvValue(value reflect.Value) string {
+	if t, ok := value.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return value.String()
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64)
+	case reflect.Slice:
+		parts := make([]string, value.Len())
+		for i := range parts {
+			parts[i] = csvValue(value.Index(i))
+		}
+		return strings.Join(parts, "; ")
+	case reflect.Pointer:
+		if value.IsNil() {
+			return ""
+		}
+		return csvValue(value.Elem())
+	default:
+		return fmt.Sprint(value.Interface())
+	}
+}
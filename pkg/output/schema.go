@@ -0,0 +1,814 @@
+package output
+
+import (
+	"time"
+
+	"github.com/schachte/better-sync/pkg/config"
+	"github.com/schachte/better-sync/pkg/model"
+	"github.com/schachte/better-sync/pkg/operations"
+)
+
+// SchemaVersion is the version of the records below. It changes when a field is renamed
+// or removed or its meaning changes; adding a field keeps the version.
+const SchemaVersion = 1
+
+// Song is a song on the device
+type Song struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	ObjectID  uint32 `json:"objectId"`
+	StorageID uint32 `json:"storageId"`
+	Storage   string `json:"storage"`
+	// DurationSeconds is -1 when the length is unknown or was not read
+	DurationSeconds int `json:"durationSeconds"`
+}
+
+// Playlist is a playlist file on the device
+type Playlist struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	ObjectID  uint32 `json:"objectId"`
+	StorageID uint32 `json:"storageId"`
+	Storage   string `json:"storage"`
+}
+
+// DevicePlaylists lists the playlists of every storage with their songs
+type DevicePlaylists struct {
+	TotalPlaylists int                `json:"totalPlaylists"`
+	Storages       []StoragePlaylists `json:"storages"`
+}
+
+// StoragePlaylists are the playlists of one storage
+type StoragePlaylists struct {
+	StorageID uint32          `json:"storageId"`
+	Storage   string          `json:"storage"`
+	Playlists []PlaylistSongs `json:"playlists"`
+}
+
+// PlaylistSongs is a playlist with the song paths it lists
+type PlaylistSongs struct {
+	Path     string   `json:"path"`
+	ObjectID uint32   `json:"objectId"`
+	Songs    []string `json:"songs"`
+}
+
+// PlaylistSong is one song of a playlist, the row form of DevicePlaylists. An empty
+// playlist has one row with position 0 and no song.
+type PlaylistSong struct {
+	StorageID        uint32 `json:"storageId"`
+	Storage          string `json:"storage"`
+	Playlist         string `json:"playlist"`
+	PlaylistObjectID uint32 `json:"playlistObjectId"`
+	Position         int    `json:"position"`
+	SongPath         string `json:"songPath"`
+}
+
+// PlaylistFile is a playlist written to the device
+type PlaylistFile struct {
+	Path      string   `json:"path"`
+	ObjectID  uint32   `json:"objectId"`
+	StorageID uint32   `json:"storageId"`
+	Songs     []string `json:"songs"`
+}
+
+// Upload is the result of uploading local files
+type Upload struct {
+	Success  bool           `json:"success"`
+	Files    []UploadedFile `json:"files"`
+	Playlist *PlaylistFile  `json:"playlist"`
+	Errors   []string       `json:"errors"`
+	// Interrupted is set when Ctrl-C stopped the upload; NotUploaded lists the local
+	// files that were not uploaded because of it
+	Interrupted bool     `json:"interrupted"`
+	NotUploaded []string `json:"notUploaded"`
+}
+
+// UploadedFile is a local file uploaded to the device
+type UploadedFile struct {
+	Path            string `json:"path"`
+	LocalPath       string `json:"localPath"`
+	ObjectID        uint32 `json:"objectId"`
+	StorageID       uint32 `json:"storageId"`
+	DurationSeconds int    `json:"durationSeconds"`
+}
+
+// Upload event statuses
+const (
+	UploadStatusUploaded = "uploaded"
+	UploadStatusPlaylist = "playlist"
+	UploadStatusError    = "error"
+	// UploadStatusNotUploaded is a local file skipped because the upload was interrupted
+	UploadStatusNotUploaded = "notUploaded"
+)
+
+// UploadEvent is the row form of Upload: one row per uploaded file, the playlist
+// created for them, and each error
+type UploadEvent struct {
+	Status          string `json:"status"`
+	Path            string `json:"path"`
+	LocalPath       string `json:"localPath"`
+	ObjectID        uint32 `json:"objectId"`
+	DurationSeconds int    `json:"durationSeconds"`
+	Message         string `json:"message"`
+}
+
+// Download is a playlist downloaded from Spotify
+type Download struct {
+	URL       string `json:"url"`
+	Directory string `json:"directory"`
+}
+
+// Deletion is the result of deleting songs
+type Deletion struct {
+	Matched          int           `json:"matched"`
+	Size             int64         `json:"size"`
+	Deleted          int           `json:"deleted"`
+	Freed            int64         `json:"freed"`
+	PlaylistsUpdated int           `json:"playlistsUpdated"`
+	Songs            []DeletedSong `json:"songs"`
+}
+
+// Deleted song statuses
+const (
+	DeleteStatusDeleted = "deleted"
+	DeleteStatusFailed  = "failed"
+	// DeleteStatusKept marks songs that matched but were not deleted, e.g. because the
+	// deletion was cancelled
+	DeleteStatusKept = "kept"
+)
+
+// DeletedSong is a song matched for deletion, the row form of Deletion
+type DeletedSong struct {
+	Status     string    `json:"status"`
+	Path       string    `json:"path"`
+	ObjectID   uint32    `json:"objectId"`
+	StorageID  uint32    `json:"storageId"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+}
+
+// PlaylistDeletion is the result of deleting a playlist, optionally with its songs
+type PlaylistDeletion struct {
+	Name         string   `json:"name"`
+	Path         string   `json:"path"`
+	ObjectID     uint32   `json:"objectId"`
+	StorageID    uint32   `json:"storageId"`
+	DeletedSongs []string `json:"deletedSongs"`
+	KeptSongs    []string `json:"keptSongs"`
+}
+
+// PrunedFolder is an empty folder removed by prune
+type PrunedFolder struct {
+	Path string `json:"path"`
+	// Status is deleted or failed
+	Status string `json:"status"`
+}
+
+// DeviceFile is a file or folder listed by fs ls
+type DeviceFile struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	ObjectID uint32 `json:"objectId"`
+	IsDir    bool   `json:"isDir"`
+	Size     int64  `json:"size"`
+	// Modified is RFC 3339, empty when the device does not report it
+	Modified string `json:"modified"`
+}
+
+// Transfer is the result of fs get or fs put
+type Transfer struct {
+	Files   int      `json:"files"`
+	Folders int      `json:"folders"`
+	Bytes   int64    `json:"bytes"`
+	Failed  []string `json:"failed"`
+	// NotTransferred lists the files left alone because Ctrl-C stopped the transfer
+	NotTransferred []string `json:"notTransferred"`
+}
+
+// PlaylistRename is a playlist renamed or copied; Source is the playlist it was renamed
+// or copied from
+type PlaylistRename struct {
+	Source    string `json:"source"`
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	ObjectID  uint32 `json:"objectId"`
+	StorageID uint32 `json:"storageId"`
+}
+
+// Playlist repair statuses, next to the entry statuses ok, fixable and broken
+const (
+	// RepairStatusError marks a playlist that could not be read or repaired
+	RepairStatusError = "error"
+)
+
+// PlaylistRepair is a playlist checked by playlists doctor
+type PlaylistRepair struct {
+	Path     string `json:"path"`
+	ObjectID uint32 `json:"objectId"`
+	Repaired bool   `json:"repaired"`
+	// Error is why the playlist could not be read or repaired
+	Error   string                `json:"error"`
+	Entries []PlaylistRepairEntry `json:"entries"`
+}
+
+// PlaylistRepairEntry is a checked playlist entry, the row form of PlaylistRepair. A
+// playlist that could not be read has one row with position 0 and status error.
+type PlaylistRepairEntry struct {
+	Playlist string `json:"playlist"`
+	Position int    `json:"position"`
+	Entry    string `json:"entry"`
+	// Status is ok, fixable, broken or error
+	Status string `json:"status"`
+	// Match is the song a fixable entry is changed to
+	Match  string `json:"match"`
+	Reason string `json:"reason"`
+	// Repaired is set when the playlist holding the entry was rewritten
+	Repaired bool `json:"repaired"`
+}
+
+// SmartRefresh is what refreshing did to one smart playlist
+type SmartRefresh struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Tracks int    `json:"tracks"`
+	// Status is created, updated, unchanged, cleared (an existing playlist emptied because
+	// no track matches any more), empty (nothing matched and no playlist exists) or failed
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+// Trash restore statuses
+const (
+	RestoreStatusRestored = "restored"
+	RestoreStatusFailed   = "failed"
+	// RestoreStatusNotRestored marks entries skipped after a failure or Ctrl-C
+	RestoreStatusNotRestored = "notRestored"
+)
+
+// TrashRestore is a trash entry given to trash restore
+type TrashRestore struct {
+	ID         int    `json:"id"`
+	Status     string `json:"status"`
+	DevicePath string `json:"devicePath"`
+	StorageID  uint32 `json:"storageId"`
+	Error      string `json:"error"`
+}
+
+// TrashEmpty is the result of emptying the trash
+type TrashEmpty struct {
+	Removed int   `json:"removed"`
+	Size    int64 `json:"size"`
+}
+
+// Import entry statuses
+const (
+	ImportStatusUploaded   = "uploaded"
+	ImportStatusExisting   = "existing"
+	ImportStatusUnresolved = "unresolved"
+	ImportStatusFailed     = "failed"
+)
+
+// PlaylistImport is a local playlist imported to the device
+type PlaylistImport struct {
+	Name string `json:"name"`
+	// Playlist is null when only tracks were uploaded or the playlist was not written
+	Playlist    *PlaylistFile `json:"playlist"`
+	Entries     []ImportEntry `json:"entries"`
+	Errors      []string      `json:"errors"`
+	Interrupted bool          `json:"interrupted"`
+}
+
+// ImportEntry is one entry of an imported playlist, the row form of PlaylistImport
+type ImportEntry struct {
+	Playlist string `json:"playlist"`
+	Position int    `json:"position"`
+	Entry    string `json:"entry"`
+	Title    string `json:"title"`
+	// Status is uploaded, existing, unresolved or failed
+	Status          string `json:"status"`
+	LocalPath       string `json:"localPath"`
+	DevicePath      string `json:"devicePath"`
+	DurationSeconds int    `json:"durationSeconds"`
+	Reason          string `json:"reason"`
+}
+
+// Export is the result of copying music from the device
+type Export struct {
+	Downloaded  int      `json:"downloaded"`
+	Skipped     int      `json:"skipped"`
+	Bytes       int64    `json:"bytes"`
+	Playlists   []string `json:"playlists"`
+	Missing     []string `json:"missing"`
+	Errors      []string `json:"errors"`
+	Interrupted bool     `json:"interrupted"`
+}
+
+// Orphans lists the songs no playlist references
+type Orphans struct {
+	Songs     int      `json:"songs"`
+	Playlists int      `json:"playlists"`
+	Size      int64    `json:"size"`
+	Orphans   []Orphan `json:"orphans"`
+	// NameMatches are kept because a playlist entry in another folder matches their name
+	NameMatches []string `json:"nameMatches"`
+}
+
+// Orphan is a song no playlist references, the row form of Orphans
+type Orphan struct {
+	// Status is deleted, failed or kept
+	Status    string `json:"status"`
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	ObjectID  uint32 `json:"objectId"`
+	StorageID uint32 `json:"storageId"`
+	Size      int64  `json:"size"`
+	Error     string `json:"error"`
+}
+
+// CleanupStatusReuploaded marks a broken track that was deleted and uploaded again
+const CleanupStatusReuploaded = "reuploaded"
+
+// BrokenTrack is an empty or truncated track found by cleanup-empty
+type BrokenTrack struct {
+	// Status is deleted, reuploaded, failed or kept
+	Status    string `json:"status"`
+	Path      string `json:"path"`
+	ObjectID  uint32 `json:"objectId"`
+	StorageID uint32 `json:"storageId"`
+	Size      int64  `json:"size"`
+	// ExpectedSize is the size of the uploaded source, 0 when unknown
+	ExpectedSize int64  `json:"expectedSize"`
+	Reason       string `json:"reason"`
+	LocalPath    string `json:"localPath"`
+	Error        string `json:"error"`
+}
+
+// Setting is the effective value of a config setting
+type Setting struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// Source is default, config file, env, .env or flag, with the file section or the
+	// variable or flag name
+	Source string `json:"source"`
+}
+
+func durationSeconds(duration time.Duration) int {
+	if duration <= 0 {
+		return -1
+	}
+	return int(duration.Round(time.Second) / time.Second)
+}
+
+// Songs converts songs; durations holds the lengths that were read, by object ID
+func Songs(songs []model.Song, durations map[uint32]time.Duration) []Song {
+	records := make([]Song, 0, len(songs))
+	for _, song := range songs {
+		records = append(records, Song{
+			Name:            song.Name,
+			Path:            song.Path,
+			ObjectID:        song.ObjectID,
+			StorageID:       song.StorageID,
+			Storage:         song.Storage,
+			DurationSeconds: durationSeconds(durations[song.ObjectID]),
+		})
+	}
+	return records
+}
+
+// PlaylistOf converts a playlist
+func PlaylistOf(playlist model.PlaylistInfo) Playlist {
+	return Playlist{
+		Name:      playlist.Name,
+		Path:      playlist.Path,
+		ObjectID:  playlist.ObjectID,
+		StorageID: playlist.StorageID,
+		Storage:   playlist.Storage,
+	}
+}
+
+// Playlists converts playlists
+func Playlists(playlists []model.PlaylistInfo) []Playlist {
+	records := make([]Playlist, 0, len(playlists))
+	for _, playlist := range playlists {
+		records = append(records, PlaylistOf(playlist))
+	}
+	return records
+}
+
+// DevicePlaylistsOf converts the playlists of a device and flattens them into rows
+func DevicePlaylistsOf(data *model.DevicePlaylistData) (DevicePlaylists, []PlaylistSong) {
+	result := DevicePlaylists{Storages: make([]StoragePlaylists, 0)}
+	var rows []PlaylistSong
+	if data == nil {
+		return result, rows
+	}
+
+	result.TotalPlaylists = data.TotalPlaylists
+	for _, storage := range data.Storages {
+		storageRecord := StoragePlaylists{
+			StorageID: storage.StorageID,
+			Storage:   storage.StorageDescription,
+			Playlists: make([]PlaylistSongs, 0, len(storage.Playlists)),
+		}
+
+		for _, playlist := range storage.Playlists {
+			songs := playlist.SongPaths
+			if songs == nil {
+				songs = make([]string, 0)
+			}
+			storageRecord.Playlists = append(storageRecord.Playlists, PlaylistSongs{
+				Path:     playlist.Path,
+				ObjectID: playlist.ObjectID,
+				Songs:    songs,
+			})
+
+			row := PlaylistSong{
+				StorageID:        storage.StorageID,
+				Storage:          storage.StorageDescription,
+				Playlist:         playlist.Path,
+				PlaylistObjectID: playlist.ObjectID,
+			}
+			if len(songs) == 0 {
+				rows = append(rows, row)
+			}
+			for i, song := range songs {
+				row.Position = i + 1
+				row.SongPath = song
+				rows = append(rows, row)
+			}
+		}
+		result.Storages = append(result.Storages, storageRecord)
+	}
+	return result, rows
+}
+
+// PlaylistFileOf converts a playlist written to the device
+func PlaylistFileOf(playlist *model.Playlist) *PlaylistFile {
+	if playlist == nil {
+		return nil
+	}
+
+	songs := playlist.SongPaths
+	if songs == nil {
+		songs = make([]string, 0)
+	}
+	return &PlaylistFile{
+		Path:      playlist.Path,
+		ObjectID:  playlist.ObjectID,
+		StorageID: playlist.StorageID,
+		Songs:     songs,
+	}
+}
+
+// UploadOf converts the result of an upload and flattens it into rows
+func UploadOf(result *operations.UploadResult) (Upload, []UploadEvent) {
+	upload := Upload{
+		Success:     result.Success,
+		Files:       make([]UploadedFile, 0, len(result.UploadedFiles)),
+		Playlist:    PlaylistFileOf(result.Playlist),
+		Errors:      make([]string, 0, len(result.Errors)),
+		Interrupted: result.Interrupted,
+		NotUploaded: make([]string, 0, len(result.NotUploaded)),
+	}
+	var rows []UploadEvent
+
+	for _, file := range result.UploadedFiles {
+		record := UploadedFile{
+			Path:            file.Path,
+			LocalPath:       file.LocalPath,
+			ObjectID:        file.ObjectID,
+			StorageID:       file.StorageID,
+			DurationSeconds: durationSeconds(file.Duration),
+		}
+		upload.Files = append(upload.Files, record)
+		rows = append(rows, UploadEvent{
+			Status:          UploadStatusUploaded,
+			Path:            record.Path,
+			LocalPath:       record.LocalPath,
+			ObjectID:        record.ObjectID,
+			DurationSeconds: record.DurationSeconds,
+		})
+	}
+
+	if upload.Playlist != nil {
+		rows = append(rows, UploadEvent{
+			Status:          UploadStatusPlaylist,
+			Path:            upload.Playlist.Path,
+			ObjectID:        upload.Playlist.ObjectID,
+			DurationSeconds: -1,
+		})
+	}
+
+	for _, message := range result.Errors {
+		upload.Errors = append(upload.Errors, message)
+		rows = append(rows, UploadEvent{Status: UploadStatusError, DurationSeconds: -1, Message: message})
+	}
+
+	for _, localPath := range result.NotUploaded {
+		upload.NotUploaded = append(upload.NotUploaded, localPath)
+		rows = append(rows, UploadEvent{Status: UploadStatusNotUploaded, LocalPath: localPath, DurationSeconds: -1})
+	}
+	return upload, rows
+}
+
+// DeletionOf converts the result of a bulk deletion; its songs are the rows
+func DeletionOf(result *operations.BulkDeleteResult) Deletion {
+	failed := make(map[string]bool)
+	for _, path := range result.Failed {
+		failed[path] = true
+	}
+	notDeleted := make(map[string]bool)
+	for _, path := range result.NotDeleted {
+		notDeleted[path] = true
+	}
+	attempted := result.Deleted+len(result.Failed) > 0
+
+	deletion := Deletion{
+		Matched:          len(result.Matched),
+		Size:             result.Size,
+		Deleted:          result.Deleted,
+		Freed:            result.Freed,
+		PlaylistsUpdated: result.Playlists,
+		Songs:            make([]DeletedSong, 0, len(result.Matched)),
+	}
+	for _, song := range result.Matched {
+		status := DeleteStatusDeleted
+		switch {
+		case !attempted || notDeleted[song.Path]:
+			status = DeleteStatusKept
+		case failed[song.Path]:
+			status = DeleteStatusFailed
+		}
+
+		deletion.Songs = append(deletion.Songs, DeletedSong{
+			Status:     status,
+			Path:       song.Path,
+			ObjectID:   song.ObjectID,
+			StorageID:  song.StorageID,
+			Size:       song.Size,
+			ModifiedAt: song.ModTime,
+		})
+	}
+	return deletion
+}
+
+// PlaylistDeletionOf describes a deleted playlist; plan is nil when only the playlist
+// was deleted
+func PlaylistDeletionOf(playlist model.PlaylistInfo, plan *operations.PlaylistDeletionPlan, deletedSongs []string) PlaylistDeletion {
+	deletion := PlaylistDeletion{
+		Name:         playlist.Name,
+		Path:         playlist.Path,
+		ObjectID:     playlist.ObjectID,
+		StorageID:    playlist.StorageID,
+		DeletedSongs: make([]string, 0, len(deletedSongs)),
+		KeptSongs:    make([]string, 0),
+	}
+	deletion.DeletedSongs = append(deletion.DeletedSongs, deletedSongs...)
+
+	if plan != nil {
+		for _, track := range plan.Keep {
+			deletion.KeptSongs = append(deletion.KeptSongs, track.Entry)
+		}
+	}
+	return deletion
+}
+
+// PrunedFolders converts the result of pruning empty folders
+func PrunedFolders(result *operations.PruneResult) []PrunedFolder {
+	folders := make([]PrunedFolder, 0, len(result.Deleted)+len(result.Failed))
+	for _, path := range result.Deleted {
+		folders = append(folders, PrunedFolder{Path: path, Status: DeleteStatusDeleted})
+	}
+	for _, path := range result.Failed {
+		folders = append(folders, PrunedFolder{Path: path, Status: DeleteStatusFailed})
+	}
+	return folders
+}
+
+// DeviceFiles converts a listing of device files and folders
+func DeviceFiles(entries []operations.DeviceEntry) []DeviceFile {
+	records := make([]DeviceFile, 0, len(entries))
+	for _, entry := range entries {
+		record := DeviceFile{
+			Name:     entry.Name,
+			Path:     entry.Path,
+			ObjectID: entry.ObjectID,
+			IsDir:    entry.IsDir,
+			Size:     entry.Size,
+		}
+		if !entry.ModTime.IsZero() {
+			record.Modified = entry.ModTime.Format(time.RFC3339)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// DeviceFileOf converts a single device file or folder
+func DeviceFileOf(entry *operations.DeviceEntry) DeviceFile {
+	return DeviceFiles([]operations.DeviceEntry{*entry})[0]
+}
+
+// TransferOf converts the result of fs get or fs put
+func TransferOf(result *operations.TransferResult) Transfer {
+	return Transfer{
+		Files:          result.Files,
+		Folders:        result.Folders,
+		Bytes:          result.Bytes,
+		Failed:         append(make([]string, 0, len(result.Failed)), result.Failed...),
+		NotTransferred: append(make([]string, 0, len(result.NotTransferred)), result.NotTransferred...),
+	}
+}
+
+// PlaylistRenameOf describes a playlist renamed or copied from source
+func PlaylistRenameOf(source string, playlist model.PlaylistInfo) PlaylistRename {
+	return PlaylistRename{
+		Source:    source,
+		Name:      playlist.Name,
+		Path:      playlist.Path,
+		ObjectID:  playlist.ObjectID,
+		StorageID: playlist.StorageID,
+	}
+}
+
+// PlaylistRepairsOf converts the checks of playlists doctor and flattens them into rows
+func PlaylistRepairsOf(checks []operations.PlaylistCheck) ([]PlaylistRepair, []PlaylistRepairEntry) {
+	repairs := make([]PlaylistRepair, 0, len(checks))
+	var rows []PlaylistRepairEntry
+	for _, check := range checks {
+		repair := PlaylistRepair{
+			Path:     check.Playlist.Path,
+			ObjectID: check.Playlist.ObjectID,
+			Repaired: check.Repaired,
+			Error:    check.Error,
+			Entries:  make([]PlaylistRepairEntry, 0, len(check.Entries)),
+		}
+		for _, entry := range check.Entries {
+			repair.Entries = append(repair.Entries, PlaylistRepairEntry{
+				Playlist: check.Playlist.Path,
+				Position: entry.Index + 1,
+				Entry:    entry.Entry,
+				Status:   entry.Status,
+				Match:    entry.Match,
+				Reason:   entry.Reason,
+				Repaired: check.Repaired,
+			})
+		}
+
+		if check.Error != "" && len(check.Entries) == 0 {
+			rows = append(rows, PlaylistRepairEntry{Playlist: check.Playlist.Path, Status: RepairStatusError, Reason: check.Error})
+		}
+		rows = append(rows, repair.Entries...)
+		repairs = append(repairs, repair)
+	}
+	return repairs, rows
+}
+
+// SmartRefreshes converts the results of refreshing smart playlists
+func SmartRefreshes(results []operations.SmartRefreshResult) []SmartRefresh {
+	records := make([]SmartRefresh, 0, len(results))
+	for _, result := range results {
+		status := result.Status
+		if status == operations.SmartStatusEmpty {
+			status = "empty"
+		}
+		records = append(records, SmartRefresh{
+			Name:   result.Name,
+			Path:   result.Path,
+			Tracks: result.Tracks,
+			Status: status,
+			Error:  result.Error,
+		})
+	}
+	return records
+}
+
+// importStatuses maps the statuses shown on the console to the schema
+var importStatuses = map[string]string{
+	operations.ImportStatusUploaded:   ImportStatusUploaded,
+	operations.ImportStatusExisting:   ImportStatusExisting,
+	operations.ImportStatusUnresolved: ImportStatusUnresolved,
+	operations.ImportStatusFailed:     ImportStatusFailed,
+}
+
+// PlaylistImportsOf converts imported playlists and flattens their entries into rows
+func PlaylistImportsOf(results []*operations.PlaylistImportResult) ([]PlaylistImport, []ImportEntry) {
+	imports := make([]PlaylistImport, 0, len(results))
+	var rows []ImportEntry
+	for _, result := range results {
+		record := PlaylistImport{
+			Name:     result.Name,
+			Playlist: PlaylistFileOf(result.Playlist),
+			Entries:  make([]ImportEntry, 0, len(result.Entries)),
+			Errors:   make([]string, 0),
+		}
+		if result.Upload != nil {
+			record.Errors = append(record.Errors, result.Upload.Errors...)
+			record.Interrupted = result.Upload.Interrupted
+		}
+
+		for i, entry := range result.Entries {
+			seconds := entry.Seconds
+			if seconds <= 0 {
+				seconds = -1
+			}
+			record.Entries = append(record.Entries, ImportEntry{
+				Playlist:        result.Name,
+				Position:        i + 1,
+				Entry:           entry.Entry,
+				Title:           entry.Title,
+				Status:          importStatuses[entry.Status],
+				LocalPath:       entry.LocalPath,
+				DevicePath:      entry.DevicePath,
+				DurationSeconds: seconds,
+				Reason:          entry.Reason,
+			})
+		}
+		rows = append(rows, record.Entries...)
+		imports = append(imports, record)
+	}
+	return imports, rows
+}
+
+// ExportOf converts the result of an export
+func ExportOf(result *operations.ExportResult) Export {
+	return Export{
+		Downloaded:  result.Downloaded,
+		Skipped:     result.Skipped,
+		Bytes:       result.Bytes,
+		Playlists:   append(make([]string, 0, len(result.Playlists)), result.Playlists...),
+		Missing:     append(make([]string, 0, len(result.Missing)), result.Missing...),
+		Errors:      append(make([]string, 0, len(result.Errors)), result.Errors...),
+		Interrupted: result.Interrupted,
+	}
+}
+
+// OrphansOf converts an orphan report; its orphans are the rows
+func OrphansOf(report *operations.OrphanReport) Orphans {
+	orphans := Orphans{
+		Songs:       report.Songs,
+		Playlists:   report.Playlists,
+		Size:        report.TotalSize,
+		Orphans:     make([]Orphan, 0, len(report.Orphans)),
+		NameMatches: make([]string, 0, len(report.NameMatches)),
+	}
+	for _, orphan := range report.Orphans {
+		status := DeleteStatusKept
+		switch {
+		case orphan.Deleted:
+			status = DeleteStatusDeleted
+		case orphan.Error != "":
+			status = DeleteStatusFailed
+		}
+		orphans.Orphans = append(orphans.Orphans, Orphan{
+			Status:    status,
+			Name:      orphan.Song.Name,
+			Path:      orphan.Song.Path,
+			ObjectID:  orphan.Song.ObjectID,
+			StorageID: orphan.Song.StorageID,
+			Size:      orphan.Size,
+			Error:     orphan.Error,
+		})
+	}
+	for _, song := range report.NameMatches {
+		orphans.NameMatches = append(orphans.NameMatches, song.Path)
+	}
+	return orphans
+}
+
+// BrokenTracks converts the report of cleanup-empty
+func BrokenTracks(report *operations.EmptyCleanupReport) []BrokenTrack {
+	records := make([]BrokenTrack, 0, len(report.Tracks))
+	for _, track := range report.Tracks {
+		status := DeleteStatusKept
+		switch {
+		case track.Error != "":
+			status = DeleteStatusFailed
+		case track.Reuploaded:
+			status = CleanupStatusReuploaded
+		case track.Deleted:
+			status = DeleteStatusDeleted
+		}
+		records = append(records, BrokenTrack{
+			Status:       status,
+			Path:         track.Path,
+			ObjectID:     track.ObjectID,
+			StorageID:    track.StorageID,
+			Size:         track.Size,
+			ExpectedSize: track.ExpectedSize,
+			Reason:       track.Reason,
+			LocalPath:    track.LocalPath,
+			Error:        track.Error,
+		})
+	}
+	return records
+}
+
+// Settings converts effective config values, with secrets masked
+func Settings(values []config.Value) []Setting {
+	records := make([]Setting, 0, len(values))
+	for _, value := range values {
+		records = append(records, Setting{Key: value.Key, Value: value.Display(), Source: value.Source})
+	}
+	return records
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
@@ -201,3 +202,51 @@ func CreateFolder(dev *mtp.Device, storageID, parentID uint32, folderName string
 
 	return newObjectID, nil
 }
+
+// FormatDuration renders a duration as m:ss, or h:mm:ss from an hour up
+func FormatDuration(d time.Duration) string {
+	seconds := int(d.Round(time.Second) / time.Second)
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// FormatSize renders a byte count in B, KB, MB or GB
+func FormatSize(bytes int64) string {
+	switch {
+	case bytes >= 1024*1024*1024:
+		return fmt.Sprintf("%.1f GB", float64(bytes)/1024/1024/1024)
+	case bytes >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/1024/1024)
+	case bytes >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(bytes)/1024)
+	default:
+		return fmt.Sprintf("%d B", bytes)
+	}
+}
+
+// ParseSize parses a byte count such as 500K, 8MB or 1.5GB; a plain number is bytes
+func ParseSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	number := strings.TrimSuffix(value, "B")
+
+	multiplier := float64(1)
+	switch {
+	case strings.HasSuffix(number, "K"):
+		multiplier = 1024
+	case strings.HasSuffix(number, "M"):
+		multiplier = 1024 * 1024
+	case strings.HasSuffix(number, "G"):
+		multiplier = 1024 * 1024 * 1024
+	}
+	if multiplier > 1 {
+		number = number[:len(number)-1]
+	}
+
+	size, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid size %q, expected e.g. 500K, 8MB or 1.5GB", value)
+	}
+	return int64(size * multiplier), nil
+}
@@ -15,16 +15,17 @@ var (
 	verboseOutput bool
 )
 
-func SetupLogging(verbose bool) {
+// SetupLogging writes a new log file to logDir, and to the console as well when verbose
+func SetupLogging(verbose bool, logDir string) {
 	verboseOutput = verbose
 
-	err := os.MkdirAll("logs", 0755)
+	err := os.MkdirAll(logDir, 0755)
 	if err != nil {
 		fmt.Println("Warning: Failed to create logs directory:", err)
 	}
 
 	timestamp := time.Now().Format("2006-01-02_15-04-05")
-	logPath := filepath.Join("logs", fmt.Sprintf("mtpapp_%s.log", timestamp))
+	logPath := filepath.Join(logDir, fmt.Sprintf("mtpapp_%s.log", timestamp))
 
 	logFile, err := os.Create(logPath)
 	if err != nil {
@@ -0,0 +1,33 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConfigDir returns the directory used for locally stored settings, creating it if needed
+func ConfigDir() (string, error) {
+	baseDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("error locating config directory: %w", err)
+	}
+
+	dir := filepath.Join(baseDir, "better-sync")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("error creating config directory: %w", err)
+	}
+
+	return dir, nil
+}
+
+// ExpandPath replaces a leading "~/" with the user's home directory
+func ExpandPath(path string) string {
+	if strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
@@ -126,6 +126,33 @@ func SanitizeForPath(text string) string {
 	return text
 }
 
+// Playlist entry path styles understood by FormatPlaylistPath
+const (
+	PathStyleDrive     = 1 // 0:/MUSIC/ARTIST/ALBUM/01 TRACK.MP3
+	PathStyleAbsolute  = 2 // /MUSIC/ARTIST/ALBUM/01 TRACK.MP3
+	PathStyleRelative  = 3 // MUSIC/ARTIST/ALBUM/01 TRACK.MP3
+	PathStyleMixedCase = 4 // 0:/Music/Artist/Album/01 Track.mp3
+
+	DefaultPathStyle = PathStyleDrive
+)
+
+// PathStyles lists every path style in the order they are probed
+var PathStyles = []int{PathStyleDrive, PathStyleAbsolute, PathStyleRelative, PathStyleMixedCase}
+
+// PathStyleExample shows what an entry looks like in the given style
+func PathStyleExample(style int) string {
+	switch style {
+	case PathStyleAbsolute:
+		return "/MUSIC/ARTIST/ALBUM/01 TRACK.MP3"
+	case PathStyleRelative:
+		return "MUSIC/ARTIST/ALBUM/01 TRACK.MP3"
+	case PathStyleMixedCase:
+		return "0:/Music/Artist/Album/01 Track.mp3"
+	default:
+		return "0:/MUSIC/ARTIST/ALBUM/01 TRACK.MP3"
+	}
+}
+
 func FormatPlaylistPath(path string, devicePathStyle int) string {
 	path = NormalizePathForDevice(path)
 	switch devicePathStyle {
@@ -7,11 +7,8 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"os"
 	"regexp"
 	"strings"
-
-	"github.com/joho/godotenv"
 )
 
 type SpotifyTokenResponse struct {
@@ -26,20 +23,18 @@ type SpotifyPlaylist struct {
 	Id          string `json:"id"`
 }
 
-func GetSpotifyAccessToken() (string, error) {
-	err := godotenv.Load()
-	if err != nil {
-		return "", fmt.Errorf("error loading .env file: %v", err)
-	}
-
-	clientID := os.Getenv("SPOTIFY_CLIENT_ID")
-	clientSecret := os.Getenv("SPOTIFY_CLIENT_SECRET")
+// SpotifyCredentials are the client ID and secret of a Spotify app
+type SpotifyCredentials struct {
+	ClientID     string
+	ClientSecret string
+}
 
-	if clientID == "" || clientSecret == "" {
-		return "", fmt.Errorf("SPOTIFY_CLIENT_ID or SPOTIFY_CLIENT_SECRET not set in .env file")
+func GetSpotifyAccessToken(credentials SpotifyCredentials) (string, error) {
+	if credentials.ClientID == "" || credentials.ClientSecret == "" {
+		return "", fmt.Errorf("SPOTIFY_CLIENT_ID or SPOTIFY_CLIENT_SECRET not set in the environment, .env or config file")
 	}
 
-	auth := base64.StdEncoding.EncodeToString([]byte(clientID + ":" + clientSecret))
+	auth := base64.StdEncoding.EncodeToString([]byte(credentials.ClientID + ":" + credentials.ClientSecret))
 
 	data := url.Values{}
 	data.Set("grant_type", "client_credentials")
@@ -94,14 +89,14 @@ func ExtractPlaylistID(input string) (string, error) {
 	return "", fmt.Errorf("could not extract playlist ID from input: %s", input)
 }
 
-func GetSpotifyPlaylistName(input string) (string, error) {
+func GetSpotifyPlaylistName(input string, credentials SpotifyCredentials) (string, error) {
 	playlistID, err := ExtractPlaylistID(input)
 	if err != nil {
 		return "", err
 	}
 	LogVerbose("Extracted Playlist ID: %s", playlistID)
 
-	token, err := GetSpotifyAccessToken()
+	token, err := GetSpotifyAccessToken(credentials)
 	if err != nil {
 		return "", fmt.Errorf("error getting access token: %v", err)
 	}
@@ -0,0 +1,14 @@
+package util
+
+import (
+	"os"
+
+	"github.com/mattn/go-isatty"
+)
+
+// StdinIsTerminal reports whether stdin is an interactive terminal rather than a pipe,
+// a file or /dev/null, as it is under cron
+func StdinIsTerminal() bool {
+	fd := os.Stdin.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}